@@ -1,40 +1,289 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
+	"io/fs"
 	"os"
+	pathpkg "path"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	LogDir  string         `yaml:"log_dir"`  // Directory for auto-discovery
-	Streams []StreamConfig `yaml:"streams"`
-	Theme   ThemeConfig    `yaml:"theme"`
-	Filters []FilterConfig `yaml:"filters"`
-	Groups  []GroupConfig  `yaml:"groups"`
+	LogDir string `yaml:"log_dir"` // Directory for auto-discovery
+	// CatchAll adds an "other" stream for files in LogDir that no other
+	// stream claims, so nothing there is silently ignored.
+	CatchAll bool           `yaml:"catch_all"`
+	Streams  []StreamConfig `yaml:"streams"`
+	Theme    ThemeConfig    `yaml:"theme"`
+	Filters  []FilterConfig `yaml:"filters"`
+	Groups   []GroupConfig  `yaml:"groups"`
+	Buffer   BufferConfig   `yaml:"buffer"`
+	MCP      MCPConfig      `yaml:"mcp"`
+	// BufferSize is shorthand for buffer.max_entries. Zero keeps every
+	// entry, so memory grows with the logs for as long as logdump runs.
+	BufferSize *int `yaml:"buffer_size"`
+	// Heartbeat, a duration such as "1m", emits a status entry from the
+	// "logdump" source at that interval. Empty disables it.
+	Heartbeat string `yaml:"heartbeat"`
+	// Overflow is what a stream does with a line when logdump cannot keep
+	// up: "block" (default) pauses reading, "drop_newest" skips the line
+	// and "drop_oldest" discards the oldest queued one.
+	Overflow string `yaml:"overflow"`
+	// DiscoverRecursive makes auto-discovery look in the subdirectories of
+	// LogDir too, naming their streams by relative path, e.g. nginx/access.
+	DiscoverRecursive bool `yaml:"discover_recursive"`
+	// Sink, when its path is set, writes every entry to a file as well.
+	Sink SinkConfig `yaml:"sink"`
+	// Include lists config files, or globs of them, whose streams, groups
+	// and filters are added to this file's. Relative paths are relative to
+	// the including file, and included files may include others.
+	Include []string `yaml:"include"`
+
+	// Path is the file the config was loaded from, empty if none was.
+	Path string `yaml:"-"`
+}
+
+// DefaultBufferSize is the number of entries buffered when neither
+// buffer_size nor buffer.max_entries is set.
+const DefaultBufferSize = 1000
+
+// EntryLimit returns how many entries the buffers hold, zero meaning
+// unlimited. buffer_size takes precedence over buffer.max_entries.
+func (c *Config) EntryLimit() int {
+	if c.BufferSize != nil {
+		return max(*c.BufferSize, 0)
+	}
+	if c.Buffer.MaxEntries > 0 {
+		return c.Buffer.MaxEntries
+	}
+	return DefaultBufferSize
+}
+
+// MCPConfig holds server-wide defaults for the MCP tools. Per-call tool
+// arguments always take precedence.
+type MCPConfig struct {
+	GrepCaseInsensitive bool `yaml:"grep_case_insensitive"`
+	GrepLiteral         bool `yaml:"grep_literal"` // treat grep patterns as plain text
+	// Strict rejects requests that do not follow JSON-RPC 2.0 instead of
+	// accepting them on a best-effort basis.
+	Strict bool `yaml:"strict"`
+	// DefaultAgent is who agent accesses are attributed to when the client
+	// neither sends clientInfo on initialize nor calls logdump/set_agent.
+	DefaultAgent string `yaml:"default_agent"`
+	// MaxMessageBytes caps websocket messages in both directions (default
+	// 4 MiB). Larger requests close the connection; larger responses are
+	// replaced by an error asking for a narrower query.
+	MaxMessageBytes int64 `yaml:"max_message_bytes"`
+	// ExportDir is the only directory logdump_export may write to
+	// (default ~/.local/share/logdump/exports).
+	ExportDir string `yaml:"export_dir"`
+	// AllowedOrigins are the browser origins, such as
+	// "https://app.example.com" or "*", allowed to connect over websocket
+	// or SSE. When empty, any origin may connect to a loopback address
+	// and only the server's own origin to other addresses.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	// GrepTimeout bounds how long a logdump_grep search may run, as a
+	// duration such as "10s" (default 5s).
+	GrepTimeout string `yaml:"grep_timeout"`
+}
+
+// DefaultMaxMessageBytes is the websocket message cap when
+// max_message_bytes is not set.
+const DefaultMaxMessageBytes = 4 << 20
+
+// MessageLimit returns MaxMessageBytes, or the default if it is not set.
+func (c MCPConfig) MessageLimit() int64 {
+	if c.MaxMessageBytes > 0 {
+		return c.MaxMessageBytes
+	}
+	return DefaultMaxMessageBytes
+}
+
+// DefaultGrepTimeout is how long a logdump_grep search may run when
+// grep_timeout is not set.
+const DefaultGrepTimeout = 5 * time.Second
+
+// SearchTimeout returns GrepTimeout, or the default if it is not set or
+// not a positive duration.
+func (c MCPConfig) SearchTimeout() time.Duration {
+	if d, err := time.ParseDuration(c.GrepTimeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultGrepTimeout
+}
+
+// ExportDirectory returns ExportDir with ~ expanded, or the default.
+func (c MCPConfig) ExportDirectory() string {
+	if c.ExportDir != "" {
+		return expandPath(c.ExportDir)
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "share", "logdump", "exports")
+}
+
+// BufferConfig controls how much history the in-memory buffer retains.
+// Strategy is one of "count", "bytes" or "time", or a comma-separated
+// combination such as "count,time" where every selected limit applies.
+type BufferConfig struct {
+	Strategy   string `yaml:"strategy"`
+	MaxEntries int    `yaml:"max_entries"` // count strategy, per stream in the Manager's buffer (default 1000)
+	MaxBytes   int64  `yaml:"max_bytes"`   // bytes strategy, total content size
+	MaxAge     string `yaml:"max_age"`     // time strategy, e.g. "15m"
+	// MaxMemoryMB caps the estimated memory of every stream's buffered
+	// entries together, whatever the strategy; 0 means no cap.
+	MaxMemoryMB int `yaml:"max_memory_mb"`
 }
 
 type GroupConfig struct {
 	Name    string   `yaml:"name"`
-	Pattern string   `yaml:"pattern"`
-	Color   string   `yaml:"color"`
-	Streams []string `yaml:"streams"`
+	Pattern string   `yaml:"pattern,omitempty"`
+	Color   string   `yaml:"color,omitempty"`
+	Streams []string `yaml:"streams,omitempty"`
 }
 
+// Stream sources other than files, see StreamConfig.Source.
+const (
+	SourceStdin   = "stdin"
+	SourceCommand = "command"
+	SourceSyslog  = "syslog"
+)
+
 type StreamConfig struct {
-	Name     string   `yaml:"name"`
+	Name string `yaml:"name"`
+	// Source "stdin" reads standard input instead of the files under Path.
+	// Source "command", implied by Command, reads the output of Command.
+	// Source "syslog", implied by Listen, receives syslog messages.
+	Source string `yaml:"source"`
+	// Command is a program and its arguments, e.g. ["journalctl", "-f"],
+	// whose stdout and stderr are the stream's lines. It is restarted
+	// with backoff whenever it exits.
+	Command []string `yaml:"command"`
+	// Listen is a udp:// or tcp:// address, e.g. udp://0.0.0.0:5514, on
+	// which to receive RFC 3164 or RFC 5424 syslog messages. Each sender's
+	// hostname is added to the tags of its entries.
+	Listen string `yaml:"listen"`
+	// Path is the directory holding the stream's files. It may be a glob,
+	// e.g. ~/logs/service-*/current, to tail every matching directory,
+	// including ones created later.
 	Path     string   `yaml:"path"`
 	Patterns []string `yaml:"patterns"`
 	Tags     []string `yaml:"tags"`
 	Color    string   `yaml:"color"`
+	// ExcludeFiles are file name patterns that never match, even if they
+	// match Patterns.
+	ExcludeFiles []string `yaml:"exclude_files"`
+
+	// TimestampFormat is a Go time layout for the timestamp at the start of
+	// each line. When empty, common layouts are detected automatically.
+	TimestampFormat string `yaml:"timestamp_format"`
+	// TimestampRegex optionally locates the timestamp; its first capture
+	// group (or the whole match) is parsed.
+	TimestampRegex string `yaml:"timestamp_regex"`
+
+	// LevelRegex finds the log level in each line; its first capture group
+	// that matched (or the whole match) is the level. The default finds
+	// level=warn, "level":"info" and upper-case words such as ERROR.
+	LevelRegex string `yaml:"level_regex"`
+
+	Multiline *MultilineConfig `yaml:"multiline"`
+
+	// Format "json" parses each line as an object: the message field
+	// becomes the content and the remaining keys become entry fields.
+	Format       string `yaml:"format"`
+	MessageField string `yaml:"message_field"` // default "msg" or "message"
+	LevelField   string `yaml:"level_field"`   // default "level"
+
+	// SampleRate keeps one line in every N while tailing (0 or 1 keeps all).
+	SampleRate int `yaml:"sample_rate"`
+	// MaxLinesPerSec drops lines beyond this rate, leaving a marker with
+	// the number dropped (0 is unlimited).
+	MaxLinesPerSec int `yaml:"max_lines_per_sec"`
+	// HistoryLines limits the existing content loaded from each file to its
+	// last N lines, 0 loading none; unset loads the whole file.
+	HistoryLines *int `yaml:"history_lines"`
+	// MaxLineLength is how many bytes of a line are kept; the rest is cut
+	// and noted in the entry. 0 uses the default of 64 KiB.
+	MaxLineLength int `yaml:"max_line_length"`
+	// IncludePatterns, if any, are regexes of which an entry must match
+	// one to be kept; ExcludePatterns drop the entries matching any of
+	// them. Both apply to whole entries, after multiline grouping, before
+	// anything reaches the buffer.
+	IncludePatterns []string `yaml:"include_patterns"`
+	ExcludePatterns []string `yaml:"exclude_patterns"`
+	// CollapseRepeats replaces a run of identical consecutive lines with
+	// one entry carrying the repeat count.
+	CollapseRepeats bool `yaml:"collapse_repeats"`
+	// IncludeRotated reads gzip-compressed rotated files (app.log.1.gz)
+	// found at startup as history, oldest first, before the live file's.
+	// Auto-discovered streams set it.
+	IncludeRotated bool `yaml:"include_rotated"`
+	// Recursive also tails matching files in the subdirectories of Path,
+	// including ones created later, and tags each entry with its file's
+	// path relative to Path. A "**" in a pattern implies it.
+	Recursive bool `yaml:"recursive"`
+}
+
+// MultilineConfig groups continuation lines (stack traces, wrapped
+// messages) into a single entry.
+type MultilineConfig struct {
+	Pattern      string `yaml:"pattern"`      // regex matching the first line of an entry
+	Continuation string `yaml:"continuation"` // regex matching lines that extend the previous entry, e.g. '^\s'
+	MaxLines     int    `yaml:"max_lines"`    // lines per entry before forcing a new one (default 500)
+	Timeout      string `yaml:"timeout"`      // flush a pending entry after this idle time (default 1s)
+}
+
+// SinkConfig is a file that every entry read is written to, rotated by
+// size.
+type SinkConfig struct {
+	Path      string `yaml:"path"`        // output file; empty disables the sink
+	Format    string `yaml:"format"`      // "text" (default), "[timestamp] [source] content" lines, or "json", one object per line
+	MaxSizeMB int    `yaml:"max_size_mb"` // rotate the file when it reaches this size, 0 for never
+	Keep      int    `yaml:"keep"`        // rotated files kept, as path.1 (newest) to path.N (default 5)
 }
 
 type ThemeConfig struct {
+	// Background and Foreground color the TUI's header bars and text, and
+	// Accent its title and headings, as "#rrggbb" or "#rgb".
 	Background string `yaml:"background"`
 	Foreground string `yaml:"foreground"`
 	Accent     string `yaml:"accent"`
+	// ColorBy is "stream" (default) to color log lines by their stream, or
+	// "level" to color them by severity where a level was detected.
+	ColorBy string `yaml:"color_by"`
+	// SelectionBg and AltRowBg are the background colors of the selected
+	// row and of every other row in the log table, e.g. "#3d5c5c".
+	SelectionBg string `yaml:"selection_bg"`
+	AltRowBg    string `yaml:"alt_row_bg"`
+}
+
+// validate reports a Background, Foreground or Accent that is not a hex
+// color.
+func (t ThemeConfig) validate() error {
+	for _, c := range []struct{ key, value string }{
+		{"background", t.Background},
+		{"foreground", t.Foreground},
+		{"accent", t.Accent},
+	} {
+		if c.value != "" && !isHexColor(c.value) {
+			return fmt.Errorf("theme %s %q is not a hex color like \"#00d9ff\"", c.key, c.value)
+		}
+	}
+	return nil
+}
+
+// isHexColor reports whether s is "#" followed by 3 or 6 hex digits.
+func isHexColor(s string) bool {
+	digits, ok := strings.CutPrefix(s, "#")
+	if !ok || (len(digits) != 3 && len(digits) != 6) {
+		return false
+	}
+	return strings.Trim(strings.ToLower(digits), "0123456789abcdef") == ""
 }
 
 type FilterConfig struct {
@@ -42,6 +291,10 @@ type FilterConfig struct {
 	Pattern string   `yaml:"pattern"`
 	Color   string   `yaml:"color"`
 	Actions []string `yaml:"actions"`
+	// Webhook is the URL the alert action POSTs matches to, at most once
+	// per Cooldown, a duration such as "5m" (default 1m).
+	Webhook  string `yaml:"webhook"`
+	Cooldown string `yaml:"cooldown"`
 }
 
 func Load(path string) (*Config, error) {
@@ -58,6 +311,30 @@ func LoadWithOptions(path string, globalOnly bool) (*Config, error) {
 		path = FindConfigFile(globalOnly)
 	}
 
+	cfg, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		if err := cfg.include(filepath.Dir(abs), map[string]bool{abs: true}); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.Theme.validate(); err != nil {
+		return nil, err
+	}
+
+	// Expand ~ in stream paths
+	for i := range cfg.Streams {
+		cfg.Streams[i].Path = expandPath(cfg.Streams[i].Path)
+	}
+	cfg.Sink.Path = expandPath(cfg.Sink.Path)
+	cfg.Path = path
+
+	return cfg, nil
+}
+
+func readConfig(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config: %w", err)
@@ -67,17 +344,132 @@ func LoadWithOptions(path string, globalOnly bool) (*Config, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
+	return &cfg, nil
+}
 
-	// Expand ~ in stream paths
-	for i := range cfg.Streams {
-		cfg.Streams[i].Path = expandPath(cfg.Streams[i].Path)
+// include merges in the files cfg includes, resolved against dir, after
+// merging in the files those include. including holds the files being
+// included, to catch include cycles.
+func (cfg *Config) include(dir string, including map[string]bool) error {
+	for _, pattern := range cfg.Include {
+		pattern = expandPath(pattern)
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		paths := []string{pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			var err error
+			if paths, err = filepath.Glob(pattern); err != nil {
+				return fmt.Errorf("invalid include %q: %w", pattern, err)
+			}
+		}
+
+		for _, path := range paths {
+			if including[path] {
+				return fmt.Errorf("config %s includes itself", path)
+			}
+			included, err := readConfig(path)
+			if err != nil {
+				return fmt.Errorf("include %s: %w", path, err)
+			}
+			including[path] = true
+			err = included.include(filepath.Dir(path), including)
+			delete(including, path)
+			if err != nil {
+				return err
+			}
+			if err := cfg.merge(included, path); err != nil {
+				return err
+			}
+		}
 	}
+	return nil
+}
 
-	return &cfg, nil
+// merge adds the streams, groups and filters of included, read from path.
+// A stream named like one already defined is an error; groups and filters
+// already defined are kept, so an including file can override them.
+func (cfg *Config) merge(included *Config, path string) error {
+	for _, s := range included.Streams {
+		if slices.ContainsFunc(cfg.Streams, func(o StreamConfig) bool { return o.Name == s.Name }) {
+			return fmt.Errorf("include %s: stream %s is already defined", path, s.Name)
+		}
+		cfg.Streams = append(cfg.Streams, s)
+	}
+	for _, g := range included.Groups {
+		if !slices.ContainsFunc(cfg.Groups, func(o GroupConfig) bool { return o.Name == g.Name }) {
+			cfg.Groups = append(cfg.Groups, g)
+		}
+	}
+	for _, f := range included.Filters {
+		if !slices.ContainsFunc(cfg.Filters, func(o FilterConfig) bool { return o.Name == f.Name }) {
+			cfg.Filters = append(cfg.Filters, f)
+		}
+	}
+	return nil
 }
 
-// expandPath expands ~ to the user's home directory
+// SaveGroups replaces the groups section of the config file at path,
+// creating the file if needed. The rest of the file is kept as written,
+// rather than re-encoding a Config that auto-discovery has added to.
+func SaveGroups(path string, groups []GroupConfig) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	doc := yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s is not a YAML mapping", path)
+	}
+
+	var value yaml.Node
+	if err := value.Encode(groups); err != nil {
+		return err
+	}
+	replaced := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "groups" {
+			root.Content[i+1] = &value
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "groups"}
+		root.Content = append(root.Content, key, &value)
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// expandPath expands environment variables, then ~ to the user's home
+// directory.
 func expandPath(path string) string {
+	path = os.ExpandEnv(path)
 	if len(path) == 0 {
 		return path
 	}
@@ -92,13 +484,19 @@ func expandPath(path string) string {
 }
 
 // FindConfigFile locates the config file. If globalOnly is true, only checks global config location.
+// GlobalConfigPath is where the global config is created when there is
+// none yet.
+func GlobalConfigPath() string {
+	return filepath.Join(os.Getenv("HOME"), ".config", "logdump.yaml")
+}
+
 func FindConfigFile(globalOnly bool) string {
 	var locations []string
 
 	if globalOnly {
 		// MCP mode: only use global config for consistent agent access
 		locations = []string{
-			filepath.Join(os.Getenv("HOME"), ".config", "logdump.yaml"),
+			GlobalConfigPath(),
 			filepath.Join(os.Getenv("HOME"), ".config", "logdump.yml"),
 		}
 	} else {
@@ -108,7 +506,7 @@ func FindConfigFile(globalOnly bool) string {
 			"logdump.yml",
 			".logdump.yaml",
 			".logdump.yml",
-			filepath.Join(os.Getenv("HOME"), ".config", "logdump.yaml"),
+			GlobalConfigPath(),
 			filepath.Join(os.Getenv("HOME"), ".config", "logdump.yml"),
 		}
 	}
@@ -123,15 +521,79 @@ func FindConfigFile(globalOnly bool) string {
 }
 
 func (c *StreamConfig) Matches(path string) bool {
+	name := filepath.Base(path)
+	if c.IsRecursive() {
+		name = c.RelativePath(path)
+	}
+	for _, pattern := range c.ExcludeFiles {
+		if matchPattern(pattern, name) {
+			return false
+		}
+	}
 	for _, pattern := range c.Patterns {
-		matched, err := filepath.Match(pattern, filepath.Base(path))
-		if err == nil && matched {
+		if matchPattern(pattern, name) {
 			return true
 		}
 	}
 	return false
 }
 
+// HasGlobPath reports whether Path is a glob matching several directories.
+func (c *StreamConfig) HasGlobPath() bool {
+	return strings.ContainsAny(c.Path, "*?[")
+}
+
+// IsRecursive reports whether the stream takes in the subdirectories of
+// Path, because it says so or one of its patterns has a "**".
+func (c *StreamConfig) IsRecursive() bool {
+	return c.Recursive || slices.ContainsFunc(c.Patterns, func(p string) bool {
+		return strings.Contains(p, "**")
+	})
+}
+
+// RelativePath returns path relative to Path with slash separators, or its
+// base name if it is not under Path.
+func (c *StreamConfig) RelativePath(path string) string {
+	rel, err := filepath.Rel(c.Path, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.Base(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// matchPattern matches a file pattern against name, a base name or a
+// slash-separated relative path. A pattern without a slash matches the
+// base name, at any depth; otherwise it matches the whole path segment by
+// segment, with "**" standing for any number of directories.
+func matchPattern(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		matched, err := filepath.Match(pattern, pathpkg.Base(name))
+		return err == nil && matched
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if matched, err := pathpkg.Match(pattern[0], name[0]); err != nil || !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
 // colors for auto-discovered streams
 var streamColors = []string{"cyan", "green", "yellow", "magenta", "blue", "red"}
 
@@ -152,12 +614,10 @@ func (cfg *Config) AutoDiscover(exclude map[string]bool) error {
 	}
 
 	// Find all .log and .txt files
-	files, err := filepath.Glob(filepath.Join(logDir, "*.log"))
+	files, err := discoverFiles(logDir, cfg.DiscoverRecursive)
 	if err != nil {
 		return err
 	}
-	txtFiles, _ := filepath.Glob(filepath.Join(logDir, "*.txt"))
-	files = append(files, txtFiles...)
 
 	// Create a stream for each file
 	existingStreams := make(map[string]bool)
@@ -165,28 +625,141 @@ func (cfg *Config) AutoDiscover(exclude map[string]bool) error {
 		existingStreams[s.Name] = true
 	}
 
+	discovered := make(map[string]int) // stream name -> index in cfg.Streams
 	colorIdx := len(cfg.Streams)
+	var topLevel []string // files directly in logDir
 	for _, file := range files {
 		base := filepath.Base(file)
-		name := base[:len(base)-len(filepath.Ext(base))] // Remove extension
+		name := strings.TrimSuffix(base, ".gz")
+		name = name[:len(name)-len(filepath.Ext(name))] // Remove extension
+		if dir, _ := filepath.Rel(logDir, filepath.Dir(file)); dir != "." {
+			name = filepath.ToSlash(dir) + "/" + name
+		} else {
+			topLevel = append(topLevel, file)
+		}
+
+		// Compressed history joins the stream of its live file
+		if idx, ok := discovered[name]; ok {
+			cfg.Streams[idx].Patterns = append(cfg.Streams[idx].Patterns, base)
+			continue
+		}
 
 		// Skip if excluded or already defined
 		if exclude[name] || existingStreams[name] {
 			continue
 		}
 
+		discovered[name] = len(cfg.Streams)
+
 		cfg.Streams = append(cfg.Streams, StreamConfig{
-			Name:     name,
-			Path:     logDir,
-			Patterns: []string{base},
-			Color:    streamColors[colorIdx%len(streamColors)],
+			Name:           name,
+			Path:           filepath.Dir(file),
+			Patterns:       []string{base},
+			Color:          streamColors[colorIdx%len(streamColors)],
+			IncludeRotated: true,
 		})
 		colorIdx++
 	}
 
+	if cfg.CatchAll && !exclude[CatchAllStream] && !existingStreams[CatchAllStream] {
+		cfg.Streams = append(cfg.Streams, cfg.catchAllStream(logDir, topLevel, exclude))
+	}
+
 	return nil
 }
 
+// discoverPatterns are the files auto-discovery makes streams of, in the
+// order it considers them: compressed history last, so it can join the
+// stream of its live file.
+var discoverPatterns = []string{"*.log", "*.txt", "*.log.gz"}
+
+// discoverFiles returns the files in logDir, and its subdirectories if
+// recursive, matching discoverPatterns.
+func discoverFiles(logDir string, recursive bool) ([]string, error) {
+	var files []string
+	for _, pattern := range discoverPatterns {
+		if !recursive {
+			matches, err := filepath.Glob(filepath.Join(logDir, pattern))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, matches...)
+			continue
+		}
+		err := filepath.WalkDir(logDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// Skip unreadable subdirectories
+				if path == logDir {
+					return err
+				}
+				return fs.SkipDir
+			}
+			if matched, _ := filepath.Match(pattern, d.Name()); matched && !d.IsDir() {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// Snippet returns c as an entry of the streams list, ready to paste into a
+// config file. Only the fields auto-discovery fills in are included, so a
+// discovered stream can be made explicit and then customized.
+func (c *StreamConfig) Snippet() (string, error) {
+	entry := []struct {
+		Name           string   `yaml:"name"`
+		Source         string   `yaml:"source,omitempty"`
+		Command        []string `yaml:"command,omitempty"`
+		Listen         string   `yaml:"listen,omitempty"`
+		Path           string   `yaml:"path,omitempty"`
+		Patterns       []string `yaml:"patterns,omitempty"`
+		Color          string   `yaml:"color,omitempty"`
+		ExcludeFiles   []string `yaml:"exclude_files,omitempty"`
+		IncludeRotated bool     `yaml:"include_rotated,omitempty"`
+		Recursive      bool     `yaml:"recursive,omitempty"`
+	}{{c.Name, c.Source, c.Command, c.Listen, c.Path, c.Patterns, c.Color, c.ExcludeFiles, c.IncludeRotated, c.Recursive}}
+
+	data, err := yaml.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// CatchAllStream is the name of the stream created by the catch_all option.
+const CatchAllStream = "other"
+
+// catchAllStream builds a stream matching every file in logDir except those
+// claimed by another stream in that directory, hidden files, and the files
+// of excluded auto-discovered streams.
+func (cfg *Config) catchAllStream(logDir string, discovered []string, exclude map[string]bool) StreamConfig {
+	excludeFiles := []string{".*"}
+	for _, s := range cfg.Streams {
+		if filepath.Clean(s.Path) == filepath.Clean(logDir) {
+			excludeFiles = append(excludeFiles, s.Patterns...)
+		}
+	}
+	for _, file := range discovered {
+		base := filepath.Base(file)
+		name := strings.TrimSuffix(base, ".gz")
+		if exclude[name[:len(name)-len(filepath.Ext(name))]] {
+			excludeFiles = append(excludeFiles, base)
+		}
+	}
+
+	return StreamConfig{
+		Name:         CatchAllStream,
+		Path:         logDir,
+		Patterns:     []string{"*"},
+		ExcludeFiles: excludeFiles,
+		Color:        "white",
+	}
+}
+
 // DefaultLogDir returns the default log directory path
 func DefaultLogDir() string {
 	home, _ := os.UserHomeDir()
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a config file at path under dir, creating its
+// directory, and returns the file's full path.
+func writeConfig(t *testing.T, dir, path, data string) string {
+	t.Helper()
+	path = filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func streamNames(cfg *Config) []string {
+	var names []string
+	for _, s := range cfg.Streams {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestIncludeResolvesPaths(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", filepath.Join(dir, "home"))
+	t.Setenv("LOGDUMP_TEST_CONF", filepath.Join(dir, "env"))
+
+	writeConfig(t, dir, "conf/relative.yaml", "streams:\n  - name: relative\n    path: /var/log/relative.log\n")
+	writeConfig(t, dir, "home/tilde.yaml", "streams:\n  - name: tilde\n    path: /var/log/tilde.log\n")
+	writeConfig(t, dir, "env/env.yaml", "streams:\n  - name: env\n    path: /var/log/env.log\n")
+	writeConfig(t, dir, "conf/globbed/a.yaml", "streams:\n  - name: a\n    path: /var/log/a.log\n")
+	writeConfig(t, dir, "conf/globbed/b.yaml", "streams:\n  - name: b\n    path: /var/log/b.log\n")
+	path := writeConfig(t, dir, "conf/logdump.yaml", `include:
+  - relative.yaml
+  - ~/tilde.yaml
+  - $LOGDUMP_TEST_CONF/env.yaml
+  - globbed/*.yaml
+streams:
+  - name: main
+    path: /var/log/main.log
+`)
+
+	// Load from another directory, so relative includes can't be
+	// resolved against the working directory by accident
+	t.Chdir(t.TempDir())
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(streamNames(cfg), " ")
+	if want := "main relative tilde env a b"; got != want {
+		t.Errorf("streams %q, want %q", got, want)
+	}
+}
+
+func TestIncludeMerges(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, dir, "shared.yaml", `streams:
+  - name: shared
+    path: /var/log/shared.log
+groups:
+  - name: web
+    streams: [shared]
+  - name: all
+    streams: [shared]
+filters:
+  - name: quiet
+    pattern: DEBUG
+  - name: errors
+    pattern: ERROR
+`)
+	path := writeConfig(t, dir, "logdump.yaml", `include: [shared.yaml]
+streams:
+  - name: main
+    path: /var/log/main.log
+groups:
+  - name: web
+    streams: [main]
+filters:
+  - name: quiet
+    pattern: TRACE
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(streamNames(cfg), " "); got != "main shared" {
+		t.Errorf("streams %q, want %q", got, "main shared")
+	}
+
+	// The including file's groups and filters win over included ones
+	if len(cfg.Groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(cfg.Groups))
+	}
+	if g := cfg.Groups[0]; g.Name != "web" || strings.Join(g.Streams, " ") != "main" {
+		t.Errorf("group web is %+v, want the including file's", g)
+	}
+	if g := cfg.Groups[1]; g.Name != "all" || strings.Join(g.Streams, " ") != "shared" {
+		t.Errorf("group all is %+v, want the included file's", g)
+	}
+	if len(cfg.Filters) != 2 {
+		t.Fatalf("got %d filters, want 2", len(cfg.Filters))
+	}
+	if f := cfg.Filters[0]; f.Name != "quiet" || f.Pattern != "TRACE" {
+		t.Errorf("filter quiet is %+v, want the including file's", f)
+	}
+	if f := cfg.Filters[1]; f.Name != "errors" {
+		t.Errorf("second filter is %+v, want errors", f)
+	}
+}
+
+func TestIncludeNested(t *testing.T) {
+	dir := t.TempDir()
+	// inner.yaml is relative to middle.yaml, not to the top file
+	writeConfig(t, dir, "sub/inner.yaml", "streams:\n  - name: inner\n    path: /var/log/inner.log\n")
+	writeConfig(t, dir, "sub/middle.yaml", "include: [inner.yaml]\nstreams:\n  - name: middle\n    path: /var/log/middle.log\n")
+	path := writeConfig(t, dir, "logdump.yaml", "include: [sub/middle.yaml]\nstreams:\n  - name: top\n    path: /var/log/top.log\n")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(streamNames(cfg), " "), "top middle inner"; got != want {
+		t.Errorf("streams %q, want %q", got, want)
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "conflicting stream",
+			files: map[string]string{
+				"logdump.yaml": "include: [other.yaml]\nstreams:\n  - name: app\n    path: /a.log\n",
+				"other.yaml":   "streams:\n  - name: app\n    path: /b.log\n",
+			},
+			want: "stream app is already defined",
+		},
+		{
+			name: "conflict between included files",
+			files: map[string]string{
+				"logdump.yaml": "include: [one.yaml, two.yaml]\n",
+				"one.yaml":     "streams:\n  - name: app\n    path: /a.log\n",
+				"two.yaml":     "streams:\n  - name: app\n    path: /b.log\n",
+			},
+			want: "stream app is already defined",
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"logdump.yaml": "include: [other.yaml]\n",
+				"other.yaml":   "include: [logdump.yaml]\n",
+			},
+			want: "includes itself",
+		},
+		{
+			name: "missing file",
+			files: map[string]string{
+				"logdump.yaml": "include: [missing.yaml]\n",
+			},
+			want: "missing.yaml",
+		},
+		{
+			name: "invalid yaml",
+			files: map[string]string{
+				"logdump.yaml": "include: [other.yaml]\n",
+				"other.yaml":   "streams: [\n",
+			},
+			want: "failed to parse config",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, data := range tt.files {
+				writeConfig(t, dir, name, data)
+			}
+			_, err := Load(filepath.Join(dir, "logdump.yaml"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
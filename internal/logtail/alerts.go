@@ -0,0 +1,185 @@
+package logtail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// defaultAlertCooldown is the shortest time between two calls to a
+// filter's webhook when the filter sets no cooldown.
+const defaultAlertCooldown = time.Minute
+
+// alertAttempts is how many times a webhook call is tried, waiting
+// alertBackoff after the first failure and twice as long after each next.
+const (
+	alertAttempts = 3
+	alertBackoff  = time.Second
+)
+
+var alertClient = &http.Client{Timeout: 10 * time.Second}
+
+// AlertPayload is the JSON body POSTed to a filter's webhook.
+type AlertPayload struct {
+	Filter    string    `json:"filter"`
+	Stream    string    `json:"stream"`
+	Timestamp time.Time `json:"timestamp"`
+	Line      string    `json:"line"`
+	// Count is how many lines matched since the previous call, the one
+	// in Line being the latest.
+	Count int `json:"count"`
+}
+
+// alerter calls a filter's webhook for its matches, at most once per
+// cooldown. Matches within the cooldown are counted and reported together
+// when it ends.
+type alerter struct {
+	manager  *Manager
+	filter   string
+	webhook  string
+	cooldown time.Duration
+
+	mu     sync.Mutex
+	latest AlertPayload // newest match not yet sent
+	count  int          // matches not yet sent
+	next   time.Time    // when the webhook may be called again
+	timer  *time.Timer  // sends the matches held back by the cooldown
+}
+
+// newAlerter checks the webhook and cooldown of the filter named name.
+func newAlerter(m *Manager, name, webhook, cooldown string) (*alerter, error) {
+	if webhook == "" {
+		return nil, fmt.Errorf("filter %s: the alert action needs a webhook", name)
+	}
+	u, err := url.Parse(webhook)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("filter %s: webhook %q is not an http(s) URL", name, webhook)
+	}
+	a := &alerter{manager: m, filter: name, webhook: webhook, cooldown: defaultAlertCooldown}
+	if cooldown != "" {
+		if a.cooldown, err = time.ParseDuration(cooldown); err != nil || a.cooldown < 0 {
+			return nil, fmt.Errorf("filter %s: invalid cooldown %q", name, cooldown)
+		}
+	}
+	return a, nil
+}
+
+// match reports a matching entry, calling the webhook now unless it is
+// cooling down.
+func (a *alerter) match(entry LogEntry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.count++
+	a.latest = AlertPayload{
+		Filter:    a.filter,
+		Stream:    entry.Source,
+		Timestamp: entry.Timestamp,
+		Line:      entry.Content,
+	}
+
+	now := time.Now()
+	if now.Before(a.next) {
+		if a.timer == nil {
+			a.timer = time.AfterFunc(a.next.Sub(now), a.flush)
+		}
+		return
+	}
+	a.send(now)
+}
+
+// flush sends the matches held back until the end of the cooldown.
+func (a *alerter) flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.timer = nil
+	if a.count > 0 {
+		a.send(time.Now())
+	}
+}
+
+// send calls the webhook in the background with the matches so far.
+// a.mu must be held.
+func (a *alerter) send(now time.Time) {
+	payload := a.latest
+	payload.Count = a.count
+	a.count = 0
+	a.next = now.Add(a.cooldown)
+
+	ctx := a.manager.ctx
+	if ctx.Err() != nil {
+		return
+	}
+	go func() {
+		if err := postAlert(ctx, a.webhook, payload); err != nil && ctx.Err() == nil {
+			a.manager.logAlert(fmt.Sprintf("alert %s: webhook %s failed after %d attempts: %v",
+				a.filter, a.webhook, alertAttempts, err))
+		}
+	}()
+}
+
+// postAlert POSTs payload to webhook, retrying with backoff until it
+// answers with a 2xx status.
+func postAlert(ctx context.Context, webhook string, payload AlertPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	backoff := alertBackoff
+	for attempt := 1; ; attempt++ {
+		err = postOnce(ctx, webhook, body)
+		if err == nil || attempt == alertAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func postOnce(ctx context.Context, webhook string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := alertClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
+
+// SetAlertLog sets where failed webhook calls are reported: log writes
+// them to the file at path. Without it they are not reported. Lines of
+// that file, if it is tailed, are not matched by alert filters, so a
+// failure cannot raise an alert of its own. Call it before Tail.
+func (m *Manager) SetAlertLog(path string, log func(message string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.alertLog, m.alertLogPath = log, path
+}
+
+func (m *Manager) logAlert(message string) {
+	m.mu.RLock()
+	log := m.alertLog
+	m.mu.RUnlock()
+	if log != nil {
+		log(message)
+	}
+}
@@ -0,0 +1,263 @@
+package logtail
+
+import (
+	"time"
+	"unsafe"
+)
+
+// buffer holds the Manager's entries in one ring per source, each with
+// its own capacity, so a chatty stream only ever evicts its own history.
+// Merged views are in Seq order, the order entries were added in. buffer
+// is not safe for concurrent use; the Manager guards it with bufferMu.
+type buffer struct {
+	parts    map[string]*partition
+	capacity int // entries per source, zero meaning unbounded
+	size     int
+	bytes    int64
+	memory   int64  // estimated heap held by the entries, see entryMemory
+	evicted  uint64 // highest Seq evicted to stay within the policy
+}
+
+type partition struct {
+	ring    *Ring[LogEntry]
+	bytes   int64
+	memory  int64
+	evicted int64 // entries evicted to make room, not counting purges
+}
+
+// entryOverhead is the fixed cost of an entry held in a ring.
+const entryOverhead = int64(unsafe.Sizeof(LogEntry{}))
+
+// entryMemory estimates the memory entry holds: the struct itself plus the
+// strings and fields it references. Strings shared between entries, like
+// Source, are counted each time, so this errs on the high side.
+func entryMemory(entry LogEntry) int64 {
+	n := entryOverhead + int64(len(entry.Source)+len(entry.Content)+len(entry.Level))
+	for _, tag := range entry.Tags {
+		n += int64(len(tag)) + 16
+	}
+	for k, v := range entry.Fields {
+		n += int64(len(k)+len(v)) + 32
+	}
+	return n
+}
+
+func newBuffer(capacity int) *buffer {
+	return &buffer{
+		parts:    make(map[string]*partition),
+		capacity: max(capacity, 0),
+	}
+}
+
+// push adds entry to its source's ring, evicting that source's oldest
+// entry if the ring is full.
+func (b *buffer) push(entry LogEntry) {
+	p, ok := b.parts[entry.Source]
+	if !ok {
+		p = &partition{ring: NewRing[LogEntry](b.capacity)}
+		b.parts[entry.Source] = p
+	}
+
+	size, memory := int64(len(entry.Content)), entryMemory(entry)
+	if old, evicted := p.ring.Push(entry); evicted {
+		b.dropped(p, old)
+	}
+	b.size++
+	p.bytes += size
+	b.bytes += size
+	p.memory += memory
+	b.memory += memory
+}
+
+// popOldest evicts the oldest entry of p.
+func (b *buffer) popOldest(p *partition) {
+	if old, ok := p.ring.PopOldest(); ok {
+		b.dropped(p, old)
+	}
+}
+
+// dropped accounts for old having been evicted from p.
+func (b *buffer) dropped(p *partition, old LogEntry) {
+	b.removed(p, old)
+	b.evicted = max(b.evicted, old.Seq)
+	p.evicted++
+}
+
+// removed accounts for old no longer being held by p.
+func (b *buffer) removed(p *partition, old LogEntry) {
+	size, memory := int64(len(old.Content)), entryMemory(old)
+	b.size--
+	p.bytes -= size
+	b.bytes -= size
+	p.memory -= memory
+	b.memory -= memory
+}
+
+// evict drops entries until the buffer satisfies the policy's byte, age and
+// memory limits; entry counts are bounded by the rings themselves. Over the
+// byte limit, the source holding the most bytes gives up its oldest entry,
+// so a quiet stream keeps its share. The newest entry is always kept.
+func (b *buffer) evict(policy BufferPolicy, now time.Time) {
+	if policy.MaxAge > 0 {
+		for _, p := range b.parts {
+			for b.size > 1 && p.ring.Len() > 0 && now.Sub(p.ring.At(0).Timestamp) > policy.MaxAge {
+				b.popOldest(p)
+			}
+		}
+	}
+
+	for policy.MaxBytes > 0 && b.bytes > policy.MaxBytes && b.size > 1 {
+		var largest *partition
+		for _, p := range b.parts {
+			if p.ring.Len() > 0 && (largest == nil || p.bytes > largest.bytes) {
+				largest = p
+			}
+		}
+		b.popOldest(largest)
+	}
+
+	if policy.MaxMemory > 0 && b.memory > policy.MaxMemory {
+		b.trimMemory(policy.MaxMemory * 9 / 10)
+	}
+}
+
+// trimMemory brings the estimated memory down to target, each source giving
+// up its oldest entries in proportion to how much it holds. Trimming below
+// the limit leaves room for the next entries, so this runs now and then
+// rather than on every push.
+func (b *buffer) trimMemory(target int64) {
+	excess, total := b.memory-target, b.memory
+	for _, p := range b.parts {
+		// Rounding each share up frees at least the excess overall
+		share := (excess*p.memory + total - 1) / total
+		for start := p.memory; start-p.memory < share && p.ring.Len() > 0 && b.size > 1; {
+			b.popOldest(p)
+		}
+	}
+}
+
+// resize moves every source to a ring of the new capacity, keeping its
+// newest entries.
+func (b *buffer) resize(capacity int) {
+	capacity = max(capacity, 0)
+	if capacity == b.capacity {
+		return
+	}
+	b.capacity = capacity
+	for _, p := range b.parts {
+		old := p.ring
+		if capacity > 0 {
+			for old.Len() > capacity {
+				b.popOldest(p)
+			}
+		}
+		p.ring = NewRing[LogEntry](capacity)
+		old.Each(func(entry LogEntry) bool {
+			p.ring.Push(entry)
+			return true
+		})
+	}
+}
+
+// filter removes the entries keep returns false for, and the sources left
+// empty.
+func (b *buffer) filter(keep func(LogEntry) bool) {
+	for source, p := range b.parts {
+		p.ring.Filter(func(entry LogEntry) bool {
+			if keep(entry) {
+				return true
+			}
+			b.removed(p, entry)
+			return false
+		})
+		if p.ring.Len() == 0 {
+			delete(b.parts, source)
+		}
+	}
+}
+
+// each calls fn on the entries of source, or of every source if it is
+// empty, oldest first, until fn returns false.
+func (b *buffer) each(source string, fn func(LogEntry) bool) {
+	if source != "" {
+		if p, ok := b.parts[source]; ok {
+			p.ring.Each(fn)
+		}
+		return
+	}
+	b.merge(false, fn)
+}
+
+// reverse is each, newest first.
+func (b *buffer) reverse(source string, fn func(LogEntry) bool) {
+	if source != "" {
+		if p, ok := b.parts[source]; ok {
+			p.ring.Reverse(fn)
+		}
+		return
+	}
+	b.merge(true, fn)
+}
+
+// merge walks every source's ring together in Seq order, newest first if
+// reverse is set. Each ring is already in Seq order, so this picks the next
+// entry across the rings' heads.
+func (b *buffer) merge(reverse bool, fn func(LogEntry) bool) {
+	rings := make([]*Ring[LogEntry], 0, len(b.parts))
+	for _, p := range b.parts {
+		rings = append(rings, p.ring)
+	}
+	if len(rings) == 1 {
+		if reverse {
+			rings[0].Reverse(fn)
+		} else {
+			rings[0].Each(fn)
+		}
+		return
+	}
+
+	// pos[i] counts the entries of rings[i] already visited
+	pos := make([]int, len(rings))
+	for {
+		next := -1
+		var nextEntry LogEntry
+		for i, r := range rings {
+			if pos[i] == r.Len() {
+				continue
+			}
+			idx := pos[i]
+			if reverse {
+				idx = r.Len() - 1 - pos[i]
+			}
+			entry := r.At(idx)
+			if next < 0 || (entry.Seq < nextEntry.Seq) != reverse {
+				next, nextEntry = i, entry
+			}
+		}
+		if next < 0 || !fn(nextEntry) {
+			return
+		}
+		pos[next]++
+	}
+}
+
+// snapshot copies every entry, in Seq order.
+func (b *buffer) snapshot() []LogEntry {
+	entries := make([]LogEntry, 0, b.size)
+	b.each("", func(entry LogEntry) bool {
+		entries = append(entries, entry)
+		return true
+	})
+	return entries
+}
+
+// fillStats sets the Buffered and Evicted counts of the sources in stats.
+func (b *buffer) fillStats(stats map[string]StreamStats) {
+	for name, p := range b.parts {
+		if st, ok := stats[name]; ok {
+			st.Buffered = p.ring.Len()
+			st.Evicted = p.evicted
+			stats[name] = st
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package logtail
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// addEntries adds n entries for source to m's buffer.
+func addEntries(m *Manager, source string, n int) {
+	for i := range n {
+		m.AddEntry(LogEntry{Timestamp: time.Now(), Source: source, Content: fmt.Sprintf("%s %d", source, i)})
+	}
+}
+
+// seqs returns the Seq of each entry.
+func seqs(entries []LogEntry) []uint64 {
+	var out []uint64
+	for _, e := range entries {
+		out = append(out, e.Seq)
+	}
+	return out
+}
+
+func TestCursorAcrossWraparound(t *testing.T) {
+	m := newTestManager(t)
+	m.SetBufferPolicy(BufferPolicy{MaxEntries: 10})
+
+	addEntries(m, "app", 5)
+	entries, next, truncated := m.GetEntriesSinceFunc(0, 0, func(LogEntry) bool { return true })
+	if len(entries) != 5 || entries[0].Seq != 1 || next != 5 || truncated {
+		t.Fatalf("first read: seqs %v, next %d, truncated %v", seqs(entries), next, truncated)
+	}
+
+	// The ring wraps around twice; what the cursor missed is reported
+	addEntries(m, "app", 20)
+	entries, next, truncated = m.GetEntriesSinceFunc(next, 0, func(LogEntry) bool { return true })
+	if len(entries) != 10 || entries[0].Seq != 16 || next != 25 || !truncated {
+		t.Fatalf("read after wrapping: seqs %v, next %d, truncated %v", seqs(entries), next, truncated)
+	}
+	entries, next, truncated = m.GetEntriesSinceFunc(next, 0, func(LogEntry) bool { return true })
+	if len(entries) != 0 || next != 25 || truncated {
+		t.Fatalf("read with nothing new: seqs %v, next %d, truncated %v", seqs(entries), next, truncated)
+	}
+
+	// A limit pages through what is left, without gaps or repeats
+	var paged []uint64
+	for cursor := uint64(15); ; {
+		entries, next = m.GetEntriesSince("app", cursor, 4)
+		if len(entries) == 0 {
+			break
+		}
+		paged = append(paged, seqs(entries)...)
+		cursor = next
+	}
+	if len(paged) != 10 || paged[0] != 16 || paged[9] != 25 {
+		t.Errorf("paged through seqs %v, want 16 to 25", paged)
+	}
+}
+
+func TestCursorWithConcurrentWriters(t *testing.T) {
+	const writers, perWriter = 8, 1000
+	m := newTestManager(t)
+	m.SetBufferPolicy(BufferPolicy{})
+
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			addEntries(m, fmt.Sprintf("writer%d", w), perWriter)
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// A reader polling as entries are added sees each exactly once, in
+	// order, then the rest once the writers are done
+	var cursor uint64
+	seen := 0
+	for finished := false; ; {
+		entries, next := m.GetEntriesSince("", cursor, 100)
+		if len(entries) == 0 && finished {
+			break
+		}
+		for _, e := range entries {
+			if e.Seq != cursor+1 {
+				t.Fatalf("read seq %d after %d", e.Seq, cursor)
+			}
+			cursor = e.Seq
+			seen++
+		}
+		if next < cursor {
+			t.Fatalf("next cursor %d after reading up to %d", next, cursor)
+		}
+		select {
+		case <-done:
+			finished = true
+		case <-time.After(time.Millisecond):
+		}
+	}
+	if seen != writers*perWriter || m.Cursor() != writers*perWriter {
+		t.Errorf("read %d entries, cursor %d, want %d", seen, m.Cursor(), writers*perWriter)
+	}
+}
+
+func TestChattyStreamKeepsOthersHistory(t *testing.T) {
+	m := newTestManager(t)
+	m.SetBufferPolicy(BufferPolicy{MaxEntries: 10})
+
+	addEntries(m, "quiet", 3)
+	addEntries(m, "chatty", 1000)
+
+	if got := contents(m.GetEntries("quiet", 100)); len(got) != 3 || got[0] != "quiet 0" {
+		t.Errorf("quiet stream kept %q, want all 3 entries", got)
+	}
+	if got := contents(m.GetEntries("chatty", 100)); len(got) != 10 || got[0] != "chatty 990" {
+		t.Errorf("chatty stream kept %q, want its newest 10", got)
+	}
+	results, err := m.Search(context.Background(), "quiet", "", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for range results {
+		n++
+	}
+	if n != 3 {
+		t.Errorf("search found %d of the quiet stream's entries, want 3", n)
+	}
+
+	// The merged view is in the order entries were added
+	all := m.GetBuffer()
+	if len(all) != 13 || !slices.IsSortedFunc(all, func(a, b LogEntry) int { return cmp.Compare(a.Seq, b.Seq) }) {
+		t.Errorf("GetBuffer returned seqs %v, want 13 in order", seqs(all))
+	}
+	if chatty, quiet := m.buffer.parts["chatty"].evicted, m.buffer.parts["quiet"].evicted; chatty != 990 || quiet != 0 {
+		t.Errorf("evicted %d chatty and %d quiet entries, want 990 and 0", chatty, quiet)
+	}
+}
+
+func TestBufferConcurrentAccess(t *testing.T) {
+	const sources, perSource, limit = 4, 2000, 100
+	m := newTestManager(t)
+	m.SetBufferPolicy(BufferPolicy{MaxEntries: limit})
+
+	var started, writers sync.WaitGroup
+	for s := range sources {
+		started.Add(1)
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			started.Done()
+			addEntries(m, fmt.Sprintf("s%d", s), perSource)
+		}()
+	}
+
+	// Read while the writers run, for a bounded number of rounds that
+	// yield to them, so a single CPU can't be kept busy with searches
+	started.Wait()
+	for range 200 {
+		m.GetEntries("s0", 10)
+		m.GetEntries("", 10)
+		m.GetBuffer()
+		m.Stats()
+		if results, err := m.Search(context.Background(), "s1", "", 10); err == nil {
+			for range results {
+			}
+		}
+		runtime.Gosched()
+	}
+	writers.Wait()
+
+	for s := range sources {
+		source := fmt.Sprintf("s%d", s)
+		entries := m.GetEntries(source, 0)
+		if len(entries) != limit || entries[limit-1].Content != fmt.Sprintf("%s %d", source, perSource-1) {
+			t.Errorf("%s kept %d entries, want its newest %d", source, len(entries), limit)
+		}
+		if !slices.IsSortedFunc(entries, func(a, b LogEntry) int { return cmp.Compare(a.Seq, b.Seq) }) {
+			t.Errorf("%s's entries are out of order: %v", source, seqs(entries))
+		}
+	}
+	if n := m.BufferLen(); n != sources*limit {
+		t.Errorf("buffer holds %d entries, want %d", n, sources*limit)
+	}
+}
@@ -0,0 +1,168 @@
+package logtail
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// checkpointInterval is how often read offsets are saved while tailing.
+const checkpointInterval = 5 * time.Second
+
+// DefaultCheckpointPath is where read offsets are kept between runs:
+// ~/.local/share/logdump/state.json.
+func DefaultCheckpointPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "share", "logdump", "state.json")
+}
+
+// checkpoints are the read offsets of files, keyed by fileID so a file is
+// recognised after being renamed and a new file at the same path is not
+// mistaken for the old one.
+type checkpoints struct {
+	path  string
+	mu    sync.Mutex
+	files map[string]fileCheckpoint
+}
+
+type fileCheckpoint struct {
+	Path   string `json:"path"`
+	Offset int64  `json:"offset"`
+}
+
+type checkpointState struct {
+	Files map[string]fileCheckpoint `json:"files"`
+}
+
+// loadCheckpoints reads the state file at path. A missing file is an empty
+// state.
+func loadCheckpoints(path string) (*checkpoints, error) {
+	c := &checkpoints{path: path, files: make(map[string]fileCheckpoint)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state checkpointState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	if state.Files != nil {
+		c.files = state.Files
+	}
+	return c, nil
+}
+
+// lookup returns where to resume reading the file id found at path. A file
+// at a path last seen with another id was rotated while logdump was down,
+// so it is read from the start. ok is false for a path never checkpointed.
+func (c *checkpoints) lookup(id, path string) (offset int64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if f, ok := c.files[id]; ok {
+		return f.Offset, true
+	}
+	for _, f := range c.files {
+		if f.Path == path {
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+// save records the offsets of streams and writes the state file. Files
+// checkpointed by earlier runs but not tailed now are kept.
+func (c *checkpoints) save(streams []*Stream) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, s := range streams {
+		if !s.onDisk() {
+			continue
+		}
+		id, offset := s.checkpoint()
+		if id == "" {
+			continue
+		}
+		// A path holds one file at a time
+		for other, f := range c.files {
+			if f.Path == s.Path && other != id {
+				delete(c.files, other)
+			}
+		}
+		c.files[id] = fileCheckpoint{Path: s.Path, Offset: offset}
+	}
+
+	data, err := json.MarshalIndent(checkpointState{Files: c.files}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	// Write a temporary file and rename it, so a crash never leaves a
+	// partial state file
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// checkpoint returns the id of the stream's current file and how much of
+// it has been read.
+func (s *Stream) checkpoint() (id string, offset int64) {
+	s.fileMu.Lock()
+	defer s.fileMu.Unlock()
+	return s.fileID, s.offset.Load()
+}
+
+// ResumeFrom loads the read offsets saved at path, so files tailed from now
+// on start where the previous run stopped reading them instead of loading
+// their history. Offsets are saved back to path periodically and on Close.
+func (m *Manager) ResumeFrom(path string) error {
+	c, err := loadCheckpoints(path)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.checkpoints = c
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(checkpointInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-ticker.C:
+				_ = m.saveCheckpoints()
+			}
+		}
+	}()
+	return nil
+}
+
+// saveCheckpoints writes the offsets of every stream, if ResumeFrom was
+// called.
+func (m *Manager) saveCheckpoints() error {
+	m.mu.RLock()
+	c := m.checkpoints
+	streams := make([]*Stream, 0, len(m.streams))
+	for _, s := range m.streams {
+		streams = append(streams, s)
+	}
+	m.mu.RUnlock()
+
+	if c == nil {
+		return nil
+	}
+	return c.save(streams)
+}
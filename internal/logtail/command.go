@@ -0,0 +1,209 @@
+package logtail
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/appgram/logdump/internal/config"
+)
+
+const (
+	// commandBackoff is how long to wait before restarting a command that
+	// exited, doubling on each quick exit up to commandMaxBackoff.
+	commandBackoff    = time.Second
+	commandMaxBackoff = time.Minute
+	// commandStable is how long a command must run for its next exit to
+	// be restarted after the initial backoff again.
+	commandStable = time.Minute
+	// commandWaitDelay bounds how long to wait for output pipes held open
+	// by a command's children once it has exited or been killed.
+	commandWaitDelay = 2 * time.Second
+)
+
+// commandPath stands in for the path of a command stream: the command line.
+func commandPath(cfg config.StreamConfig) string {
+	return "$ " + strings.Join(cfg.Command, " ")
+}
+
+// Process returns the PID of the stream's command, 0 while it is not
+// running, and how many times it has been restarted.
+func (s *Stream) Process() (pid int, restarts int64) {
+	return int(s.pid.Load()), s.restarts.Load()
+}
+
+// tailCommand adds the stream running cfg's command.
+func (m *Manager) tailCommand(cfg config.StreamConfig) error {
+	if len(cfg.Command) == 0 {
+		return fmt.Errorf("stream %s: source %q needs a command", cfg.Name, config.SourceCommand)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path := commandPath(cfg)
+	if _, ok := m.streams[path]; ok {
+		return nil
+	}
+	t, ok := m.tails[cfg.Name]
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(t.ctx)
+	stream, err := m.newStream(cfg, path, cancel, nil)
+	if err != nil {
+		cancel()
+		return err
+	}
+	stream.WatchMode = WatchCommand
+	m.streams[path] = stream
+	go stream.runCommand(ctx, m.entries)
+	return nil
+}
+
+// runCommand runs the stream's command until ctx is cancelled, restarting
+// it with exponential backoff whenever it exits.
+func (s *Stream) runCommand(ctx context.Context, entries chan<- LogEntry) {
+	defer close(s.Done)
+
+	// A command has no history to load
+	s.finishHistory()
+
+	backoff := commandBackoff
+	for {
+		started := time.Now()
+		err := s.runCommandOnce(ctx, entries)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if !s.flushPending(ctx, entries) {
+			return
+		}
+		status := "exited"
+		if err != nil {
+			status = err.Error()
+		}
+		if time.Since(started) > commandStable {
+			backoff = commandBackoff
+		}
+		marker := LogEntry{
+			Timestamp: time.Now(),
+			Source:    s.Config.Name,
+			Content:   fmt.Sprintf("--- command %s, restarting in %s ---", status, backoff),
+			Tags:      s.Config.Tags,
+		}
+		if !s.emit(ctx, entries, marker) {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, commandMaxBackoff)
+		s.restarts.Add(1)
+	}
+}
+
+type commandLine struct {
+	line       string
+	n, dropped int64
+	stderr     bool
+}
+
+// runCommandOnce starts the command and emits its output until it exits or
+// ctx is cancelled, which kills it. Either way the process is waited for,
+// so it never lingers as a zombie.
+func (s *Stream) runCommandOnce(ctx context.Context, entries chan<- LogEntry) error {
+	cmd := exec.CommandContext(ctx, s.Config.Command[0], s.Config.Command[1:]...)
+	cmd.WaitDelay = commandWaitDelay
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	s.pid.Store(int64(cmd.Process.Pid))
+	defer s.pid.Store(0)
+
+	lines := make(chan commandLine)
+	var wg sync.WaitGroup
+	for _, pipe := range []struct {
+		r      io.Reader
+		stderr bool
+	}{{stdout, false}, {stderr, true}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reader := bufio.NewReader(pipe.r)
+			for {
+				line, n, dropped, err := readLine(reader, s.maxLine)
+				if line != "" {
+					select {
+					case lines <- commandLine{line, n, dropped, pipe.stderr}:
+					case <-ctx.Done():
+						return
+					}
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+
+	// Wait closes the pipes, so it is only called once both are drained,
+	// or once ctx is done and their remaining output is unwanted
+	defer func() {
+		for range lines {
+		}
+	}()
+
+	for {
+		// Wake up to flush a pending entry if no line follows it
+		var expired <-chan time.Time
+		if remaining, ok := s.pendingWait(time.Now()); ok {
+			expired = time.After(max(remaining, 0))
+		}
+
+		select {
+		case <-ctx.Done():
+			return cmd.Wait()
+		case <-expired:
+			if !s.flushExpired(ctx, entries, time.Now()) {
+				return cmd.Wait()
+			}
+		case l, ok := <-lines:
+			if !ok {
+				return cmd.Wait()
+			}
+			s.LineNumber++
+			s.countLine(l.n)
+			entry := s.newEntry(l.line, l.dropped)
+			if l.stderr {
+				entry.Tags = append(slices.Clone(entry.Tags), "stderr")
+			}
+			if !s.admit(ctx, entries, entry) {
+				return cmd.Wait()
+			}
+		}
+	}
+}
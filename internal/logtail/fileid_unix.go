@@ -0,0 +1,19 @@
+//go:build !windows
+
+package logtail
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fileID identifies the file behind info by device and inode, which
+// survive renames, so a checkpoint follows a file through rotation.
+func fileID(info os.FileInfo) (string, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%d:%d", st.Dev, st.Ino), true
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package logtail
+
+import "os"
+
+// fileID is unavailable on Windows, where FileInfo carries no file index,
+// so files are not checkpointed there.
+func fileID(info os.FileInfo) (string, bool) {
+	return "", false
+}
@@ -0,0 +1,176 @@
+package logtail
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sync/atomic"
+
+	"github.com/appgram/logdump/internal/config"
+)
+
+// Filter actions, as listed in a filter's actions.
+const (
+	FilterExclude   = "exclude"   // drop matching entries
+	FilterHide      = "hide"      // same as exclude
+	FilterHighlight = "highlight" // set Highlight and HighlightColor on matching entries
+	FilterTag       = "tag"       // add the filter's name to the Tags of matching entries
+	FilterAlert     = "alert"     // POST matching entries to the filter's webhook
+)
+
+// filter is a configured filter with its pattern compiled.
+type filter struct {
+	config.FilterConfig
+	re        *regexp.Regexp
+	exclude   bool
+	highlight bool
+	tag       bool
+	alert     *alerter // nil unless the filter has the alert action
+	disabled  atomic.Bool
+	matches   atomic.Int64
+}
+
+// FilterState describes one of the Manager's filters.
+type FilterState struct {
+	config.FilterConfig
+	Enabled bool
+	Matches int64 // entries matched while the filter was enabled
+}
+
+// newFilter compiles cfg. A filter without actions highlights.
+func (m *Manager) newFilter(cfg config.FilterConfig) (*filter, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("filter /%s/ has no name", cfg.Pattern)
+	}
+	if cfg.Pattern == "" {
+		return nil, fmt.Errorf("filter %s has no pattern", cfg.Name)
+	}
+	re, err := regexp.Compile(cfg.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("filter %s: invalid pattern: %w", cfg.Name, err)
+	}
+
+	f := &filter{FilterConfig: cfg, re: re}
+	if len(cfg.Actions) == 0 {
+		f.highlight = true
+	}
+	for _, action := range cfg.Actions {
+		switch action {
+		case FilterExclude, FilterHide:
+			f.exclude = true
+		case FilterHighlight:
+			f.highlight = true
+		case FilterTag:
+			f.tag = true
+		case FilterAlert:
+			if f.alert, err = newAlerter(m, cfg.Name, cfg.Webhook, cfg.Cooldown); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("filter %s: unknown action %q, expected %s, %s, %s, %s or %s",
+				cfg.Name, action, FilterExclude, FilterHide, FilterHighlight, FilterTag, FilterAlert)
+		}
+	}
+	return f, nil
+}
+
+// SetFilters replaces the filters applied to entries as they are read.
+// A filter named like one it replaces keeps that one's enabled state, so
+// filters toggled off stay off when the config is reloaded. Invalid
+// filters are left out and reported in the error.
+func (m *Manager) SetFilters(cfgs []config.FilterConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous := make(map[string]*filter)
+	if old := m.filters.Load(); old != nil {
+		for _, f := range *old {
+			previous[f.Name] = f
+		}
+	}
+
+	var filters []*filter
+	var errs []error
+	for _, cfg := range cfgs {
+		f, err := m.newFilter(cfg)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if old, ok := previous[f.Name]; ok {
+			f.disabled.Store(old.disabled.Load())
+			f.matches.Store(old.matches.Load())
+			// Keep the cooldown of an unchanged alert
+			if f.alert != nil && old.alert != nil &&
+				f.alert.webhook == old.alert.webhook && f.alert.cooldown == old.alert.cooldown {
+				f.alert = old.alert
+			}
+		}
+		filters = append(filters, f)
+	}
+	m.filters.Store(&filters)
+	return errors.Join(errs...)
+}
+
+// Filters returns the Manager's filters in config order.
+func (m *Manager) Filters() []FilterState {
+	filters := m.filters.Load()
+	if filters == nil {
+		return nil
+	}
+	states := make([]FilterState, 0, len(*filters))
+	for _, f := range *filters {
+		states = append(states, FilterState{
+			FilterConfig: f.FilterConfig,
+			Enabled:      !f.disabled.Load(),
+			Matches:      f.matches.Load(),
+		})
+	}
+	return states
+}
+
+// SetFilterEnabled turns the named filter on or off for the entries read
+// from now on.
+func (m *Manager) SetFilterEnabled(name string, enabled bool) error {
+	if filters := m.filters.Load(); filters != nil {
+		for _, f := range *filters {
+			if f.Name == name {
+				f.disabled.Store(!enabled)
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("no filter named %s", name)
+}
+
+// applyFilters runs the enabled filters over entry, returning false if
+// one of them excludes it. The first highlighting filter to match sets
+// the entry's highlight. Heartbeats are left alone, and history replayed
+// from existing files and lines of the alert log raise no alerts.
+func (m *Manager) applyFilters(entry LogEntry) (LogEntry, bool) {
+	filters := m.filters.Load()
+	if filters == nil || entry.IsHeartbeat() {
+		return entry, true
+	}
+	for _, f := range *filters {
+		if f.disabled.Load() || !f.re.MatchString(entry.Content) {
+			continue
+		}
+		f.matches.Add(1)
+		if f.alert != nil && !entry.noAlert {
+			f.alert.match(entry)
+		}
+		if f.exclude {
+			return entry, false
+		}
+		if f.highlight && entry.Highlight == "" {
+			entry.Highlight, entry.HighlightColor = f.Name, f.Color
+		}
+		if f.tag && !slices.Contains(entry.Tags, f.Name) {
+			// Tags may be shared with the stream's other entries
+			entry.Tags = append(slices.Clip(entry.Tags), f.Name)
+		}
+	}
+	return entry, true
+}
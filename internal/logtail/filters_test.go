@@ -0,0 +1,108 @@
+package logtail
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/appgram/logdump/internal/config"
+)
+
+// webhook records the alerts POSTed to it.
+func webhook(t *testing.T) (string, <-chan AlertPayload) {
+	posts := make(chan AlertPayload, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p AlertPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("decoding alert: %v", err)
+		}
+		posts <- p
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, posts
+}
+
+func TestAlertSkipsHistory(t *testing.T) {
+	url, posts := webhook(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writeFile(t, path, "FATAL before start")
+
+	m := newTestManager(t)
+	err := m.SetFilters([]config.FilterConfig{{
+		Name: "fatal", Pattern: "FATAL", Actions: []string{FilterAlert}, Webhook: url, Cooldown: "0s",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := subscribe(t, m)
+	if err := m.Tail(fileStream("app", dir, "app.log")); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, entries, 1)
+	historyLoaded(t, m)
+
+	appendFile(t, path, "FATAL after start")
+	receive(t, entries, 1)
+
+	select {
+	case p := <-posts:
+		if p.Line != "FATAL after start" || p.Stream != "app" || p.Count != 1 {
+			t.Errorf("alert = %+v, want the new line only", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no alert for the new line")
+	}
+	select {
+	case p := <-posts:
+		t.Errorf("unexpected second alert %+v", p)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestAlertSkipsAlertLog(t *testing.T) {
+	url, posts := webhook(t)
+	dir := t.TempDir()
+	alertLog := filepath.Join(dir, "activity.log")
+	app := filepath.Join(dir, "app.log")
+	writeFile(t, alertLog)
+	writeFile(t, app)
+
+	m := newTestManager(t)
+	m.SetAlertLog(alertLog, func(message string) { appendFile(t, alertLog, message) })
+	err := m.SetFilters([]config.FilterConfig{{
+		Name: "failures", Pattern: "failed", Actions: []string{FilterAlert}, Webhook: url, Cooldown: "0s",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := subscribe(t, m)
+	for _, cfg := range []config.StreamConfig{fileStream("activity", dir, "activity.log"), fileStream("app", dir, "app.log")} {
+		if err := m.Tail(cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	historyLoaded(t, m)
+
+	m.logAlert("alert failures: webhook failed after 3 attempts")
+	receive(t, entries, 1)
+	appendFile(t, app, "job failed")
+	receive(t, entries, 1)
+
+	select {
+	case p := <-posts:
+		if p.Stream != "app" {
+			t.Errorf("alert = %+v, want one for the app stream", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no alert for the app stream")
+	}
+	select {
+	case p := <-posts:
+		t.Errorf("unexpected alert %+v", p)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,60 @@
+package logtail
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// HeartbeatSource is the source of the status entries emitted by
+// StartHeartbeat.
+const HeartbeatSource = "logdump"
+
+// heartbeatTag marks heartbeat entries, so consumers can tell them from a
+// stream that happens to be named like HeartbeatSource.
+const heartbeatTag = "heartbeat"
+
+// IsHeartbeat reports whether the entry was emitted by StartHeartbeat.
+func (e LogEntry) IsHeartbeat() bool {
+	return e.Source == HeartbeatSource && slices.Contains(e.Tags, heartbeatTag)
+}
+
+// StartHeartbeat emits a status entry every interval summarising the
+// active streams and lines read, so quiet logs can be told apart from a
+// stalled logdump. It stops when the Manager is closed.
+func (m *Manager) StartHeartbeat(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.ctx.Done():
+				return
+			case now := <-ticker.C:
+				entry := m.heartbeat(now)
+				// A heartbeat is not worth blocking for
+				select {
+				case m.entries <- entry:
+				default:
+				}
+			}
+		}
+	}()
+}
+
+func (m *Manager) heartbeat(now time.Time) LogEntry {
+	streams := m.GetStreams()
+	var lines int64
+	for _, stream := range streams {
+		lines += stream.LinesRead()
+	}
+
+	return LogEntry{
+		Timestamp: now,
+		Timed:     true,
+		Source:    HeartbeatSource,
+		Content:   fmt.Sprintf("heartbeat: %d streams active, %d lines read", len(streams), lines),
+		Tags:      []string{heartbeatTag},
+	}
+}
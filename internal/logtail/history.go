@@ -0,0 +1,138 @@
+package logtail
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// FileMatches streams the results of SearchFiles.
+type FileMatches struct {
+	// Entries receives each match and is closed when the search ends.
+	Entries <-chan LogEntry
+
+	scanned   atomic.Int64
+	truncated atomic.Bool
+}
+
+// Scanned returns how many bytes of log data have been searched so far.
+func (f *FileMatches) Scanned() int64 {
+	return f.scanned.Load()
+}
+
+// Truncated reports whether the search stopped at its byte limit before
+// reaching the end of every file. It is final once Entries is closed.
+func (f *FileMatches) Truncated() bool {
+	return f.truncated.Load()
+}
+
+// SearchFiles scans the files on disk of the streams named source (all
+// streams if empty) from the beginning, rather than the buffer, sending
+// each line matching pattern with its real line number. It stops after
+// maxResults matches and after maxBytes bytes of log data; zero means no
+// limit for either. Compressed files are searched decompressed, and pipes
+// and stdin are skipped.
+func (m *Manager) SearchFiles(ctx context.Context, pattern, source string, maxResults int, maxBytes int64) (*FileMatches, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	var streams []*Stream
+	m.mu.RLock()
+	for _, stream := range m.streams {
+		if (source == "" || stream.Config.Name == source) && stream.onDisk() {
+			streams = append(streams, stream)
+		}
+	}
+	m.mu.RUnlock()
+
+	slices.SortFunc(streams, func(a, b *Stream) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	results := make(chan LogEntry, 100)
+	matches := &FileMatches{Entries: results}
+
+	go func() {
+		defer close(results)
+
+		found := 0
+		for _, stream := range streams {
+			done, err := stream.searchFile(ctx, re, results, matches, &found, maxResults, maxBytes)
+			if err != nil || done {
+				return
+			}
+		}
+	}()
+
+	return matches, nil
+}
+
+// searchFile sends the lines of the stream's file matching re. It reports
+// done once a limit is reached or ctx is cancelled.
+func (s *Stream) searchFile(ctx context.Context, re *regexp.Regexp, results chan<- LogEntry, matches *FileMatches, found *int, maxResults int, maxBytes int64) (bool, error) {
+	file, err := os.Open(s.Path)
+	if err != nil {
+		// The file may have been rotated away; search the rest
+		return false, nil
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, nil
+	}
+
+	var r io.Reader = file
+	if isCompressed(s.Path) {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return false, nil
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	reader := bufio.NewReader(r)
+	lineNumber := 0
+	for {
+		if ctx.Err() != nil {
+			return true, ctx.Err()
+		}
+		if maxBytes > 0 && matches.scanned.Load() >= maxBytes {
+			matches.truncated.Store(true)
+			return true, nil
+		}
+
+		line, n, _, err := readLine(reader, s.maxLine)
+		if line != "" {
+			lineNumber++
+			matches.scanned.Add(n)
+
+			content := strings.TrimSuffix(line, "\n")
+			if re.MatchString(content) {
+				entry := s.buildEntry(content, lineNumber, info.ModTime())
+				select {
+				case results <- entry:
+				case <-ctx.Done():
+					return true, ctx.Err()
+				}
+				*found++
+				if maxResults > 0 && *found >= maxResults {
+					return true, nil
+				}
+			}
+		}
+		if err != nil {
+			return false, nil
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package logtail
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/appgram/logdump/internal/config"
+)
+
+// jsonParser turns NDJSON log lines into a message plus flattened fields.
+type jsonParser struct {
+	messageFields []string
+	levelField    string
+}
+
+// timeFields are checked, in order, for the event time of a JSON line.
+var timeFields = []string{"timestamp", "time", "ts", "@timestamp"}
+
+func newJSONParser(cfg config.StreamConfig) *jsonParser {
+	if cfg.Format != "json" {
+		return nil
+	}
+
+	p := &jsonParser{
+		messageFields: []string{"msg", "message"},
+		levelField:    "level",
+	}
+	if cfg.MessageField != "" {
+		p.messageFields = []string{cfg.MessageField}
+	}
+	if cfg.LevelField != "" {
+		p.levelField = cfg.LevelField
+	}
+	return p
+}
+
+// apply replaces the entry's content with the JSON message and stores the
+// remaining keys in Fields. Lines that are not JSON objects are left as is.
+func (p *jsonParser) apply(entry *LogEntry) {
+	if p == nil || !strings.HasPrefix(strings.TrimSpace(entry.Content), "{") {
+		return
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(entry.Content))
+	decoder.UseNumber()
+
+	var obj map[string]interface{}
+	if err := decoder.Decode(&obj); err != nil {
+		return
+	}
+
+	fields := make(map[string]string)
+	flattenJSON("", obj, fields)
+
+	for _, key := range p.messageFields {
+		if msg, ok := fields[key]; ok {
+			entry.Content = msg
+			delete(fields, key)
+			break
+		}
+	}
+
+	// Store the level under a fixed key regardless of the source field name
+	if level, ok := fields[p.levelField]; ok {
+		delete(fields, p.levelField)
+		fields["level"] = level
+	}
+
+	for _, key := range timeFields {
+		if t, err := time.Parse(time.RFC3339, fields[key]); err == nil {
+			entry.Timestamp = t
+			entry.Timed = true
+			break
+		}
+	}
+
+	entry.Fields = fields
+}
+
+// flattenJSON writes every leaf of value into fields, joining nested object
+// keys with dots. Arrays are kept as compact JSON.
+func flattenJSON(prefix string, value interface{}, fields map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			flattenJSON(key, child, fields)
+		}
+	case string:
+		fields[prefix] = v
+	case nil:
+		fields[prefix] = "null"
+	case json.Number, bool:
+		fields[prefix] = fmt.Sprint(v)
+	default:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(v); err == nil {
+			fields[prefix] = strings.TrimSpace(buf.String())
+		}
+	}
+}
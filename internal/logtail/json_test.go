@@ -0,0 +1,114 @@
+package logtail
+
+import (
+	"maps"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/appgram/logdump/internal/config"
+)
+
+func TestJSONParser(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.StreamConfig
+		line    string
+		content string
+		fields  map[string]string
+	}{
+		{
+			name:    "nested objects",
+			line:    `{"msg":"login","level":"warn","user":{"id":42,"geo":{"country":"NL"}},"roles":["a","b"],"admin":false,"team":null}`,
+			content: "login",
+			fields: map[string]string{
+				"level": "warn", "user.id": "42", "user.geo.country": "NL",
+				"roles": `["a","b"]`, "admin": "false", "team": "null",
+			},
+		},
+		{
+			name:    "configured fields",
+			cfg:     config.StreamConfig{MessageField: "event", LevelField: "severity"},
+			line:    `{"event":"saved","severity":"debug","msg":"kept"}`,
+			content: "saved",
+			fields:  map[string]string{"level": "debug", "msg": "kept"},
+		},
+		{
+			name:    "large numbers kept exactly",
+			line:    `{"message":"id","id":12345678901234567890}`,
+			content: "id",
+			fields:  map[string]string{"id": "12345678901234567890"},
+		},
+		{
+			name:    "no message field",
+			line:    `{"status":200}`,
+			content: `{"status":200}`,
+			fields:  map[string]string{"status": "200"},
+		},
+		{
+			name:    "malformed",
+			line:    `{"msg":"cut off`,
+			content: `{"msg":"cut off`,
+		},
+		{
+			name:    "array",
+			line:    `[1,2]`,
+			content: `[1,2]`,
+		},
+		{
+			name:    "plain text",
+			line:    "server started",
+			content: "server started",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Format = "json"
+			entry := LogEntry{Content: tt.line}
+			newJSONParser(tt.cfg).apply(&entry)
+			if entry.Content != tt.content {
+				t.Errorf("content %q, want %q", entry.Content, tt.content)
+			}
+			if !maps.Equal(entry.Fields, tt.fields) {
+				t.Errorf("fields %v, want %v", entry.Fields, tt.fields)
+			}
+		})
+	}
+}
+
+func TestJSONParserTimestamp(t *testing.T) {
+	entry := LogEntry{Content: `{"msg":"m","ts":"2026-01-02T03:04:05Z"}`}
+	newJSONParser(config.StreamConfig{Format: "json"}).apply(&entry)
+	if want := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC); !entry.Timed || !entry.Timestamp.Equal(want) {
+		t.Errorf("timestamp %v (timed %v), want %v", entry.Timestamp, entry.Timed, want)
+	}
+}
+
+func TestJSONStream(t *testing.T) {
+	m := newTestManager(t)
+	entries := subscribe(t, m)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "app.log"), `{"msg":"first","level":"error","req":{"id":"r1"}}`, "not json", `{"msg":"broken"`)
+
+	cfg := fileStream("app", dir, "app.log")
+	cfg.Format = "json"
+	if err := m.Tail(cfg); err != nil {
+		t.Fatal(err)
+	}
+	var got []LogEntry
+	for len(got) < 3 {
+		select {
+		case e := <-entries:
+			got = append(got, e)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %d entries, want 3", len(got))
+		}
+	}
+	if got[0].Content != "first" || got[0].Level != "ERROR" || got[0].Fields["req.id"] != "r1" {
+		t.Errorf("parsed entry is %q, level %q, fields %v", got[0].Content, got[0].Level, got[0].Fields)
+	}
+	// Lines that fail to parse are shown as they are
+	if got[1].Content != "not json" || got[2].Content != `{"msg":"broken"` || got[1].Fields != nil || got[2].Fields != nil {
+		t.Errorf("unparsed entries are %q %v and %q %v", got[1].Content, got[1].Fields, got[2].Content, got[2].Fields)
+	}
+}
@@ -0,0 +1,39 @@
+package logtail
+
+import (
+	"bytes"
+	"io"
+)
+
+// lastLinesChunk is how much lastLines reads at a time, scanning backwards.
+const lastLinesChunk = 64 << 10
+
+// lastLines returns the offset at which the last n complete lines of the
+// first size bytes of r begin, or 0 if there are no more than n. A trailing
+// line without a newline is still being written and does not count. Only
+// the tail of the file is read, a chunk at a time.
+func lastLines(r io.ReaderAt, size int64, n int) (int64, error) {
+	buf := make([]byte, lastLinesChunk)
+	newlines := 0
+	for end := size; end > 0; {
+		start := max(end-lastLinesChunk, 0)
+		chunk := buf[:end-start]
+		if _, err := r.ReadAt(chunk, start); err != nil && err != io.EOF {
+			return 0, err
+		}
+		// The newline ending the last complete line is the first found;
+		// the one before the n-th line from the end is the (n+1)-th
+		for i := len(chunk); ; {
+			i = bytes.LastIndexByte(chunk[:i], '\n')
+			if i < 0 {
+				break
+			}
+			newlines++
+			if newlines > n {
+				return start + int64(i) + 1, nil
+			}
+		}
+		end = start
+	}
+	return 0, nil
+}
@@ -0,0 +1,88 @@
+package logtail
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/appgram/logdump/internal/config"
+)
+
+// Levels are the recognised severities, least severe first.
+var Levels = []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+
+// defaultLevelPattern finds the level when a stream has no level_regex:
+// a key such as level=warn or "level":"info" in any case, or an upper-case
+// level word such as ERROR.
+var defaultLevelPattern = regexp.MustCompile(
+	`(?i:\b(?:level|lvl|severity)\W{1,3}(trace|debug|info|warn(?:ing)?|err(?:or)?|fatal|crit(?:ical)?|panic)\b)` +
+		`|\b(TRACE|DEBUG|INFO|WARN(?:ING)?|ERROR|FATAL|CRITICAL|PANIC)\b`)
+
+// levelAliases maps other spellings onto Levels.
+var levelAliases = map[string]string{
+	"TRACE":    "DEBUG",
+	"WARNING":  "WARN",
+	"ERR":      "ERROR",
+	"CRIT":     "FATAL",
+	"CRITICAL": "FATAL",
+	"PANIC":    "FATAL",
+}
+
+func newLevelParser(cfg config.StreamConfig) (*regexp.Regexp, error) {
+	if cfg.LevelRegex == "" {
+		return defaultLevelPattern, nil
+	}
+	re, err := regexp.Compile(cfg.LevelRegex)
+	if err != nil {
+		return nil, fmt.Errorf("stream %s: invalid level_regex: %w", cfg.Name, err)
+	}
+	return re, nil
+}
+
+// parseLevel returns the normalized level found in line by re, using the
+// first capture group that matched, or the whole match if it has none. It
+// returns "" if there is no level.
+func parseLevel(re *regexp.Regexp, line string) string {
+	match := re.FindStringSubmatch(line)
+	if match == nil {
+		return ""
+	}
+	for _, group := range match[1:] {
+		if group != "" {
+			return NormalizeLevel(group)
+		}
+	}
+	return NormalizeLevel(match[0])
+}
+
+// NormalizeLevel upper-cases level and maps aliases such as WARNING onto
+// Levels. Unrecognised levels are returned upper-cased.
+func NormalizeLevel(level string) string {
+	level = strings.ToUpper(strings.TrimSpace(level))
+	if alias, ok := levelAliases[level]; ok {
+		return alias
+	}
+	return level
+}
+
+// LevelRank returns the position of level in Levels, or -1 if it is not
+// a recognised level.
+func LevelRank(level string) int {
+	for i, l := range Levels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// LevelAtLeast reports whether an entry with level should be shown at the
+// minimum level min. An empty min shows everything, and entries without a
+// recognised level are always shown since they cannot be ranked.
+func LevelAtLeast(level, min string) bool {
+	if min == "" {
+		return true
+	}
+	rank := LevelRank(level)
+	return rank < 0 || rank >= LevelRank(min)
+}
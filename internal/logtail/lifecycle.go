@@ -0,0 +1,204 @@
+package logtail
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/appgram/logdump/internal/config"
+)
+
+// tailing is a stream config being tailed. Cancelling ctx stops its files
+// and stops new matching files from being picked up.
+type tailing struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// startTailing registers cfg as tailed, or returns its registration if it
+// already is. Paths previously stopped with StopStream are tailed again.
+func (m *Manager) startTailing(cfg config.StreamConfig) *tailing {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for path := range m.stopped {
+		if cfg.Matches(path) {
+			delete(m.stopped, path)
+		}
+	}
+
+	if t, ok := m.tails[cfg.Name]; ok {
+		return t
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	t := &tailing{ctx: ctx, cancel: cancel}
+	m.tails[cfg.Name] = t
+	return t
+}
+
+// AddStream starts tailing a stream at runtime. It is Tail under the name
+// that pairs with RemoveStream.
+func (m *Manager) AddStream(cfg config.StreamConfig) error {
+	return m.Tail(cfg)
+}
+
+// StopStream stops tailing the file at path and closes it. The file is not
+// picked up again until its stream is added again. With purge, the
+// stream's buffered entries are dropped too if this was its last file.
+func (m *Manager) StopStream(path string, purge bool) error {
+	m.mu.Lock()
+	stream, ok := m.streams[path]
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("no stream is tailing %s", path)
+	}
+	delete(m.streams, path)
+	m.stopped[path] = true
+	last := true
+	for _, other := range m.streams {
+		if other.Config.Name == stream.Config.Name {
+			last = false
+		}
+	}
+	m.mu.Unlock()
+
+	stream.cancel()
+	<-stream.Done
+
+	if purge && last {
+		m.purge(stream.Config.Name)
+	}
+	return nil
+}
+
+// RemoveStream stops tailing every file of the named stream and stops
+// watching for new ones. With purge, its buffered entries are dropped. It
+// returns how many files were closed.
+func (m *Manager) RemoveStream(name string, purge bool) (int, error) {
+	m.mu.Lock()
+	t, ok := m.tails[name]
+	if !ok {
+		m.mu.Unlock()
+		return 0, fmt.Errorf("stream not found: %s", name)
+	}
+	delete(m.tails, name)
+	var removed []*Stream
+	for path, stream := range m.streams {
+		if stream.Config.Name == name {
+			removed = append(removed, stream)
+			delete(m.streams, path)
+		}
+	}
+	m.mu.Unlock()
+
+	m.watchMu.Lock()
+	for dir, cfgs := range m.pending {
+		kept := cfgs[:0]
+		for _, cfg := range cfgs {
+			if cfg.Name != name {
+				kept = append(kept, cfg)
+			}
+		}
+		if len(kept) == 0 {
+			delete(m.pending, dir)
+		} else {
+			m.pending[dir] = kept
+		}
+	}
+	m.watchMu.Unlock()
+
+	t.cancel()
+	for _, stream := range removed {
+		<-stream.Done
+	}
+
+	if purge {
+		m.purge(name)
+	}
+	return len(removed), nil
+}
+
+// RestartStream tails the named stream again with cfg, whose settings
+// changed. Its files continue from where they had been read to instead of
+// loading their history again.
+func (m *Manager) RestartStream(cfg config.StreamConfig) error {
+	m.mu.RLock()
+	var files []*Stream
+	for _, stream := range m.streams {
+		if stream.Config.Name == cfg.Name && stream.onDisk() {
+			files = append(files, stream)
+		}
+	}
+	m.mu.RUnlock()
+
+	if _, err := m.RemoveStream(cfg.Name, false); err != nil {
+		return err
+	}
+
+	// The files are closed now, so their offsets are final
+	m.mu.Lock()
+	if m.restarted == nil {
+		m.restarted = make(map[string]int64)
+	}
+	var ids []string
+	for _, stream := range files {
+		if id, offset := stream.checkpoint(); id != "" {
+			m.restarted[id] = offset
+			ids = append(ids, id)
+		}
+	}
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		for _, id := range ids {
+			delete(m.restarted, id)
+		}
+		m.mu.Unlock()
+	}()
+	return m.Tail(cfg)
+}
+
+// purge drops the buffered entries of source.
+func (m *Manager) purge(source string) {
+	m.bufferMu.Lock()
+	defer m.bufferMu.Unlock()
+
+	m.buffer.filter(func(entry LogEntry) bool {
+		return entry.Source != source
+	})
+}
+
+// removedGrace is how long a tailed path may be missing before its stream
+// stops. Rotation briefly leaves no file at the path, which must not end
+// the stream.
+const removedGrace = 5 * time.Second
+
+// forget drops a stream that stopped because its file was deleted, so the
+// file is picked up as new if it is created again.
+func (m *Manager) forget(stream *Stream) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.streams[stream.Path] == stream {
+		delete(m.streams, stream.Path)
+	}
+}
+
+// Wait blocks until the read loop of every stream has returned and its
+// file is closed, as they do after Close, and the sink has written out
+// what it holds.
+func (m *Manager) Wait() {
+	m.mu.RLock()
+	done := make([]chan struct{}, 0, len(m.streams)+1)
+	for _, stream := range m.streams {
+		done = append(done, stream.Done)
+	}
+	if m.sink != nil {
+		done = append(done, m.sink.done)
+	}
+	m.mu.RUnlock()
+
+	for _, d := range done {
+		<-d
+	}
+}
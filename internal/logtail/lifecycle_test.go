@@ -0,0 +1,116 @@
+package logtail
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestAddRemoveDoesNotLeak(t *testing.T) {
+	m := newTestManager(t)
+	m.StartBuffering()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "app.log"), "one", "two")
+	cfg := fileStream("app", dir, "app.log")
+
+	cycle := func() {
+		t.Helper()
+		if err := m.AddStream(cfg); err != nil {
+			t.Fatal(err)
+		}
+		if len(m.GetStreams()) != 1 {
+			t.Fatalf("streams after adding: %v", m.GetStreams())
+		}
+		waitFor(t, "the stream's lines", func() bool { return m.BufferLen() == 2 })
+		if n, err := m.RemoveStream("app", true); n != 1 || err != nil {
+			t.Fatalf("RemoveStream closed %d files: %v", n, err)
+		}
+		if len(m.GetStreams()) != 0 || m.BufferLen() != 0 {
+			t.Fatalf("%d streams and %d entries left after removing", len(m.GetStreams()), m.BufferLen())
+		}
+	}
+
+	// The first cycle starts the goroutines that live as long as m
+	cycle()
+	before := runtime.NumGoroutine()
+	for range 50 {
+		cycle()
+	}
+	// Goroutines of the last stream may still be returning
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines after 50 more add and remove cycles, %d after the first", n, before)
+	}
+}
+
+func TestStopStreamKeepsItStopped(t *testing.T) {
+	m := newTestManager(t)
+	m.StartBuffering()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writeFile(t, path, "one")
+	if err := m.Tail(fileStream("app", dir, "app.log")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the stream's line", func() bool { return m.BufferLen() == 1 })
+
+	if err := m.StopStream(path, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.GetStreams()) != 0 {
+		t.Fatalf("streams after stopping: %v", m.GetStreams())
+	}
+	if err := m.StopStream(path, false); err == nil {
+		t.Error("stopping the stream twice succeeded")
+	}
+
+	// Writes to the file are no longer read; without purge, what was
+	// read stays buffered
+	appendFile(t, path, "two")
+	time.Sleep(200 * time.Millisecond)
+	if got := contents(m.GetEntries("app", 10)); len(got) != 1 || got[0] != "one" {
+		t.Errorf("buffer holds %q after stopping, want only \"one\"", got)
+	}
+}
+
+func TestCloseWithManyStreams(t *testing.T) {
+	m := newTestManager(t)
+	entries := subscribe(t, m)
+	dir := t.TempDir()
+	for i := range 20 {
+		name := fmt.Sprintf("app%d", i)
+		writeFile(t, filepath.Join(dir, name+".log"), name)
+		if err := m.Tail(fileStream(name, dir, name+".log")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	receive(t, entries, 20)
+	historyLoaded(t, m)
+	streams := m.GetStreams()
+
+	start := time.Now()
+	m.Close()
+	m.Wait()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Close and Wait took %v with %d streams", elapsed, len(streams))
+	}
+
+	// Every stream has finished and released its file
+	for path, stream := range streams {
+		select {
+		case <-stream.Done:
+		default:
+			t.Errorf("stream for %s still running", path)
+		}
+		if _, err := stream.File.Read(make([]byte, 1)); !errors.Is(err, os.ErrClosed) {
+			t.Errorf("file %s still open: read gave %v", path, err)
+		}
+	}
+}
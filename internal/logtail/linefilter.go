@@ -0,0 +1,65 @@
+package logtail
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/appgram/logdump/internal/config"
+)
+
+// lineFilter drops entries by content: those matching an exclude pattern,
+// and, when there are include patterns, those matching none of them.
+type lineFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// newLineFilter compiles cfg's include_patterns and exclude_patterns. It
+// returns nil if there are none.
+func newLineFilter(cfg config.StreamConfig) (*lineFilter, error) {
+	if len(cfg.IncludePatterns) == 0 && len(cfg.ExcludePatterns) == 0 {
+		return nil, nil
+	}
+	include, err := compilePatterns(cfg.Name, "include_patterns", cfg.IncludePatterns)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compilePatterns(cfg.Name, "exclude_patterns", cfg.ExcludePatterns)
+	if err != nil {
+		return nil, err
+	}
+	return &lineFilter{include: include, exclude: exclude}, nil
+}
+
+func compilePatterns(stream, setting string, patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("stream %s: invalid %s entry %q: %w", stream, setting, pattern, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// keep reports whether an entry with content passes the filter.
+func (f *lineFilter) keep(content string) bool {
+	if f == nil {
+		return true
+	}
+	for _, re := range f.exclude {
+		if re.MatchString(content) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, re := range f.include {
+		if re.MatchString(content) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,19 +2,77 @@ package logtail
 
 import (
 	"bufio"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
+
 	"github.com/appgram/logdump/internal/config"
 )
 
+const (
+	// pollInterval is how often streams re-check their file when no
+	// filesystem notifications are available.
+	pollInterval = 100 * time.Millisecond
+	// notifyFallbackInterval bounds the delay if a notification is missed.
+	notifyFallbackInterval = 2 * time.Second
+)
+
+// Watch modes reported by Stream.WatchMode.
+const (
+	WatchNotify  = "notify"  // woken by filesystem notifications
+	WatchPoll    = "poll"    // re-checks the file every pollInterval
+	WatchArchive = "archive" // compressed history, read once and closed
+	WatchPipe    = "pipe"    // named pipe, reopened for each writer
+	WatchStdin   = "stdin"   // standard input, read until it ends
+	WatchCommand = "command" // output of a command, restarted when it exits
+	WatchSyslog  = "syslog"  // syslog messages received on a socket
+)
+
+// Overflow decides what a stream does with an entry when the Manager's
+// entries channel is full. The dropping policies count what they discard
+// and report it with an "[overflow: N lines dropped]" entry once there is
+// room again.
+type Overflow int
+
+const (
+	// OverflowBlock waits for room, pausing the stream's reader. Nothing is
+	// lost, since unread lines stay in the file.
+	OverflowBlock Overflow = iota
+	// OverflowDropNewest discards the incoming entry, so readers never
+	// fall behind the files.
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest queued entry to make room, so
+	// what gets through is the most recent.
+	OverflowDropOldest
+)
+
+// ParseOverflow returns the policy named by the overflow config setting:
+// "block" (the default when empty), "drop_newest" or "drop_oldest".
+func ParseOverflow(name string) (Overflow, error) {
+	switch name {
+	case "", "block":
+		return OverflowBlock, nil
+	case "drop_newest":
+		return OverflowDropNewest, nil
+	case "drop_oldest":
+		return OverflowDropOldest, nil
+	}
+	return OverflowBlock, fmt.Errorf("unknown overflow policy %q", name)
+}
+
 type LogEntry struct {
 	Timestamp  time.Time
 	Source     string
@@ -22,106 +80,622 @@ type LogEntry struct {
 	Tags       []string
 	Filtered   bool
 	LineNumber int
+	Fields     map[string]string // structured fields, for format: json streams
+	Level      string            // normalized severity (see Levels), "" if none was found
+	Timed      bool              // Timestamp was parsed from the line rather than being the read time
+	Seq        uint64            // buffer sequence number, assigned by AddEntry
+	// RepeatCount is how many identical consecutive lines the entry stands
+	// for when collapse_repeats folded them together, 0 otherwise.
+	RepeatCount int
+	// Truncated is set when the line was longer than the stream's
+	// max_line_length and the rest was cut; Content then ends with a
+	// "…[truncated N bytes]" note.
+	Truncated bool
+	// Highlight is the name of the config filter that highlighted the
+	// entry, and HighlightColor that filter's color; both are "" if none
+	// did.
+	Highlight      string
+	HighlightColor string
+
+	// noAlert keeps alert filters from matching the entry: it was read
+	// with the file's existing content, or from the file failed alerts
+	// are logged to, where a match would set off another alert.
+	noAlert bool
 }
 
 type Stream struct {
 	Config     config.StreamConfig
+	Path       string
 	File       *os.File
 	Reader     *bufio.Reader
 	LineNumber int
+	WatchMode  string
 	Done       chan struct{}
+	cancel     context.CancelFunc // stops this stream's read loop
+	fileMu     sync.Mutex         // guards File replacement on rotation
+	fileID     string             // identity of File for checkpoints, guarded by fileMu
+	offset     atomic.Int64       // bytes of File consumed
+	resumeAt   int64              // checkpointed offset to start reading at, -1 if none
+	wake       chan struct{}      // signalled by the watcher when the file changes
+	interval   time.Duration
+	timestamps *timestampParser
+	levels     *regexp.Regexp
+	multiline  *multiline
+	repeats    *repeats
+	limiter    *rateLimiter
+	json       *jsonParser
+	filter     *lineFilter
+	maxLine    int // bytes kept of each line; the rest is cut
+	dropped    atomic.Int64
+	linesRead  atomic.Int64 // lines read over the stream's lifetime
+	bytesRead  atomic.Int64
+	lastLine   atomic.Int64 // UnixNano of the last line read
+	rate       lineRate
+	overflow   Overflow
+	overflowed atomic.Int64  // entries discarded by the overflow policy
+	excluded   atomic.Int64  // entries filtered out by include/exclude patterns
+	unreported int64         // of those, how many no overflow marker has reported yet
+	queue      chan LogEntry // the Manager's entries, which OverflowDropOldest drains
+	pid        atomic.Int64  // of the running command, for command streams
+	restarts   atomic.Int64  // times the command was restarted
+	health     atomic.Value  // string, state of a syslog stream's socket
+	relPath    string        // tagged on the entries of a recursive stream
+	alertLog   bool          // the file is the one failed alerts are logged to
+
+	historyRead atomic.Int64 // bytes of the initial history read so far
+	historySize atomic.Int64 // size of the file when tailing started
+	historyDone atomic.Bool
+
+	missingSince time.Time     // when the path was first found missing, zero while it exists
+	removed      atomic.Bool   // the read loop stopped because the file was deleted
+	loaded       chan struct{} // closed once the history is loaded
+	loadedOnce   sync.Once
+	after        <-chan struct{} // history of an older rotated file to wait for
+}
+
+// onDisk reports whether the stream reads a file on disk, as opposed to a
+// pipe, stdin, a command or a socket, which have no history to search or
+// re-read.
+func (s *Stream) onDisk() bool {
+	switch s.WatchMode {
+	case WatchPipe, WatchStdin, WatchCommand, WatchSyslog:
+		return false
+	}
+	return true
+}
+
+// HistoryProgress reports how many bytes of the file's existing content
+// have been read, out of its size when tailing started. done is true once
+// the history is loaded and the stream is following new lines.
+func (s *Stream) HistoryProgress() (read, total int64, done bool) {
+	return s.historyRead.Load(), s.historySize.Load(), s.historyDone.Load()
+}
+
+// LinesRead returns how many lines the stream has read, across rotations.
+func (s *Stream) LinesRead() int64 {
+	return s.linesRead.Load()
+}
+
+// Dropped returns how many lines were discarded by sampling or rate limiting.
+func (s *Stream) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Overflowed returns how many entries were discarded because the Manager's
+// entries channel was full under a dropping overflow policy.
+func (s *Stream) Overflowed() int64 {
+	return s.overflowed.Load()
+}
+
+// notify wakes the read loop without blocking if a wake-up is already pending.
+func (s *Stream) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// BufferPolicy bounds the Manager's buffer. A zero field means that
+// dimension is unlimited; entries are evicted oldest-first until every
+// non-zero limit is satisfied. MaxEntries applies to each stream's entries
+// separately, MaxBytes and MaxAge to the whole buffer. MaxMemory is a
+// ceiling on the buffer's estimated memory use, which every stream gives
+// up entries towards in proportion to its share.
+type BufferPolicy struct {
+	MaxEntries int
+	MaxBytes   int64
+	MaxAge     time.Duration
+	MaxMemory  int64
+}
+
+// DefaultBufferPolicy keeps the most recent 1000 entries.
+func DefaultBufferPolicy() BufferPolicy {
+	return BufferPolicy{MaxEntries: config.DefaultBufferSize}
+}
+
+// NewBufferPolicy builds a BufferPolicy from the buffer section of the config.
+func NewBufferPolicy(cfg config.BufferConfig) (BufferPolicy, error) {
+	policy := BufferPolicy{}
+
+	strategy := cfg.Strategy
+	if strategy == "" {
+		strategy = "count"
+	}
+
+	for _, mode := range strings.Split(strategy, ",") {
+		switch strings.TrimSpace(mode) {
+		case "count":
+			policy.MaxEntries = cfg.MaxEntries
+			if policy.MaxEntries <= 0 {
+				policy.MaxEntries = config.DefaultBufferSize
+			}
+		case "bytes":
+			if cfg.MaxBytes <= 0 {
+				return policy, fmt.Errorf("buffer strategy %q requires max_bytes", mode)
+			}
+			policy.MaxBytes = cfg.MaxBytes
+		case "time":
+			age, err := time.ParseDuration(cfg.MaxAge)
+			if err != nil || age <= 0 {
+				return policy, fmt.Errorf("buffer strategy %q requires a valid max_age", mode)
+			}
+			policy.MaxAge = age
+		default:
+			return policy, fmt.Errorf("unknown buffer strategy %q", mode)
+		}
+	}
+
+	// The memory budget applies whatever the strategy
+	if cfg.MaxMemoryMB < 0 {
+		return policy, fmt.Errorf("buffer max_memory_mb must not be negative")
+	}
+	policy.MaxMemory = int64(cfg.MaxMemoryMB) << 20
+
+	return policy, nil
 }
 
 type Manager struct {
 	streams  map[string]*Stream
 	entries  chan LogEntry
-	buffer   []LogEntry
+	buffer   *buffer
+	policy   BufferPolicy
 	bufferMu sync.RWMutex
+	seq      uint64 // sequence number of the newest buffered entry
 	mu       sync.RWMutex
 	ctx      context.Context
 	cancel   context.CancelFunc
-	tailOnly bool // skip history, only show new logs
+	history  int // lines of history per file, overriding the stream's history_lines; -1 defers to it
+	overflow Overflow
+	tails    map[string]*tailing // tailed streams by name
+	stopped  map[string]bool     // paths removed with StopStream
+
+	checkpoints *checkpoints     // read offsets to resume from, nil unless ResumeFrom was called
+	restarted   map[string]int64 // read offsets by file id of streams being restarted, see RestartStream
+
+	filters      atomic.Pointer[[]*filter] // config filters, applied as entries are read; see SetFilters
+	alertLog     func(message string)      // reports failed webhook calls, see SetAlertLog
+	alertLogPath string                    // the file alertLog writes to
+	sink         *sink                     // nil unless StartSink was called
+
+	// watcher is nil when the platform has no filesystem notifications,
+	// in which case streams and directories are polled instead.
+	watcher *fsnotify.Watcher
+	watchMu sync.Mutex
+	watched map[string]bool                  // directories added to the watcher
+	pending map[string][]config.StreamConfig // directories awaiting new files
+
+	subscribers     map[*subscriber]struct{}
+	subsMu          sync.Mutex
+	subscriberDrops atomic.Int64
+	fanOutOnce      sync.Once
 }
 
 func NewManager() *Manager {
 	return NewManagerWithOptions(false)
 }
 
+// NewManagerWithOptions returns a Manager that, with tailOnly, skips the
+// existing content of every file and shows only new lines.
 func NewManagerWithOptions(tailOnly bool) *Manager {
+	history := -1
+	if tailOnly {
+		history = 0
+	}
 	ctx, cancel := context.WithCancel(context.Background())
-	return &Manager{
-		streams:  make(map[string]*Stream),
-		entries:  make(chan LogEntry, 10000),
-		buffer:   make([]LogEntry, 0, 1000),
-		ctx:      ctx,
-		cancel:   cancel,
-		tailOnly: tailOnly,
+	m := &Manager{
+		streams: make(map[string]*Stream),
+		entries: make(chan LogEntry, 10000),
+		buffer:  newBuffer(DefaultBufferPolicy().MaxEntries),
+		policy:  DefaultBufferPolicy(),
+		ctx:     ctx,
+		cancel:  cancel,
+		history: history,
+		watched: make(map[string]bool),
+		pending: make(map[string][]config.StreamConfig),
+		tails:   make(map[string]*tailing),
+		stopped: make(map[string]bool),
+
+		subscribers: make(map[*subscriber]struct{}),
+	}
+
+	if watcher, err := fsnotify.NewWatcher(); err == nil {
+		m.watcher = watcher
+		go m.dispatchEvents()
+	}
+
+	return m
+}
+
+// SetOverflow sets the overflow policy for streams tailed from now on. The
+// default is OverflowBlock.
+func (m *Manager) SetOverflow(overflow Overflow) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.overflow = overflow
+}
+
+// SetHistoryLines limits the history loaded from each file tailed from now
+// on to its last n lines, overriding StreamConfig.HistoryLines. A negative n
+// restores the per-stream setting.
+func (m *Manager) SetHistoryLines(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.history = max(n, -1)
+}
+
+// historyLines returns how many lines of history to load from a file of
+// cfg, negative meaning all of it.
+func (m *Manager) historyLines(cfg config.StreamConfig) int {
+	if m.history >= 0 {
+		return m.history
+	}
+	if cfg.HistoryLines != nil {
+		return max(*cfg.HistoryLines, 0)
+	}
+	return -1
+}
+
+// watch subscribes to notifications for dir. It reports false if
+// notifications are unavailable and the caller should poll instead.
+func (m *Manager) watch(dir string) bool {
+	if m.watcher == nil {
+		return false
+	}
+
+	m.watchMu.Lock()
+	defer m.watchMu.Unlock()
+
+	if m.watched[dir] {
+		return true
+	}
+	if err := m.watcher.Add(dir); err != nil {
+		return false
+	}
+	m.watched[dir] = true
+	return true
+}
+
+func (m *Manager) dispatchEvents() {
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case event, ok := <-m.watcher.Events:
+			if !ok {
+				return
+			}
+			m.handleEvent(event)
+		case _, ok := <-m.watcher.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+func (m *Manager) handleEvent(event fsnotify.Event) {
+	m.mu.RLock()
+	stream := m.streams[event.Name]
+	m.mu.RUnlock()
+
+	if stream != nil {
+		stream.notify()
+	}
+
+	if event.Has(fsnotify.Create) {
+		m.watchMu.Lock()
+		cfgs := m.pending[filepath.Dir(event.Name)]
+		m.watchMu.Unlock()
+
+		for _, cfg := range cfgs {
+			if cfg.IsRecursive() {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					// Not from this goroutine: adding a watch can wait on
+					// the event loop
+					go m.watchTree(cfg, event.Name)
+					continue
+				}
+			}
+			if tailsNew(cfg, event.Name) {
+				_ = m.addFile(cfg, event.Name)
+			}
+		}
 	}
 }
 
 func (m *Manager) Tail(cfg config.StreamConfig) error {
-	matches, err := filepath.Glob(filepath.Join(cfg.Path, "*"))
+	source := cfg.Source
+	switch {
+	case source != "":
+	case len(cfg.Command) > 0:
+		source = config.SourceCommand
+	case cfg.Listen != "":
+		source = config.SourceSyslog
+	}
+	switch source {
+	case "":
+	case config.SourceStdin:
+		m.startTailing(cfg)
+		return m.tailStdin(cfg)
+	case config.SourceCommand:
+		m.startTailing(cfg)
+		return m.tailCommand(cfg)
+	case config.SourceSyslog:
+		m.startTailing(cfg)
+		return m.tailSyslog(cfg)
+	default:
+		return fmt.Errorf("stream %s: unknown source %q", cfg.Name, cfg.Source)
+	}
+
+	t := m.startTailing(cfg)
+	if cfg.HasGlobPath() {
+		return m.tailGlob(cfg, t)
+	}
+	return m.tailDirectory(cfg, t)
+}
+
+// tailDirectory tails the files of cfg.Path that match its patterns and
+// watches for new ones.
+func (m *Manager) tailDirectory(cfg config.StreamConfig, t *tailing) error {
+	matches, err := listFiles(cfg)
 	if err != nil {
 		return err
 	}
 
+	// Read rotated files oldest first, each waiting for the history of the
+	// one before it, so startup history comes out in chronological order
+	sortRotated(matches)
+	var previous <-chan struct{}
 	for _, match := range matches {
-		if !cfg.Matches(match) {
+		if !tailsAtStart(cfg, match) {
 			continue
 		}
-		if err := m.addFile(cfg, match); err != nil {
+		if previous, err = m.addFileAfter(cfg, match, previous); err != nil {
 			return err
 		}
 	}
 
-	if len(matches) == 0 {
-		m.watchDirectory(cfg)
+	// With notifications, keep watching for new matching files even when
+	// some already exist; polling only waits for the first ones to appear,
+	// unless new subdirectories are to be picked up too
+	if len(matches) == 0 || m.watcher != nil || cfg.IsRecursive() {
+		m.watchDirectory(t.ctx, cfg)
 	}
 
 	return nil
 }
 
+// globInterval is how often a glob Path is expanded again to find new
+// matching directories.
+const globInterval = 5 * time.Second
+
+// tailGlob tails every directory matching cfg.Path, a glob, as if it were
+// the stream's Path, and keeps looking for new ones until the stream is
+// removed.
+func (m *Manager) tailGlob(cfg config.StreamConfig, t *tailing) error {
+	if _, err := filepath.Match(cfg.Path, ""); err != nil {
+		return fmt.Errorf("stream %s: invalid path %q: %w", cfg.Name, cfg.Path, err)
+	}
+
+	tailed := make(map[string]bool)
+	expand := func() error {
+		dirs, _ := filepath.Glob(cfg.Path)
+		var errs []error
+		for _, dir := range dirs {
+			if tailed[dir] {
+				continue
+			}
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				continue
+			}
+			tailed[dir] = true
+			dirCfg := cfg
+			dirCfg.Path = dir
+			if err := m.tailDirectory(dirCfg, t); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+	err := expand()
+
+	go func() {
+		ticker := time.NewTicker(globInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.ctx.Done():
+				return
+			case <-ticker.C:
+				_ = expand()
+			}
+		}
+	}()
+	return err
+}
+
 func (m *Manager) addFile(cfg config.StreamConfig, path string) error {
+	_, err := m.addFileAfter(cfg, path, nil)
+	return err
+}
+
+// addFileAfter adds a stream for path whose history is read only once
+// after is closed, and returns a channel closed when its own history has
+// been read.
+func (m *Manager) addFileAfter(cfg config.StreamConfig, path string, after <-chan struct{}) (<-chan struct{}, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, ok := m.streams[path]; ok {
-		return nil
+	if stream, ok := m.streams[path]; ok {
+		return stream.loaded, nil
+	}
+	// Files of removed streams are no longer picked up
+	t, ok := m.tails[cfg.Name]
+	if !ok || m.stopped[path] {
+		return nil, nil
 	}
 
-	file, err := os.Open(path)
+	ctx, cancel := context.WithCancel(t.ctx)
+	stream, err := m.newStream(cfg, path, cancel, after)
 	if err != nil {
-		return fmt.Errorf("failed to open %s: %w", path, err)
+		cancel()
+		return nil, err
+	}
+	if cfg.IsRecursive() {
+		stream.relPath = cfg.RelativePath(path)
 	}
 
-	stream := &Stream{
-		Config:     cfg,
-		File:       file,
-		Reader:     bufio.NewReader(file),
-		LineNumber: 0,
-		Done:       make(chan struct{}),
+	// Opening a FIFO blocks until a writer connects, so the pipe is opened
+	// by its read loop instead
+	if isPipe(path) {
+		stream.WatchMode = WatchPipe
+		m.streams[path] = stream
+		go stream.readPipe(ctx, m.entries)
+		return stream.loaded, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	stream.File = file
+	stream.Reader = bufio.NewReader(file)
+	if info, err := file.Stat(); err == nil {
+		stream.fileID, _ = fileID(info)
+		if m.alertLogPath != "" {
+			logInfo, err := os.Stat(m.alertLogPath)
+			stream.alertLog = err == nil && os.SameFile(info, logInfo)
+		}
+	}
+	if m.checkpoints != nil && stream.fileID != "" {
+		if offset, ok := m.checkpoints.lookup(stream.fileID, path); ok {
+			stream.resumeAt = offset
+		}
+	}
+	if offset, ok := m.restarted[stream.fileID]; ok && stream.fileID != "" {
+		if isCompressed(path) {
+			// Already read in full before the restart
+			cancel()
+			file.Close()
+			return nil, nil
+		}
+		stream.resumeAt = offset
 	}
 
 	m.streams[path] = stream
 
-	go stream.read(m.ctx, m.entries, m.tailOnly)
+	// Compressed files are rotated history: read them once, never tail
+	if isCompressed(path) {
+		stream.WatchMode = WatchArchive
+		go stream.readCompressed(ctx, m.entries, m.historyLines(cfg))
+		return stream.loaded, nil
+	}
 
-	return nil
+	// Watch the directory rather than the file so rotation is observed
+	if m.watch(filepath.Dir(path)) {
+		stream.WatchMode = WatchNotify
+		stream.interval = notifyFallbackInterval
+	}
+
+	history := m.historyLines(cfg)
+	go func() {
+		stream.read(ctx, m.entries, history)
+		if stream.removed.Load() {
+			m.forget(stream)
+		}
+	}()
+
+	return stream.loaded, nil
 }
 
-func (m *Manager) watchDirectory(cfg config.StreamConfig) {
+// newStream builds a stream reading path for cfg, polled until its caller
+// picks a watch mode. cancel stops its read loop.
+func (m *Manager) newStream(cfg config.StreamConfig, path string, cancel context.CancelFunc, after <-chan struct{}) (*Stream, error) {
+	timestamps, err := newTimestampParser(cfg)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := newLevelParser(cfg)
+	if err != nil {
+		return nil, err
+	}
+	multiline, err := newMultiline(cfg)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := newLineFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Stream{
+		Config:     cfg,
+		Path:       path,
+		LineNumber: 0,
+		Done:       make(chan struct{}),
+		cancel:     cancel,
+		WatchMode:  WatchPoll,
+		wake:       make(chan struct{}, 1),
+		interval:   pollInterval,
+		timestamps: timestamps,
+		levels:     levels,
+		multiline:  multiline,
+		repeats:    newRepeats(cfg),
+		limiter:    newRateLimiter(cfg),
+		json:       newJSONParser(cfg),
+		filter:     filter,
+		maxLine:    maxLineLength(cfg),
+		resumeAt:   -1,
+		overflow:   m.overflow,
+		queue:      m.entries,
+		loaded:     make(chan struct{}),
+		after:      after,
+	}, nil
+}
+
+func isCompressed(path string) bool {
+	return strings.HasSuffix(path, ".gz")
+}
+
+// watchDirectory picks up new files matching cfg until ctx is cancelled.
+func (m *Manager) watchDirectory(ctx context.Context, cfg config.StreamConfig) {
+	if m.watchTree(cfg, filepath.Clean(cfg.Path)) {
+		return
+	}
+
 	go func() {
 		ticker := time.NewTicker(5 * time.Second)
 		defer ticker.Stop()
 
 		for {
 			select {
-			case <-m.ctx.Done():
+			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				matches, _ := filepath.Glob(filepath.Join(cfg.Path, "*"))
+				matches, _ := listFiles(cfg)
 				for _, match := range matches {
-					if cfg.Matches(match) {
+					if tailsNew(cfg, match) {
 						_ = m.addFile(cfg, match)
 					}
 				}
@@ -130,20 +704,116 @@ func (m *Manager) watchDirectory(cfg config.StreamConfig) {
 	}()
 }
 
-func (s *Stream) read(ctx context.Context, entries chan<- LogEntry, tailOnly bool) {
-	defer s.File.Close()
+// watchTree subscribes cfg to the files created in dir and, for a
+// recursive stream, in its subdirectories. Matching files created before
+// the watches were registered are picked up. It reports false if dir
+// cannot be watched and the caller should poll instead.
+func (m *Manager) watchTree(cfg config.StreamConfig, dir string) bool {
+	if !m.watch(dir) {
+		return false
+	}
+	dirs := []string{dir}
+	if cfg.IsRecursive() {
+		dirs = append(dirs, subdirectories(dir)...)
+	}
+
+	for i, d := range dirs {
+		if i > 0 && !m.watch(d) {
+			continue
+		}
+		m.watchMu.Lock()
+		if !slices.ContainsFunc(m.pending[d], func(c config.StreamConfig) bool { return c.Name == cfg.Name }) {
+			m.pending[d] = append(m.pending[d], cfg)
+		}
+		m.watchMu.Unlock()
+
+		// Pick up files created before the watch was registered
+		matches, _ := filepath.Glob(filepath.Join(d, "*"))
+		for _, match := range matches {
+			if tailsNew(cfg, match) {
+				_ = m.addFile(cfg, match)
+			}
+		}
+	}
+	return true
+}
+
+// listFiles returns the paths Tail considers for cfg: the entries of its
+// directory, and for a recursive stream the files of its subdirectories.
+func listFiles(cfg config.StreamConfig) ([]string, error) {
+	if !cfg.IsRecursive() {
+		return filepath.Glob(filepath.Join(cfg.Path, "*"))
+	}
+	var files []string
+	err := filepath.WalkDir(cfg.Path, func(path string, d fs.DirEntry, err error) error {
+		// A missing or unreadable directory has no files to tail yet
+		if err == nil && !d.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// subdirectories returns the directories below dir, at any depth.
+func subdirectories(dir string) []string {
+	var dirs []string
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() && path != dir {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	return dirs
+}
+
+// read loads the last history lines of the file, or all of them if history
+// is negative, then follows it.
+func (s *Stream) read(ctx context.Context, entries chan<- LogEntry, history int) {
+	// Close the file before signalling Done, so Wait means it is released
 	defer close(s.Done)
+	defer func() {
+		s.fileMu.Lock()
+		s.File.Close()
+		s.fileMu.Unlock()
+	}()
+	defer s.finishHistory()
 
 	var offset int64 = 0
 
-	// If tailOnly, start at end of file (skip history)
-	if tailOnly {
+	// Without history, start at the end of the file
+	if history == 0 && s.resumeAt < 0 {
 		var err error
 		offset, err = s.File.Seek(0, io.SeekEnd)
 		if err != nil {
 			return
 		}
+		s.finishHistory()
+	} else {
+		if !s.waitTurn(ctx) {
+			return
+		}
+		info, err := s.File.Stat()
+		if err != nil {
+			return
+		}
+		switch {
+		case s.resumeAt >= 0:
+			// Carry on from the last run, unless the file has since been
+			// truncated below what it read
+			if s.resumeAt <= info.Size() {
+				offset = s.resumeAt
+			}
+		case history > 0:
+			offset, err = lastLines(s.File, info.Size(), history)
+			if err != nil {
+				return
+			}
+		}
+		s.historySize.Store(info.Size() - offset)
 	}
+	start := offset
+	s.offset.Store(offset)
 
 	for {
 		select {
@@ -159,49 +829,434 @@ func (s *Stream) read(ctx context.Context, entries chan<- LogEntry, tailOnly boo
 				if _, err := s.File.Seek(offset, io.SeekStart); err != nil {
 					return
 				}
+				// Advance offset only past complete lines, so a partially
+				// written trailing line is re-read once it is finished and
+				// truncation is measured against what was really consumed
 				reader := bufio.NewReader(s.File)
 				for {
-					line, err := reader.ReadString('\n')
+					line, n, dropped, err := readLine(reader, s.maxLine)
 					if err != nil {
 						if err == io.EOF {
 							break
 						}
 						return
 					}
+					offset += n
+					if !s.historyDone.Load() {
+						s.historyRead.Store(min(offset-start, s.historySize.Load()))
+					}
 
 					s.LineNumber++
-					entry := LogEntry{
-						Timestamp:  time.Now(),
-						Source:     s.Config.Name,
-						Content:    strings.TrimSuffix(line, "\n"),
-						Tags:       s.Config.Tags,
-						LineNumber: s.LineNumber,
-					}
+					s.countLine(n)
+					entry := s.newEntry(line, dropped)
 
-					select {
-					case entries <- entry:
-					case <-ctx.Done():
+					if !s.admit(ctx, entries, entry) {
 						return
-					default:
-						go func(e LogEntry) {
-							entries <- e
-						}(entry)
 					}
+					s.offset.Store(offset)
+				}
+			}
+
+			// Check for rotation only after draining the current file, so
+			// lines written just before a rename are not lost
+			if reason := s.checkRotation(offset, time.Now()); reason != "" {
+				if !s.flushPending(ctx, entries) {
+					return
 				}
-				newOffset, err := s.File.Seek(0, io.SeekCurrent)
-				if err != nil {
+				if reason == "removed" {
+					// Release the file; it is tailed afresh if it comes back
+					s.removed.Store(true)
+					s.emit(ctx, entries, LogEntry{
+						Timestamp: time.Now(),
+						Source:    s.Config.Name,
+						Content:   "--- file removed ---",
+						Tags:      append(append([]string{}, s.Config.Tags...), "rotation"),
+					})
 					return
 				}
-				offset = newOffset
+				offset = 0
+				start = 0
+				s.offset.Store(0)
+				s.LineNumber = 0
+				marker := LogEntry{
+					Timestamp: time.Now(),
+					Source:    s.Config.Name,
+					Content:   fmt.Sprintf("--- file %s ---", reason),
+					Tags:      append(append([]string{}, s.Config.Tags...), "rotation"),
+				}
+				if !s.emit(ctx, entries, marker) {
+					return
+				}
+				// Read the new content right away instead of waiting
+				continue
+			}
+		}
+
+		// Everything up to here was history; from now on lines are live
+		s.finishHistory()
+
+		wait := s.interval
+		if remaining, ok := s.pendingWait(time.Now()); ok && remaining < wait {
+			wait = max(remaining, 0)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-time.After(wait):
+		}
+
+		// Don't hold pending entries forever if no line follows them
+		if !s.flushExpired(ctx, entries, time.Now()) {
+			return
+		}
+	}
+}
+
+// readCompressed emits every line of a gzip file once and then closes it.
+// An archive is all history, so it is only read when the whole history is
+// wanted (history negative).
+func (s *Stream) readCompressed(ctx context.Context, entries chan<- LogEntry, history int) {
+	defer close(s.Done)
+	defer func() {
+		s.fileMu.Lock()
+		s.File.Close()
+		s.fileMu.Unlock()
+	}()
+	defer s.finishHistory()
+
+	// An archive with a checkpoint was read by an earlier run
+	if history >= 0 || s.resumeAt > 0 || !s.waitTurn(ctx) {
+		return
+	}
+
+	info, err := s.File.Stat()
+	if err != nil {
+		return
+	}
+	s.historySize.Store(info.Size())
+	gz, err := gzip.NewReader(&progressReader{r: s.File, read: &s.historyRead})
+	if err != nil {
+		return
+	}
+	defer gz.Close()
+
+	reader := bufio.NewReader(gz)
+	for {
+		line, n, dropped, err := readLine(reader, s.maxLine)
+		if line != "" {
+			s.LineNumber++
+			s.countLine(n)
+			entry := s.newEntry(line, dropped)
+			if s.relPath == "" {
+				entry.Tags = append(slices.Clone(entry.Tags), filepath.Base(s.Path))
+			}
+			if !s.deliver(ctx, entries, entry) {
+				return
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				s.offset.Store(info.Size())
+			}
+			break
+		}
+	}
+
+	s.flushPending(ctx, entries)
+}
+
+// progressReader counts the bytes read through it.
+type progressReader struct {
+	r    io.Reader
+	read *atomic.Int64
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read.Add(int64(n))
+	return n, err
+}
+
+// newEntry builds the entry for a line read from the file, dropped being
+// how many bytes readLine cut from it.
+func (s *Stream) newEntry(line string, dropped int64) LogEntry {
+	entry := s.buildEntry(strings.TrimSuffix(line, "\n"), s.LineNumber, time.Now())
+	entry.noAlert = s.alertLog || !s.historyDone.Load()
+	if dropped > 0 {
+		markTruncated(&entry, dropped)
+	}
+	if s.relPath != "" {
+		entry.Tags = append(slices.Clone(entry.Tags), s.relPath)
+	}
+	return entry
+}
+
+// buildEntry parses a line into an entry, timestamped fallback if the line
+// has no timestamp of its own.
+func (s *Stream) buildEntry(content string, lineNumber int, fallback time.Time) LogEntry {
+	timestamp, timed := s.timestamps.parse(content, fallback)
+	entry := LogEntry{
+		Timestamp:  timestamp,
+		Timed:      timed,
+		Source:     s.Config.Name,
+		Content:    content,
+		Tags:       s.Config.Tags,
+		LineNumber: lineNumber,
+	}
+	s.json.apply(&entry)
+	if level, ok := entry.Fields["level"]; ok {
+		entry.Level = NormalizeLevel(level)
+	} else {
+		entry.Level = parseLevel(s.levels, content)
+	}
+	return entry
+}
+
+// admit applies sampling and rate limiting before delivering a line.
+func (s *Stream) admit(ctx context.Context, entries chan<- LogEntry, entry LogEntry) bool {
+	if s.limiter == nil {
+		return s.deliver(ctx, entries, entry)
+	}
+
+	ok, suppressed := s.limiter.allow(time.Now())
+	if !ok {
+		s.dropped.Add(1)
+		return true
+	}
+
+	if suppressed > 0 {
+		marker := LogEntry{
+			Timestamp: entry.Timestamp,
+			Source:    s.Config.Name,
+			Content:   fmt.Sprintf("[dropped %d lines]", suppressed),
+			Tags:      append(append([]string{}, s.Config.Tags...), "dropped"),
+		}
+		if !s.flushPending(ctx, entries) || !s.emit(ctx, entries, marker) {
+			return false
+		}
+	}
+
+	return s.deliver(ctx, entries, entry)
+}
+
+// deliver passes a line through multiline grouping, if configured, and
+// emits whatever entry is complete.
+func (s *Stream) deliver(ctx context.Context, entries chan<- LogEntry, entry LogEntry) bool {
+	if s.multiline == nil {
+		return s.emitKept(ctx, entries, entry)
+	}
+	if done := s.multiline.add(entry, time.Now()); done != nil {
+		return s.emitKept(ctx, entries, *done)
+	}
+	return true
+}
+
+func (s *Stream) flushMultiline(ctx context.Context, entries chan<- LogEntry) bool {
+	if s.multiline == nil {
+		return true
+	}
+	if done := s.multiline.flush(); done != nil {
+		return s.emitKept(ctx, entries, *done)
+	}
+	return true
+}
+
+// emitKept emits a complete entry unless the stream's include or exclude
+// patterns filter it out.
+func (s *Stream) emitKept(ctx context.Context, entries chan<- LogEntry, entry LogEntry) bool {
+	if !s.filter.keep(entry.Content) {
+		s.excluded.Add(1)
+		return true
+	}
+	return s.emit(ctx, entries, entry)
+}
+
+// flushPending emits the entries held back by multiline grouping and
+// repeat collapsing.
+func (s *Stream) flushPending(ctx context.Context, entries chan<- LogEntry) bool {
+	if !s.flushMultiline(ctx, entries) {
+		return false
+	}
+	if s.repeats == nil {
+		return true
+	}
+	for _, entry := range s.repeats.flush() {
+		if !s.send(ctx, entries, entry) {
+			return false
+		}
+	}
+	return true
+}
+
+// pendingWait reports how long until a held-back entry is due, or false
+// if nothing is held back.
+func (s *Stream) pendingWait(now time.Time) (time.Duration, bool) {
+	var wait time.Duration
+	var pending bool
+	if s.multiline != nil {
+		wait, pending = s.multiline.wait(now)
+	}
+	if s.repeats != nil {
+		if remaining, ok := s.repeats.wait(now); ok && (!pending || remaining < wait) {
+			wait, pending = remaining, true
+		}
+	}
+	return wait, pending
+}
+
+// flushExpired emits the held-back entries that are due.
+func (s *Stream) flushExpired(ctx context.Context, entries chan<- LogEntry, now time.Time) bool {
+	if s.multiline != nil {
+		if remaining, ok := s.multiline.wait(now); ok && remaining <= 0 {
+			if !s.flushMultiline(ctx, entries) {
+				return false
+			}
+		}
+	}
+	if s.repeats != nil {
+		if remaining, ok := s.repeats.wait(now); ok && remaining <= 0 {
+			for _, entry := range s.repeats.flush() {
+				if !s.send(ctx, entries, entry) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// emit passes entry through repeat collapsing, if configured, and sends
+// whatever is ready.
+func (s *Stream) emit(ctx context.Context, entries chan<- LogEntry, entry LogEntry) bool {
+	if s.repeats == nil {
+		return s.send(ctx, entries, entry)
+	}
+	for _, ready := range s.repeats.add(entry, time.Now()) {
+		if !s.send(ctx, entries, ready) {
+			return false
+		}
+	}
+	return true
+}
+
+// send passes entry to the Manager, applying the overflow policy when the
+// channel is full. It returns false once ctx is done.
+func (s *Stream) send(ctx context.Context, entries chan<- LogEntry, entry LogEntry) bool {
+	switch s.overflow {
+	case OverflowDropNewest:
+		s.reportOverflow(entries)
+		select {
+		case entries <- entry:
+		case <-ctx.Done():
+			return false
+		default:
+			s.dropOverflow()
+		}
+		return true
+
+	case OverflowDropOldest:
+		s.reportOverflow(entries)
+		for {
+			select {
+			case entries <- entry:
+				return true
+			case <-ctx.Done():
+				return false
+			default:
+			}
+			select {
+			case <-s.queue:
+				s.dropOverflow()
+			default:
 			}
 		}
+	}
+
+	select {
+	case entries <- entry:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// dropOverflow counts an entry discarded by the overflow policy.
+func (s *Stream) dropOverflow() {
+	s.overflowed.Add(1)
+	s.unreported++
+}
 
-		time.Sleep(100 * time.Millisecond)
+// reportOverflow queues an entry reporting the lines dropped since the
+// last report, if there were any and there is room for it.
+func (s *Stream) reportOverflow(entries chan<- LogEntry) {
+	if s.unreported == 0 {
+		return
+	}
+	marker := LogEntry{
+		Timestamp: time.Now(),
+		Source:    s.Config.Name,
+		Content:   fmt.Sprintf("[overflow: %d lines dropped]", s.unreported),
+		Tags:      append(append([]string{}, s.Config.Tags...), "overflow"),
+	}
+	select {
+	case entries <- marker:
+		s.unreported = 0
+	default:
 	}
 }
 
-func (m *Manager) Entries() <-chan LogEntry {
-	return m.entries
+// checkRotation detects whether the file at s.Path was truncated in place
+// (copytruncate) or replaced by a new file (rename and create). On
+// replacement the new file is opened in place of the old one. It returns
+// "truncated" or "rotated" when reading should restart from offset zero,
+// and "removed" once the path has been gone for longer than removedGrace.
+func (s *Stream) checkRotation(offset int64, now time.Time) string {
+	pathInfo, err := os.Stat(s.Path)
+	if err != nil {
+		// The path can briefly disappear mid-rotation; keep the old file
+		if !os.IsNotExist(err) {
+			return ""
+		}
+		if s.missingSince.IsZero() {
+			s.missingSince = now
+		} else if now.Sub(s.missingSince) > removedGrace {
+			return "removed"
+		}
+		return ""
+	}
+	s.missingSince = time.Time{}
+
+	fileInfo, err := s.File.Stat()
+	if err != nil {
+		return ""
+	}
+
+	if !os.SameFile(fileInfo, pathInfo) {
+		file, err := os.Open(s.Path)
+		if err != nil {
+			return ""
+		}
+		var id string
+		if info, err := file.Stat(); err == nil {
+			id, _ = fileID(info)
+		}
+		s.fileMu.Lock()
+		s.File.Close()
+		s.File = file
+		s.Reader = bufio.NewReader(file)
+		s.fileID = id
+		s.offset.Store(0)
+		s.fileMu.Unlock()
+		return "rotated"
+	}
+
+	if pathInfo.Size() < offset {
+		return "truncated"
+	}
+
+	return ""
 }
 
 func (m *Manager) GetStreams() map[string]*Stream {
@@ -215,29 +1270,58 @@ func (m *Manager) GetStreams() map[string]*Stream {
 	return result
 }
 
+// Close stops every stream, saves its checkpoint if resuming, and closes
+// its file. Call Wait to block until the read loops have returned.
 func (m *Manager) Close() {
 	m.cancel()
+	if m.watcher != nil {
+		m.watcher.Close()
+	}
+	_ = m.saveCheckpoints()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	for _, stream := range m.streams {
+		stream.fileMu.Lock()
 		if stream.File != nil {
 			stream.File.Close()
 		}
+		stream.fileMu.Unlock()
 	}
 }
 
+// SetBufferPolicy replaces the retention policy and immediately evicts
+// entries that no longer fit.
+func (m *Manager) SetBufferPolicy(policy BufferPolicy) {
+	m.bufferMu.Lock()
+	defer m.bufferMu.Unlock()
+
+	m.policy = policy
+	m.buffer.resize(policy.MaxEntries)
+	m.buffer.evict(policy, time.Now())
+}
+
 func (m *Manager) AddEntry(entry LogEntry) {
 	m.bufferMu.Lock()
 	defer m.bufferMu.Unlock()
 
-	m.buffer = append(m.buffer, entry)
-	if len(m.buffer) > 1000 {
-		m.buffer = m.buffer[len(m.buffer)-1000:]
-	}
+	m.seq++
+	entry.Seq = m.seq
+	m.buffer.push(entry)
+	m.buffer.evict(m.policy, time.Now())
 }
 
-func (m *Manager) Search(ctx context.Context, pattern string, source string) (<-chan LogEntry, error) {
+// searchCheckInterval is how many entries a search scans between checks
+// of its context, so it stops soon after being cancelled or timing out
+// even when few entries match.
+const searchCheckInterval = 1024
+
+// Search sends the buffered entries of source, or of every stream if it is
+// "", whose content matches pattern, oldest first. The channel is closed
+// once limit entries have been sent, if limit is positive, once the buffer
+// has been searched, or when ctx is done, so a caller that stops reading
+// early should cancel ctx.
+func (m *Manager) Search(ctx context.Context, pattern string, source string, limit int) (<-chan LogEntry, error) {
 	re, err := regexp.Compile(pattern)
 	if err != nil {
 		return nil, fmt.Errorf("invalid pattern: %w", err)
@@ -251,52 +1335,252 @@ func (m *Manager) Search(ctx context.Context, pattern string, source string) (<-
 		m.bufferMu.RLock()
 		defer m.bufferMu.RUnlock()
 
-		for _, entry := range m.buffer {
-			if source == "" || entry.Source == source {
-				if re.MatchString(entry.Content) {
-					select {
-					case results <- entry:
-					case <-ctx.Done():
-						return
-					}
-				}
+		sent, scanned := 0, 0
+		m.buffer.each(source, func(entry LogEntry) bool {
+			scanned++
+			if scanned%searchCheckInterval == 0 && ctx.Err() != nil {
+				return false
 			}
-		}
+			if !re.MatchString(entry.Content) {
+				return true
+			}
+			select {
+			case results <- entry:
+			case <-ctx.Done():
+				return false
+			}
+			sent++
+			return limit <= 0 || sent < limit
+		})
 	}()
 
 	return results, nil
 }
 
+// GetEntries returns the newest limit entries for source (all sources if
+// empty), oldest first. With a limit, only the tail of the buffer is scanned.
 func (m *Manager) GetEntries(source string, limit int) []LogEntry {
+	entries, _ := m.entriesBefore(source, 0, limit, func(LogEntry) bool { return true })
+	return entries
+}
+
+// GetEntriesFunc returns the newest limit entries for which keep returns
+// true, oldest first. keep is called with the buffer locked.
+func (m *Manager) GetEntriesFunc(limit int, keep func(LogEntry) bool) []LogEntry {
+	entries, _ := m.GetEntriesBeforeFunc(0, limit, keep)
+	return entries
+}
+
+// GetEntriesBeforeFunc is GetEntriesFunc for the entries older than the one
+// numbered before, or all entries if before is zero, so the buffer can be
+// paged backwards by passing the Seq of the oldest entry returned. more
+// reports that older matching entries remain.
+func (m *Manager) GetEntriesBeforeFunc(before uint64, limit int, keep func(LogEntry) bool) (entries []LogEntry, more bool) {
+	return m.entriesBefore("", before, limit, keep)
+}
+
+// entriesBefore is GetEntriesBeforeFunc reading only source's entries, or
+// every source's if it is empty.
+func (m *Manager) entriesBefore(source string, before uint64, limit int, keep func(LogEntry) bool) (entries []LogEntry, more bool) {
 	m.bufferMu.RLock()
 	defer m.bufferMu.RUnlock()
 
-	var entries []LogEntry
-	for _, entry := range m.buffer {
-		if source == "" || entry.Source == source {
-			entries = append(entries, entry)
+	m.buffer.reverse(source, func(entry LogEntry) bool {
+		if before > 0 && entry.Seq >= before || !keep(entry) {
+			return true
 		}
+		if limit > 0 && len(entries) == limit {
+			more = true
+			return false
+		}
+		entries = append(entries, entry)
+		return true
+	})
+
+	slices.Reverse(entries)
+	return entries, more
+}
+
+// ReadRange reads the bytes [start, end) of a tailed file straight from
+// disk and splits them into lines; the first and last may be partial. source
+// is a file path, or a stream name if that stream tails a single file. end
+// is clamped to the file size.
+func (m *Manager) ReadRange(source string, start, end int64) ([]string, error) {
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("invalid range %d-%d", start, end)
 	}
 
-	if limit > 0 && len(entries) > limit {
-		entries = entries[len(entries)-limit:]
+	stream, err := m.streamFile(source)
+	if err != nil {
+		return nil, err
+	}
+	if stream.WatchMode == WatchArchive || !stream.onDisk() {
+		return nil, fmt.Errorf("%s is not a regular file", stream.Path)
 	}
 
-	return entries
+	file, err := os.Open(stream.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if start > info.Size() {
+		return nil, fmt.Errorf("offset %d is beyond the end of %s (%d bytes)", start, stream.Path, info.Size())
+	}
+	end = min(end, info.Size())
+
+	data := make([]byte, end-start)
+	if _, err := file.ReadAt(data, start); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return []string{}, nil
+	}
+	return strings.Split(text, "\n"), nil
 }
 
+// streamFile finds the stream tailing the file at source, or the only
+// file of the stream named source.
+func (m *Manager) streamFile(source string) (*Stream, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if stream, ok := m.streams[source]; ok {
+		return stream, nil
+	}
+
+	var found []*Stream
+	for _, stream := range m.streams {
+		if stream.Config.Name == source {
+			found = append(found, stream)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no stream or file %q", source)
+	case 1:
+		return found[0], nil
+	default:
+		paths := make([]string, 0, len(found))
+		for _, stream := range found {
+			paths = append(paths, stream.Path)
+		}
+		slices.Sort(paths)
+		return nil, fmt.Errorf("stream %q has %d files, pass one of: %s", source, len(found), strings.Join(paths, ", "))
+	}
+}
+
+// GetEntriesInRange is GetEntries restricted to entries whose Timestamp
+// falls within [since, until]. A zero since or until leaves that end open.
+func (m *Manager) GetEntriesInRange(source string, since, until time.Time, limit int) []LogEntry {
+	return m.GetEntriesFunc(limit, func(entry LogEntry) bool {
+		return (source == "" || entry.Source == source) && InRange(entry.Timestamp, since, until)
+	})
+}
+
+// InRange reports whether t falls within [since, until], treating a zero
+// bound as open.
+func InRange(t, since, until time.Time) bool {
+	return (since.IsZero() || !t.Before(since)) && (until.IsZero() || !t.After(until))
+}
+
+// HistoryProgress sums Stream.HistoryProgress over the streams still
+// loading their history. loading is false once every stream is live.
+func (m *Manager) HistoryProgress() (read, total int64, loading bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, stream := range m.streams {
+		r, t, done := stream.HistoryProgress()
+		if done {
+			continue
+		}
+		read += r
+		total += t
+		loading = true
+	}
+	return read, total, loading
+}
+
+// BufferPolicy returns the policy the buffer is currently kept to.
+func (m *Manager) BufferPolicy() BufferPolicy {
+	m.bufferMu.RLock()
+	defer m.bufferMu.RUnlock()
+	return m.policy
+}
+
+// BufferLen returns the number of buffered entries.
+func (m *Manager) BufferLen() int {
+	m.bufferMu.RLock()
+	defer m.bufferMu.RUnlock()
+	return m.buffer.size
+}
+
+// Cursor returns the sequence number of the newest buffered entry.
+func (m *Manager) Cursor() uint64 {
+	m.bufferMu.RLock()
+	defer m.bufferMu.RUnlock()
+	return m.seq
+}
+
+// GetEntriesSince returns up to limit buffered entries newer than cursor,
+// oldest first, and the cursor to pass next time. Sequence numbers are never
+// reused, so the cursor stays valid as old entries are evicted.
+func (m *Manager) GetEntriesSince(source string, cursor uint64, limit int) ([]LogEntry, uint64) {
+	entries, next, _ := m.GetEntriesSinceFunc(cursor, limit, func(entry LogEntry) bool {
+		return source == "" || entry.Source == source
+	})
+	return entries, next
+}
+
+// GetEntriesSinceFunc is GetEntriesSince for the entries keep returns true
+// for. truncated reports that entries newer than cursor were evicted from
+// the buffer before they could be returned. keep is called with the buffer
+// locked.
+func (m *Manager) GetEntriesSinceFunc(cursor uint64, limit int, keep func(LogEntry) bool) (entries []LogEntry, next uint64, truncated bool) {
+	m.bufferMu.RLock()
+	defer m.bufferMu.RUnlock()
+
+	truncated = m.buffer.evicted > cursor
+
+	next = max(cursor, m.seq)
+	m.buffer.each("", func(entry LogEntry) bool {
+		if entry.Seq <= cursor || !keep(entry) {
+			return true
+		}
+		if limit > 0 && len(entries) == limit {
+			// More remain; resume after the last entry returned
+			next = entries[len(entries)-1].Seq
+			return false
+		}
+		entries = append(entries, entry)
+		return true
+	})
+
+	return entries, next, truncated
+}
+
+// GetBuffer returns every buffered entry, merged across the streams in the
+// order they were read.
 func (m *Manager) GetBuffer() []LogEntry {
 	m.bufferMu.RLock()
 	defer m.bufferMu.RUnlock()
 
-	result := make([]LogEntry, len(m.buffer))
-	copy(result, m.buffer)
-	return result
+	return m.buffer.snapshot()
 }
 
+// StartBuffering subscribes the buffer to the streams, so entries are kept
+// for Search, GetEntries and the other buffer queries.
 func (m *Manager) StartBuffering() {
+	entries := m.Subscribe(m.ctx)
 	go func() {
-		for entry := range m.entries {
+		for entry := range entries {
 			m.AddEntry(entry)
 		}
 	}()
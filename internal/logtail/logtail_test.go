@@ -0,0 +1,284 @@
+package logtail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/appgram/logdump/internal/config"
+)
+
+// newTestManager returns a Manager closed when the test ends.
+func newTestManager(t testing.TB) *Manager {
+	t.Helper()
+	m := NewManager()
+	t.Cleanup(func() {
+		m.Close()
+		m.Wait()
+	})
+	return m
+}
+
+// subscribe subscribes to m until the test ends.
+func subscribe(t *testing.T, m *Manager) <-chan LogEntry {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return m.Subscribe(ctx)
+}
+
+// fileStream is a stream tailing the file name in dir.
+func fileStream(name, dir, file string) config.StreamConfig {
+	return config.StreamConfig{Name: name, Path: dir, Patterns: []string{file}}
+}
+
+// writeFile replaces the content of path with lines.
+func writeFile(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(joinLines(lines)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// appendFile appends lines to path.
+func appendFile(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(joinLines(lines)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// waitFor fails the test unless cond holds within five seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// receive returns the contents of entries as they arrive until want of
+// them have, failing the test if they don't within five seconds.
+func receive(t *testing.T, entries <-chan LogEntry, want int) []string {
+	t.Helper()
+	var got []string
+	timeout := time.After(5 * time.Second)
+	for len(got) < want {
+		select {
+		case e, ok := <-entries:
+			if !ok {
+				t.Fatalf("subscription closed after %q", got)
+			}
+			if !e.IsHeartbeat() {
+				got = append(got, e.Content)
+			}
+		case <-timeout:
+			t.Fatalf("got %q, want %d entries", got, want)
+		}
+	}
+	return got
+}
+
+// historyLoaded waits until m has read the existing content of its files.
+func historyLoaded(t *testing.T, m *Manager) {
+	t.Helper()
+	waitFor(t, "history to load", func() bool {
+		_, _, loading := m.HistoryProgress()
+		return !loading
+	})
+}
+
+// numberedLines returns n lines "line 0", "line 1" and so on.
+func numberedLines(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i)
+	}
+	return lines
+}
+
+// maxGoroutines is how many goroutines a test may add above its starting
+// count: a few per stream and subscription, never one per entry.
+const maxGoroutines = 20
+
+func TestFloodKeepsGoroutinesBounded(t *testing.T) {
+	m := newTestManager(t)
+	before := runtime.NumGoroutine()
+
+	// With nobody subscribed the entries channel fills and stays full
+	dir := t.TempDir()
+	lines := numberedLines(3 * cap(m.entries))
+	writeFile(t, filepath.Join(dir, "flood.log"), lines...)
+	if err := m.Tail(fileStream("flood", dir, "flood.log")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the entries channel to fill", func() bool { return len(m.entries) == cap(m.entries) })
+	time.Sleep(100 * time.Millisecond)
+	if n := runtime.NumGoroutine(); n > before+maxGoroutines {
+		t.Fatalf("%d goroutines while the channel is full, started with %d", n, before)
+	}
+
+	// The blocked reader resumes as entries are taken, losing none and
+	// keeping their order
+	for i, want := range lines {
+		select {
+		case e := <-m.entries:
+			if e.Content != want {
+				t.Fatalf("entry %d is %q, want %q", i, e.Content, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no entry after %d", i)
+		}
+	}
+	if st := m.Stats().Streams["flood"]; st.Overflowed != 0 {
+		t.Errorf("%d entries overflowed under the blocking policy", st.Overflowed)
+	}
+}
+
+func TestOverflowDropsAreCounted(t *testing.T) {
+	tests := []struct {
+		name     string
+		overflow Overflow
+		newest   bool // the channel ends up holding the newest lines
+	}{
+		{"drop-newest", OverflowDropNewest, false},
+		{"drop-oldest", OverflowDropOldest, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t)
+			m.SetOverflow(tt.overflow)
+			before := runtime.NumGoroutine()
+
+			// Nobody subscribes, so all but a channel's worth are dropped
+			// and the reader never waits
+			dir := t.TempDir()
+			lines := numberedLines(3 * cap(m.entries))
+			writeFile(t, filepath.Join(dir, "flood.log"), lines...)
+			if err := m.Tail(fileStream("flood", dir, "flood.log")); err != nil {
+				t.Fatal(err)
+			}
+			historyLoaded(t, m)
+			if n := runtime.NumGoroutine(); n > before+maxGoroutines {
+				t.Fatalf("%d goroutines after the flood, started with %d", n, before)
+			}
+
+			st := m.Stats().Streams["flood"]
+			if want := int64(len(lines) - cap(m.entries)); st.LinesRead != int64(len(lines)) || st.Overflowed != want {
+				t.Errorf("read %d lines, %d overflowed, want %d and %d", st.LinesRead, st.Overflowed, len(lines), want)
+			}
+			kept := lines[:cap(m.entries)]
+			if tt.newest {
+				kept = lines[len(lines)-cap(m.entries):]
+			}
+			for i, want := range kept {
+				if e := <-m.entries; e.Content != want {
+					t.Fatalf("entry %d in the channel is %q, want %q", i, e.Content, want)
+				}
+			}
+		})
+	}
+}
+
+// tailApp tails app.log in a temporary directory, written with lines
+// beforehand, and returns its path once they have been received.
+func tailApp(t *testing.T, m *Manager, entries <-chan LogEntry, lines ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writeFile(t, path, lines...)
+	if err := m.Tail(fileStream("app", dir, "app.log")); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, entries, len(lines))
+	historyLoaded(t, m)
+	return path
+}
+
+func TestRotationCopyTruncate(t *testing.T) {
+	m := newTestManager(t)
+	entries := subscribe(t, m)
+	path := tailApp(t, m, entries, "before 1", "before 2")
+
+	// logrotate's copytruncate copies the file aside, then empties it in
+	// place while the service keeps appending
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, path+".1", string(data))
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path, "after")
+
+	got := receive(t, entries, 2)
+	if want := []string{"--- file truncated ---", "after"}; !slices.Equal(got, want) {
+		t.Errorf("got %q after copytruncate, want %q", got, want)
+	}
+}
+
+func TestRotationRenameCreate(t *testing.T) {
+	m := newTestManager(t)
+	entries := subscribe(t, m)
+	path := tailApp(t, m, entries, "before 1", "before 2")
+
+	// A line written just before the file is renamed aside is still read
+	// from it, then the new file created at the path
+	appendFile(t, path, "late")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, path, "after 1", "after 2", "after 3")
+
+	got := receive(t, entries, 5)
+	if want := []string{"late", "--- file rotated ---", "after 1", "after 2", "after 3"}; !slices.Equal(got, want) {
+		t.Errorf("got %q after rename and create, want %q", got, want)
+	}
+}
+
+func TestTruncateThenWrite(t *testing.T) {
+	m := newTestManager(t)
+	entries := subscribe(t, m)
+	path := tailApp(t, m, entries, "old 1", "old 2", "old 3")
+
+	// As the TUI's delete does, empty the file, then let it grow again
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := receive(t, entries, 1); got[0] != "--- file truncated ---" {
+		t.Fatalf("got %q after truncating, want the truncated marker", got)
+	}
+	appendFile(t, path, "new 1", "new 2")
+
+	for i, want := range []string{"new 1", "new 2"} {
+		select {
+		case e := <-entries:
+			if e.Content != want || e.LineNumber != i+1 {
+				t.Errorf("got %q at line %d, want %q at line %d", e.Content, e.LineNumber, want, i+1)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+}
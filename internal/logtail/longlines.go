@@ -0,0 +1,55 @@
+package logtail
+
+import (
+	"bufio"
+	"fmt"
+
+	"github.com/appgram/logdump/internal/config"
+)
+
+// DefaultMaxLineLength is how much of a line is kept when the stream sets
+// no max_line_length.
+const DefaultMaxLineLength = 64 << 10
+
+// maxLineLength returns the longest line cfg keeps, in bytes.
+func maxLineLength(cfg config.StreamConfig) int {
+	if cfg.MaxLineLength > 0 {
+		return cfg.MaxLineLength
+	}
+	return DefaultMaxLineLength
+}
+
+// readLine reads through the next newline like ReadString, but keeps at
+// most limit bytes of the line, so a huge line costs no more memory than a
+// long one. n is how many bytes were consumed and dropped how many of the
+// line's were discarded; a truncated line still ends in a newline if the
+// original did. As with ReadString, err is non-nil if and only if the line
+// does not end in a newline.
+func readLine(r *bufio.Reader, limit int) (line string, n int64, dropped int64, err error) {
+	var buf []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		n += int64(len(chunk))
+		newline := err == nil
+		if newline {
+			chunk = chunk[:len(chunk)-1]
+		}
+		keep := min(max(limit-len(buf), 0), len(chunk))
+		buf = append(buf, chunk[:keep]...)
+		dropped += int64(len(chunk) - keep)
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if newline {
+			buf = append(buf, '\n')
+		}
+		return string(buf), n, dropped, err
+	}
+}
+
+// markTruncated notes on entry that dropped bytes were cut from its line.
+func markTruncated(entry *LogEntry, dropped int64) {
+	entry.Content += fmt.Sprintf("…[truncated %d bytes]", dropped)
+	entry.Truncated = true
+}
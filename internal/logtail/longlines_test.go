@@ -0,0 +1,77 @@
+package logtail
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadLine(t *testing.T) {
+	tests := []struct {
+		input   string
+		line    string
+		dropped int64
+	}{
+		{"short\n", "short\n", 0},
+		{"exactly10!\n", "exactly10!\n", 0},
+		{"eleven byte\n", "eleven byt\n", 1},
+		{strings.Repeat("y", 100) + "\n", strings.Repeat("y", 10) + "\n", 90},
+	}
+	for _, tt := range tests {
+		// A small reader buffer makes long lines span several reads
+		r := bufio.NewReaderSize(strings.NewReader(tt.input+"next\n"), 16)
+		line, n, dropped, err := readLine(r, 10)
+		if line != tt.line || dropped != tt.dropped {
+			t.Errorf("readLine(%q) = %q, %d dropped; want %q, %d dropped", tt.input, line, dropped, tt.line, tt.dropped)
+		}
+		// Reading resumes right after the line's newline
+		next, _, _, _ := readLine(r, 10)
+		if err != nil || n != int64(len(tt.input)) || next != "next\n" {
+			t.Errorf("readLine(%q) consumed %d bytes (%v), then read %q", tt.input, n, err, next)
+		}
+	}
+
+	r := bufio.NewReader(strings.NewReader("partial"))
+	if line, n, _, err := readLine(r, 10); line != "partial" || n != 7 || err != io.EOF {
+		t.Errorf("readLine of a line without newline = %q, %d, %v", line, n, err)
+	}
+}
+
+func TestHugeLineIsTruncated(t *testing.T) {
+	m := newTestManager(t)
+	entries := subscribe(t, m)
+	dir := t.TempDir()
+	huge := strings.Repeat("x", 10<<20)
+	if err := os.WriteFile(filepath.Join(dir, "app.log"), []byte("before\n"+huge+"\nafter\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Tail(fileStream("app", dir, "app.log")); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []LogEntry
+	for len(got) < 3 {
+		select {
+		case e := <-entries:
+			got = append(got, e)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %d entries, want 3", len(got))
+		}
+	}
+	want := strings.Repeat("x", DefaultMaxLineLength) + fmt.Sprintf("…[truncated %d bytes]", len(huge)-DefaultMaxLineLength)
+	if !got[1].Truncated || got[1].Content != want {
+		t.Errorf("huge line has %d bytes, truncated %v, ending %q", len(got[1].Content), got[1].Truncated, got[1].Content[len(got[1].Content)-30:])
+	}
+	// Reading carries on at the next line
+	if got[0].Content != "before" || got[0].Truncated || got[2].Content != "after" || got[2].Truncated {
+		t.Errorf("lines around the huge one are %q and %q", got[0].Content, got[2].Content)
+	}
+	if lines := m.Stats().Streams["app"].LinesRead; lines != 3 {
+		t.Errorf("%d lines read, want 3", lines)
+	}
+}
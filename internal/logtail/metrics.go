@@ -0,0 +1,99 @@
+package logtail
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// rateSeconds is how many one-second buckets lineRate keeps, bounding the
+// longest window it can report.
+const rateSeconds = 60
+
+// lineRate counts lines in per-second buckets. It has a single writer, the
+// stream's read loop, so add is a few atomic operations; readers may see a
+// bucket mid-reset, which only skews a rate for that second.
+type lineRate struct {
+	secs   [rateSeconds]atomic.Int64 // the second each bucket counts
+	counts [rateSeconds]atomic.Int64
+}
+
+func (r *lineRate) add(now time.Time) {
+	sec := now.Unix()
+	i := sec % rateSeconds
+	if r.secs[i].Load() != sec {
+		r.counts[i].Store(0)
+		r.secs[i].Store(sec)
+	}
+	r.counts[i].Add(1)
+}
+
+// perSecond returns the average lines per second over the last window
+// seconds, counting the current one.
+func (r *lineRate) perSecond(now time.Time, window int) float64 {
+	window = min(window, rateSeconds)
+	sec := now.Unix()
+	var total int64
+	for i := range r.secs {
+		if age := sec - r.secs[i].Load(); age >= 0 && age < int64(window) {
+			total += r.counts[i].Load()
+		}
+	}
+	return float64(total) / float64(window)
+}
+
+// countLine records a line of n bytes read from the file for the stream's
+// metrics.
+func (s *Stream) countLine(n int64) {
+	now := time.Now()
+	s.linesRead.Add(1)
+	s.bytesRead.Add(n)
+	s.lastLine.Store(now.UnixNano())
+	s.rate.add(now)
+}
+
+// StreamStats is a snapshot of a stream's counters. For a stream tailing
+// several files they are summed, and LastLine is the latest of them.
+type StreamStats struct {
+	LinesRead  int64
+	BytesRead  int64
+	Dropped    int64 // discarded by sampling or rate limiting
+	Overflowed int64 // discarded by the overflow policy
+	Excluded   int64 // filtered out by include_patterns or exclude_patterns
+	Rate10s    float64
+	Rate60s    float64
+	LastLine   time.Time // zero if nothing has been read yet
+	Buffered   int       // entries held in the Manager's buffer, filled in by Manager.Stats
+	Evicted    int64     // entries pushed out of the Manager's buffer by newer ones, filled in by Manager.Stats
+}
+
+// Stats returns the stream's current counters.
+func (s *Stream) Stats() StreamStats {
+	now := time.Now()
+	stats := StreamStats{
+		LinesRead:  s.linesRead.Load(),
+		BytesRead:  s.bytesRead.Load(),
+		Dropped:    s.dropped.Load(),
+		Overflowed: s.overflowed.Load(),
+		Excluded:   s.excluded.Load(),
+		Rate10s:    s.rate.perSecond(now, 10),
+		Rate60s:    s.rate.perSecond(now, 60),
+	}
+	if ns := s.lastLine.Load(); ns != 0 {
+		stats.LastLine = time.Unix(0, ns)
+	}
+	return stats
+}
+
+// add sums other into st.
+func (st *StreamStats) add(other StreamStats) {
+	st.LinesRead += other.LinesRead
+	st.BytesRead += other.BytesRead
+	st.Dropped += other.Dropped
+	st.Overflowed += other.Overflowed
+	st.Excluded += other.Excluded
+	st.Rate10s += other.Rate10s
+	st.Rate60s += other.Rate60s
+	if other.LastLine.After(st.LastLine) {
+		st.LastLine = other.LastLine
+	}
+}
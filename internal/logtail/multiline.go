@@ -0,0 +1,113 @@
+package logtail
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/appgram/logdump/internal/config"
+)
+
+const (
+	defaultMultilineMaxLines = 500
+	defaultMultilineTimeout  = time.Second
+)
+
+// multiline joins continuation lines such as stack trace frames into the
+// entry they belong to. A line matching continuation is always appended to
+// the pending entry; otherwise, if start is set, any line not matching it is.
+type multiline struct {
+	start        *regexp.Regexp
+	continuation *regexp.Regexp
+	maxLines     int
+	timeout      time.Duration
+
+	pending *LogEntry
+	lines   int
+	updated time.Time
+}
+
+func newMultiline(cfg config.StreamConfig) (*multiline, error) {
+	if cfg.Multiline == nil || (cfg.Multiline.Pattern == "" && cfg.Multiline.Continuation == "") {
+		return nil, nil
+	}
+
+	ml := &multiline{
+		maxLines: cfg.Multiline.MaxLines,
+		timeout:  defaultMultilineTimeout,
+	}
+	if cfg.Multiline.Pattern != "" {
+		start, err := regexp.Compile(cfg.Multiline.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("stream %s: invalid multiline pattern: %w", cfg.Name, err)
+		}
+		ml.start = start
+	}
+	if cfg.Multiline.Continuation != "" {
+		continuation, err := regexp.Compile(cfg.Multiline.Continuation)
+		if err != nil {
+			return nil, fmt.Errorf("stream %s: invalid multiline continuation: %w", cfg.Name, err)
+		}
+		ml.continuation = continuation
+	}
+	if ml.maxLines <= 0 {
+		ml.maxLines = defaultMultilineMaxLines
+	}
+	if cfg.Multiline.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Multiline.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("stream %s: invalid multiline timeout %q", cfg.Name, cfg.Multiline.Timeout)
+		}
+		ml.timeout = timeout
+	}
+
+	return ml, nil
+}
+
+// add accepts the next line and returns the entry it completed, if any.
+func (ml *multiline) add(entry LogEntry, now time.Time) *LogEntry {
+	if ml.pending != nil && ml.continues(entry.Content) && ml.lines < ml.maxLines {
+		ml.pending.Content += "\n" + entry.Content
+		ml.pending.Truncated = ml.pending.Truncated || entry.Truncated
+		ml.lines++
+		ml.updated = now
+		return nil
+	}
+
+	done := ml.pending
+	ml.pending = &entry
+	ml.lines = 1
+	ml.updated = now
+	return done
+}
+
+// continues reports whether line belongs to the pending entry.
+func (ml *multiline) continues(line string) bool {
+	if ml.continuation != nil && ml.continuation.MatchString(line) {
+		return true
+	}
+	return ml.start != nil && !ml.start.MatchString(line)
+}
+
+// flush returns the pending entry, if any, and clears it.
+func (ml *multiline) flush() *LogEntry {
+	done := ml.pending
+	ml.pending = nil
+	ml.lines = 0
+	return done
+}
+
+// wait reports how long until the pending entry should be flushed, or
+// false if nothing is pending.
+func (ml *multiline) wait(now time.Time) (time.Duration, bool) {
+	if ml.pending == nil {
+		return 0, false
+	}
+	return ml.updated.Add(ml.timeout).Sub(now), true
+}
+
+// LineCount returns how many log lines an entry spans.
+func (e LogEntry) LineCount() int {
+	return strings.Count(e.Content, "\n") + 1
+}
@@ -0,0 +1,104 @@
+package logtail
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"syscall"
+)
+
+// isPipe reports whether path is a named pipe (FIFO).
+func isPipe(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode()&os.ModeNamedPipe != 0
+}
+
+// readPipe reads lines from a named pipe. Opening a FIFO blocks until a
+// writer connects, and the writer closing it ends the input, so the pipe is
+// reopened each time to wait for the next writer. A pipe has no history, so
+// history_lines makes no difference.
+func (s *Stream) readPipe(ctx context.Context, entries chan<- LogEntry) {
+	defer close(s.Done)
+	defer func() {
+		s.fileMu.Lock()
+		if s.File != nil {
+			s.File.Close()
+			s.File = nil
+		}
+		s.fileMu.Unlock()
+	}()
+
+	// A pipe has no history to load
+	s.finishHistory()
+
+	for {
+		file, err := openPipe(ctx, s.Path)
+		if err != nil {
+			return
+		}
+		s.fileMu.Lock()
+		s.File = file
+		s.Reader = bufio.NewReader(file)
+		reader := s.Reader
+		s.fileMu.Unlock()
+
+		for {
+			line, n, dropped, err := readLine(reader, s.maxLine)
+			if line != "" {
+				s.LineNumber++
+				s.countLine(n)
+				if !s.admit(ctx, entries, s.newEntry(line, dropped)) {
+					return
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+
+		// The writer went away; don't hold its last entry until the next one
+		if !s.flushPending(ctx, entries) {
+			return
+		}
+
+		s.fileMu.Lock()
+		s.File.Close()
+		s.File = nil
+		s.fileMu.Unlock()
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// openPipe opens a named pipe for reading, waiting for a writer to connect
+// or for ctx to be cancelled.
+func openPipe(ctx context.Context, path string) (*os.File, error) {
+	type result struct {
+		file *os.File
+		err  error
+	}
+	opened := make(chan result, 1)
+	go func() {
+		file, err := os.OpenFile(path, os.O_RDONLY, 0)
+		opened <- result{file, err}
+	}()
+
+	select {
+	case r := <-opened:
+		return r.file, r.err
+	case <-ctx.Done():
+		// Connect as a writer to release the blocked open, and close
+		// whatever it returns
+		go func() {
+			if r := <-opened; r.file != nil {
+				r.file.Close()
+			}
+		}()
+		if w, err := os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0); err == nil {
+			w.Close()
+		}
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,57 @@
+package logtail
+
+import (
+	"time"
+
+	"github.com/appgram/logdump/internal/config"
+)
+
+// rateLimiter thins out firehose streams, either by keeping one line in
+// every SampleRate or by capping lines per second.
+type rateLimiter struct {
+	sampleRate int
+	maxPerSec  int
+
+	seen        int
+	windowStart time.Time
+	windowCount int
+	suppressed  int // dropped by maxPerSec since the last marker
+}
+
+func newRateLimiter(cfg config.StreamConfig) *rateLimiter {
+	if cfg.SampleRate <= 1 && cfg.MaxLinesPerSec <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		sampleRate: cfg.SampleRate,
+		maxPerSec:  cfg.MaxLinesPerSec,
+	}
+}
+
+// allow reports whether a line arriving at now should be kept. When a line
+// is kept after a run of rate-limited drops, suppressed is the size of that
+// run so the caller can emit a marker first.
+func (r *rateLimiter) allow(now time.Time) (ok bool, suppressed int) {
+	if r.sampleRate > 1 {
+		r.seen++
+		if r.seen%r.sampleRate != 1 {
+			return false, 0
+		}
+	}
+
+	if r.maxPerSec > 0 {
+		if now.Sub(r.windowStart) >= time.Second {
+			r.windowStart = now
+			r.windowCount = 0
+		}
+		if r.windowCount >= r.maxPerSec {
+			r.suppressed++
+			return false, 0
+		}
+		r.windowCount++
+	}
+
+	suppressed = r.suppressed
+	r.suppressed = 0
+	return true, suppressed
+}
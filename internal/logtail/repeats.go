@@ -0,0 +1,71 @@
+package logtail
+
+import (
+	"time"
+
+	"github.com/appgram/logdump/internal/config"
+)
+
+// repeatWindow is how long a run of identical lines is counted before the
+// collapsed entry is emitted, so a line repeating forever still shows up.
+const repeatWindow = 2 * time.Second
+
+// repeats collapses identical consecutive lines. The first line of a run
+// is emitted as usual; the repeats that follow are counted and emitted as
+// one entry when a different line arrives or the window ends.
+type repeats struct {
+	last    string    // content of the last line emitted
+	seen    bool      // last is set
+	latest  LogEntry  // newest repeat of last, not yet emitted
+	count   int       // repeats of last not yet emitted
+	started time.Time // when counting began
+}
+
+func newRepeats(cfg config.StreamConfig) *repeats {
+	if !cfg.CollapseRepeats {
+		return nil
+	}
+	return &repeats{}
+}
+
+// add accepts the next entry and returns the entries ready to be sent.
+func (r *repeats) add(entry LogEntry, now time.Time) []LogEntry {
+	if r.seen && entry.Content == r.last {
+		if r.count == 0 {
+			r.started = now
+		}
+		r.count++
+		r.latest = entry
+		if now.Sub(r.started) >= repeatWindow {
+			return r.flush()
+		}
+		return nil
+	}
+
+	ready := r.flush()
+	r.last = entry.Content
+	r.seen = true
+	return append(ready, entry)
+}
+
+// flush returns the counted repeats as one entry, if there are any.
+func (r *repeats) flush() []LogEntry {
+	if r.count == 0 {
+		return nil
+	}
+	entry := r.latest
+	if r.count > 1 {
+		entry.RepeatCount = r.count
+	}
+	r.count = 0
+	return []LogEntry{entry}
+}
+
+// wait reports how long until the counted repeats are due, or false if
+// there are none.
+func (r *repeats) wait(now time.Time) (time.Duration, bool) {
+	if r.count == 0 {
+		return 0, false
+	}
+	return r.started.Add(repeatWindow).Sub(now), true
+}
@@ -0,0 +1,142 @@
+package logtail
+
+// Ring is a ring buffer. With a positive capacity, Push overwrites the
+// oldest item once the ring is full; with zero capacity the ring grows
+// without bound. Storage is allocated as items arrive, so a large capacity
+// costs nothing until it is used. Ring is not safe for concurrent use.
+type Ring[T any] struct {
+	items    []T
+	head     int // index of the oldest item
+	size     int
+	capacity int
+}
+
+// NewRing returns an empty ring holding at most capacity items, or an
+// unbounded one if capacity is zero or less.
+func NewRing[T any](capacity int) *Ring[T] {
+	return &Ring[T]{capacity: max(capacity, 0)}
+}
+
+// Cap returns the ring's capacity, zero meaning unbounded.
+func (r *Ring[T]) Cap() int {
+	return r.capacity
+}
+
+// Len returns the number of items in the ring.
+func (r *Ring[T]) Len() int {
+	return r.size
+}
+
+// Push appends item as the newest entry. If the ring was full, the oldest
+// item is overwritten and returned with evicted set to true.
+func (r *Ring[T]) Push(item T) (old T, evicted bool) {
+	if r.capacity > 0 && r.size == r.capacity {
+		old = r.items[r.head]
+		r.items[r.head] = item
+		r.head = (r.head + 1) % len(r.items)
+		return old, true
+	}
+
+	if r.size == len(r.items) {
+		r.grow()
+	}
+	r.items[(r.head+r.size)%len(r.items)] = item
+	r.size++
+	return old, false
+}
+
+// grow doubles the storage, up to capacity, unwrapping the items.
+func (r *Ring[T]) grow() {
+	n := max(2*len(r.items), 16)
+	if r.capacity > 0 && n > r.capacity {
+		n = r.capacity
+	}
+	items := make([]T, n)
+	r.copyTo(items)
+	r.items = items
+	r.head = 0
+}
+
+// PopOldest removes and returns the oldest item.
+func (r *Ring[T]) PopOldest() (item T, ok bool) {
+	if r.size == 0 {
+		return item, false
+	}
+	var zero T
+	item = r.items[r.head]
+	r.items[r.head] = zero
+	r.head = (r.head + 1) % len(r.items)
+	r.size--
+	return item, true
+}
+
+// At returns the i-th item, counting from the oldest.
+func (r *Ring[T]) At(i int) T {
+	return r.items[(r.head+i)%len(r.items)]
+}
+
+// Each calls fn on items from oldest to newest until fn returns false.
+func (r *Ring[T]) Each(fn func(T) bool) {
+	for i := 0; i < r.size; i++ {
+		if !fn(r.At(i)) {
+			return
+		}
+	}
+}
+
+// Reverse calls fn on items from newest to oldest until fn returns false.
+func (r *Ring[T]) Reverse(fn func(T) bool) {
+	for i := r.size - 1; i >= 0; i-- {
+		if !fn(r.At(i)) {
+			return
+		}
+	}
+}
+
+// Snapshot copies the items, oldest first.
+func (r *Ring[T]) Snapshot() []T {
+	result := make([]T, r.size)
+	r.copyTo(result)
+	return result
+}
+
+// Filter removes the items for which keep returns false, preserving the
+// order of the rest, and returns how many were removed.
+func (r *Ring[T]) Filter(keep func(T) bool) int {
+	var zero T
+	kept := 0
+	for i := 0; i < r.size; i++ {
+		item := r.At(i)
+		if !keep(item) {
+			continue
+		}
+		r.items[(r.head+kept)%len(r.items)] = item
+		kept++
+	}
+	for i := kept; i < r.size; i++ {
+		r.items[(r.head+i)%len(r.items)] = zero
+	}
+	removed := r.size - kept
+	r.size = kept
+	return removed
+}
+
+// Clear removes every item, releasing the storage.
+func (r *Ring[T]) Clear() {
+	r.items = nil
+	r.head = 0
+	r.size = 0
+}
+
+func (r *Ring[T]) copyTo(dst []T) {
+	if r.size == 0 {
+		return
+	}
+	end := r.head + r.size
+	if end <= len(r.items) {
+		copy(dst, r.items[r.head:end])
+		return
+	}
+	n := copy(dst, r.items[r.head:])
+	copy(dst[n:], r.items[:end-len(r.items)])
+}
@@ -0,0 +1,155 @@
+package logtail
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// items returns r's items from oldest to newest using Each, and from
+// newest to oldest using Reverse.
+func items(r *Ring[int]) (forward, backward []int) {
+	r.Each(func(i int) bool { forward = append(forward, i); return true })
+	r.Reverse(func(i int) bool { backward = append(backward, i); return true })
+	return forward, backward
+}
+
+func TestRingWraparound(t *testing.T) {
+	r := NewRing[int](4)
+	var evicted []int
+	for i := 1; i <= 10; i++ {
+		if old, ok := r.Push(i); ok {
+			evicted = append(evicted, old)
+		}
+	}
+	if want := []int{1, 2, 3, 4, 5, 6}; !slices.Equal(evicted, want) {
+		t.Errorf("evicted %v, want %v", evicted, want)
+	}
+	if r.Len() != 4 || r.Cap() != 4 {
+		t.Errorf("len %d and cap %d, want 4 and 4", r.Len(), r.Cap())
+	}
+	forward, backward := items(r)
+	if want := []int{7, 8, 9, 10}; !slices.Equal(r.Snapshot(), want) || !slices.Equal(forward, want) {
+		t.Errorf("snapshot %v and Each %v, want %v", r.Snapshot(), forward, want)
+	}
+	if want := []int{10, 9, 8, 7}; !slices.Equal(backward, want) {
+		t.Errorf("Reverse %v, want %v", backward, want)
+	}
+	if r.At(0) != 7 || r.At(3) != 10 {
+		t.Errorf("At(0) %d and At(3) %d, want 7 and 10", r.At(0), r.At(3))
+	}
+
+	// Stopping early
+	var newest []int
+	r.Reverse(func(i int) bool { newest = append(newest, i); return len(newest) < 2 })
+	if want := []int{10, 9}; !slices.Equal(newest, want) {
+		t.Errorf("Reverse stopped at %v, want %v", newest, want)
+	}
+
+	// Filtering the wrapped ring keeps the order, and frees room
+	if removed := r.Filter(func(i int) bool { return i%2 == 0 }); removed != 2 {
+		t.Errorf("Filter removed %d, want 2", removed)
+	}
+	r.Push(11)
+	r.Push(12)
+	if old, ok := r.Push(13); !ok || old != 8 {
+		t.Errorf("push into full ring evicted %d, %v, want 8", old, ok)
+	}
+	forward, backward = items(r)
+	if want := []int{10, 11, 12, 13}; !slices.Equal(forward, want) {
+		t.Errorf("after Filter, items %v, want %v", forward, want)
+	}
+	if want := []int{13, 12, 11, 10}; !slices.Equal(backward, want) {
+		t.Errorf("after Filter, Reverse %v, want %v", backward, want)
+	}
+
+	if item, ok := r.PopOldest(); !ok || item != 10 {
+		t.Errorf("PopOldest returned %d, %v, want 10", item, ok)
+	}
+	r.Clear()
+	if _, ok := r.PopOldest(); ok || r.Len() != 0 {
+		t.Errorf("cleared ring still holds %d items", r.Len())
+	}
+}
+
+func TestRingUnbounded(t *testing.T) {
+	r := NewRing[int](0)
+	for i := range 100 {
+		if _, evicted := r.Push(i); evicted {
+			t.Fatalf("unbounded ring evicted at %d", i)
+		}
+	}
+	// Grows past the initial storage after the head has moved
+	r.PopOldest()
+	for i := 100; i < 200; i++ {
+		r.Push(i)
+	}
+	snapshot := r.Snapshot()
+	if len(snapshot) != 199 || snapshot[0] != 1 || snapshot[198] != 199 {
+		t.Errorf("snapshot holds %d items from %d to %d, want 199 from 1 to 199", len(snapshot), snapshot[0], snapshot[len(snapshot)-1])
+	}
+}
+
+// BenchmarkManagerAddEntry measures the buffer at the default size. Each
+// AddEntry should take well under the 20µs that 50k entries/sec allows.
+func BenchmarkManagerAddEntry(b *testing.B) {
+	const sources = 5
+	newFull := func(b *testing.B) *Manager {
+		m := newTestManager(b)
+		m.SetBufferPolicy(BufferPolicy{MaxEntries: 10000})
+		for s := range sources {
+			addEntries(m, fmt.Sprintf("s%d", s), 10000)
+		}
+		return m
+	}
+
+	b.Run("AddEntry", func(b *testing.B) {
+		m := newFull(b)
+		entries := make([]LogEntry, sources)
+		for s := range entries {
+			entries[s] = LogEntry{Timestamp: time.Now(), Source: fmt.Sprintf("s%d", s), Content: "a line of about the usual length for a log"}
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			m.AddEntry(entries[i%sources])
+		}
+	})
+	b.Run("GetEntries", func(b *testing.B) {
+		m := newFull(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			m.GetEntries("s0", 100)
+		}
+	})
+	b.Run("GetEntriesAllSources", func(b *testing.B) {
+		m := newFull(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			m.GetEntries("", 100)
+		}
+	})
+}
+
+// BenchmarkRingPush compares the ring with the slice the buffer used to
+// be, which appended and then resliced past the evicted entries.
+func BenchmarkRingPush(b *testing.B) {
+	const capacity = 10000
+	entry := LogEntry{Source: "app", Content: "a line of about the usual length for a log"}
+
+	b.Run("ring", func(b *testing.B) {
+		r := NewRing[LogEntry](capacity)
+		for i := 0; i < b.N; i++ {
+			r.Push(entry)
+		}
+	})
+	b.Run("slice", func(b *testing.B) {
+		buffer := make([]LogEntry, 0, 1000)
+		for i := 0; i < b.N; i++ {
+			buffer = append(buffer, entry)
+			if len(buffer) > capacity {
+				buffer = buffer[len(buffer)-capacity:]
+			}
+		}
+	})
+}
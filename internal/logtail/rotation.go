@@ -0,0 +1,94 @@
+package logtail
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/appgram/logdump/internal/config"
+)
+
+// rotation describes where a file sits in a logrotate-style sequence such
+// as app.log.3.gz, app.log.2, app.log.1, app.log.
+type rotation struct {
+	base       string // path without the numeric and .gz suffixes
+	number     int    // rotation number, -1 for the active file
+	compressed bool
+}
+
+func parseRotation(path string) rotation {
+	r := rotation{base: path, number: -1}
+	if isCompressed(r.base) {
+		r.compressed = true
+		r.base = strings.TrimSuffix(r.base, ".gz")
+	}
+	if ext := filepath.Ext(r.base); len(ext) > 1 {
+		if n, err := strconv.Atoi(ext[1:]); err == nil && n >= 0 {
+			r.number = n
+			r.base = strings.TrimSuffix(r.base, ext)
+		}
+	}
+	return r
+}
+
+// tailsAtStart reports whether Tail picks up the existing file at path for
+// cfg. Compressed archives need include_rotated, and then also match through
+// the live file they were rotated from, so a pattern for app.log brings in
+// app.log.1.gz.
+func tailsAtStart(cfg config.StreamConfig, path string) bool {
+	if !isCompressed(path) {
+		return cfg.Matches(path)
+	}
+	return cfg.IncludeRotated && (cfg.Matches(path) || cfg.Matches(parseRotation(path).base))
+}
+
+// tailsNew reports whether a file appearing at path while cfg is tailed is
+// picked up. An archive created then holds lines already read from the
+// live file, so it never is.
+func tailsNew(cfg config.StreamConfig, path string) bool {
+	return !isCompressed(path) && cfg.Matches(path)
+}
+
+// sortRotated orders paths oldest first within each rotated log: compressed
+// files, then higher rotation numbers, with the active file last.
+func sortRotated(paths []string) {
+	slices.SortStableFunc(paths, func(a, b string) int {
+		ra, rb := parseRotation(a), parseRotation(b)
+		if c := strings.Compare(ra.base, rb.base); c != 0 {
+			return c
+		}
+		if ra.compressed != rb.compressed {
+			if ra.compressed {
+				return -1
+			}
+			return 1
+		}
+		if ra.number != rb.number {
+			return rb.number - ra.number
+		}
+		return strings.Compare(a, b)
+	})
+}
+
+// waitTurn blocks until the history of the previous rotated file has been
+// read. It returns false if ctx is cancelled first.
+func (s *Stream) waitTurn(ctx context.Context) bool {
+	if s.after == nil {
+		return true
+	}
+	select {
+	case <-s.after:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// finishHistory marks the history as loaded, releasing any newer rotated
+// file waiting on it.
+func (s *Stream) finishHistory() {
+	s.historyDone.Store(true)
+	s.loadedOnce.Do(func() { close(s.loaded) })
+}
@@ -0,0 +1,87 @@
+package logtail
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeGzip writes lines to path gzip-compressed.
+func writeGzip(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	if _, err := gz.Write([]byte(joinLines(lines))); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// contents returns the content of each entry.
+func contents(entries []LogEntry) []string {
+	var out []string
+	for _, e := range entries {
+		out = append(out, e.Content)
+	}
+	return out
+}
+
+func TestGzipArchiveIsReadOnce(t *testing.T) {
+	m := newTestManager(t)
+	m.StartBuffering()
+	dir := t.TempDir()
+	writeGzip(t, filepath.Join(dir, "app.log.2.gz"), "archived 1", "archived 2", "archived 3")
+
+	cfg := fileStream("app", dir, "app.log.2.gz")
+	cfg.IncludeRotated = true
+	if err := m.Tail(cfg); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the archive's lines", func() bool { return len(m.GetEntries("app", 10)) == 3 })
+	if got, want := contents(m.GetEntries("app", 10)), []string{"archived 1", "archived 2", "archived 3"}; !slices.Equal(got, want) {
+		t.Errorf("GetEntries returned %q, want %q", got, want)
+	}
+
+	// Archives are history only: the stream ends once read
+	for _, stream := range m.GetStreams() {
+		if stream.WatchMode != WatchArchive {
+			t.Errorf("stream watch mode is %q, want %q", stream.WatchMode, WatchArchive)
+		}
+		waitFor(t, "the archive's stream to end", func() bool {
+			select {
+			case <-stream.Done:
+				return true
+			default:
+				return false
+			}
+		})
+	}
+}
+
+func TestIncludeRotatedReadsOldestFirst(t *testing.T) {
+	m := newTestManager(t)
+	m.StartBuffering()
+	dir := t.TempDir()
+	writeGzip(t, filepath.Join(dir, "app.log.2.gz"), "oldest")
+	writeGzip(t, filepath.Join(dir, "app.log.1.gz"), "older")
+	writeFile(t, filepath.Join(dir, "app.log"), "current")
+
+	cfg := fileStream("app", dir, "app.log")
+	cfg.IncludeRotated = true
+	if err := m.Tail(cfg); err != nil {
+		t.Fatal(err)
+	}
+	historyLoaded(t, m)
+	waitFor(t, "every file's lines", func() bool { return len(m.GetEntries("app", 10)) == 3 })
+	if got, want := contents(m.GetEntries("app", 10)), []string{"oldest", "older", "current"}; !slices.Equal(got, want) {
+		t.Errorf("GetEntries returned %q, want %q", got, want)
+	}
+}
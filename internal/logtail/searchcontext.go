@@ -0,0 +1,127 @@
+package logtail
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// ContextSearch describes a buffer search that returns the entries around
+// each match, like grep -B/-A.
+type ContextSearch struct {
+	Pattern string
+	Source  string // stream name, or "" for all streams
+	Before  int    // entries of context before each match
+	After   int    // entries of context after each match
+	Limit   int    // maximum number of matches, 0 for no limit
+	// Filter, if set, must also accept an entry for it to count as a match.
+	Filter func(LogEntry) bool
+}
+
+// SearchResult is a run of consecutive entries from one source holding one
+// or more matches and their context. Overlapping or adjacent context
+// windows are merged into a single result.
+type SearchResult struct {
+	Entries []LogEntry
+	Matched []bool // Matched[i] is true if Entries[i] matched, false for context
+}
+
+// SearchContext searches the buffer and returns each match with up to
+// Before and After neighbouring entries from the same source, oldest
+// first. Context never includes lines from other streams.
+func (m *Manager) SearchContext(ctx context.Context, search ContextSearch) ([]SearchResult, int, error) {
+	re, err := regexp.Compile(search.Pattern)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	// Neighbours are taken per source, so split the buffer by stream
+	bySource := make(map[string][]LogEntry)
+	m.bufferMu.RLock()
+	m.buffer.each(search.Source, func(entry LogEntry) bool {
+		bySource[entry.Source] = append(bySource[entry.Source], entry)
+		return true
+	})
+	m.bufferMu.RUnlock()
+
+	var results []SearchResult
+	for _, entries := range bySource {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		windows, err := contextWindows(ctx, entries, re, search)
+		if err != nil {
+			return nil, 0, err
+		}
+		results = append(results, windows...)
+	}
+
+	slices.SortFunc(results, func(a, b SearchResult) int {
+		return compareSeq(a.Entries[0].Seq, b.Entries[0].Seq)
+	})
+
+	// Apply the limit in buffer order, keeping the last match's context
+	matches := 0
+	for i := range results {
+		for j, matched := range results[i].Matched {
+			if !matched {
+				continue
+			}
+			matches++
+			if search.Limit > 0 && matches == search.Limit {
+				end := min(j+1+search.After, len(results[i].Entries))
+				results[i].Entries = results[i].Entries[:end]
+				results[i].Matched = results[i].Matched[:end]
+				return results[:i+1], matches, nil
+			}
+		}
+	}
+
+	return results, matches, nil
+}
+
+// contextWindows finds the matches in one source's entries and groups them
+// with their context.
+func contextWindows(ctx context.Context, entries []LogEntry, re *regexp.Regexp, search ContextSearch) ([]SearchResult, error) {
+	var results []SearchResult
+	var current *SearchResult
+	start, end := 0, -1 // bounds of current, inclusive
+
+	for i, entry := range entries {
+		if i%searchCheckInterval == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !re.MatchString(entry.Content) || (search.Filter != nil && !search.Filter(entry)) {
+			continue
+		}
+
+		from := max(0, i-search.Before)
+		to := min(len(entries)-1, i+search.After)
+		if current == nil || from > end+1 {
+			results = append(results, SearchResult{})
+			current = &results[len(results)-1]
+			start, end = from, from-1
+		}
+
+		// Extend the window to cover this match's context
+		for k := end + 1; k <= to; k++ {
+			current.Entries = append(current.Entries, entries[k])
+			current.Matched = append(current.Matched, false)
+		}
+		end = max(end, to)
+		current.Matched[i-start] = true
+	}
+
+	return results, nil
+}
+
+func compareSeq(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
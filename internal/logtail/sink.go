@@ -0,0 +1,231 @@
+package logtail
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/appgram/logdump/internal/config"
+)
+
+// Sink formats.
+const (
+	SinkText = "text" // "[timestamp] [source] content" lines
+	SinkJSON = "json" // one JSON object per line
+)
+
+// defaultSinkKeep is how many rotated sink files are kept when the config
+// does not say.
+const defaultSinkKeep = 5
+
+// sinkFlushInterval is how often buffered sink output is written out.
+const sinkFlushInterval = time.Second
+
+// sink writes the entries the Manager passes on to a file, rotating it by
+// size. It reads from its own subscription, so a slow disk makes it miss
+// entries rather than hold up the streams.
+type sink struct {
+	path    string
+	json    bool
+	maxSize int64 // rotate before the file would grow past this, 0 for never
+	keep    int
+
+	mu      sync.Mutex
+	file    *os.File
+	w       *bufio.Writer
+	size    int64 // of the current file, buffered output included
+	written atomic.Int64
+	err     atomic.Value // string, the last write or rotation error
+	done    chan struct{}
+}
+
+// sinkEntry is one line of a JSON sink.
+type sinkEntry struct {
+	Timestamp  string            `json:"timestamp"`
+	Source     string            `json:"source"`
+	Level      string            `json:"level,omitempty"`
+	LineNumber int               `json:"line_number"`
+	Content    string            `json:"content"`
+	Tags       []string          `json:"tags,omitempty"`
+	Fields     map[string]string `json:"fields,omitempty"`
+}
+
+// SinkStats describes the sink, if one was started.
+type SinkStats struct {
+	Path         string
+	BytesWritten int64
+	Error        string // the last write error, "" if none
+}
+
+// StartSink appends every entry read from now on to the file at cfg.Path,
+// as text or JSON lines, until the Manager is closed. Call it before Tail,
+// as with Subscribe, for the file to include the history.
+func (m *Manager) StartSink(cfg config.SinkConfig) error {
+	s := &sink{
+		path:    cfg.Path,
+		maxSize: int64(cfg.MaxSizeMB) << 20,
+		keep:    cfg.Keep,
+		done:    make(chan struct{}),
+	}
+	switch cfg.Format {
+	case "", SinkText:
+	case SinkJSON:
+		s.json = true
+	default:
+		return fmt.Errorf("unknown sink format %q, expected %s or %s", cfg.Format, SinkText, SinkJSON)
+	}
+	if cfg.MaxSizeMB < 0 || cfg.Keep < 0 {
+		return fmt.Errorf("sink max_size_mb and keep must not be negative")
+	}
+	if s.keep == 0 {
+		s.keep = defaultSinkKeep
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.sink = s
+	m.mu.Unlock()
+
+	go s.run(m.Subscribe(m.ctx))
+	return nil
+}
+
+func (s *sink) open() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	s.file, s.size = file, info.Size()
+	if s.w == nil {
+		s.w = bufio.NewWriterSize(file, 64*1024)
+	} else {
+		s.w.Reset(file)
+	}
+	return nil
+}
+
+// run writes entries until the subscription ends, flushing on a timer and
+// once more before closing the file.
+func (s *sink) run(entries <-chan LogEntry) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(sinkFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				s.close()
+				return
+			}
+			s.write(entry)
+		case <-ticker.C:
+			s.flush()
+		}
+	}
+}
+
+func (s *sink) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file != nil {
+		s.fail(s.w.Flush())
+	}
+}
+
+func (s *sink) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file != nil {
+		s.fail(s.w.Flush())
+		s.fail(s.file.Close())
+		s.file = nil
+	}
+}
+
+func (s *sink) write(entry LogEntry) {
+	var line []byte
+	if s.json {
+		data, err := json.Marshal(sinkEntry{
+			Timestamp:  entry.Timestamp.Format(time.RFC3339Nano),
+			Source:     entry.Source,
+			Level:      entry.Level,
+			LineNumber: entry.LineNumber,
+			Content:    entry.Content,
+			Tags:       entry.Tags,
+			Fields:     entry.Fields,
+		})
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		line = append(data, '\n')
+	} else {
+		line = fmt.Appendf(nil, "[%s] [%s] %s\n", entry.Timestamp.Format(time.RFC3339), entry.Source, entry.Content)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+		s.fail(s.rotate())
+	}
+	if s.file == nil {
+		return // reopening failed in rotate, which the next write retries
+	}
+	n, err := s.w.Write(line)
+	s.size += int64(n)
+	s.written.Add(int64(n))
+	s.fail(err)
+}
+
+// rotate renames the file to path.1, shifting older ones up to path.keep,
+// and starts a new one. s.mu must be held.
+func (s *sink) rotate() error {
+	if s.file != nil {
+		if err := s.w.Flush(); err != nil {
+			return err
+		}
+		if err := s.file.Close(); err != nil {
+			return err
+		}
+		s.file = nil
+	}
+
+	_ = os.Remove(fmt.Sprintf("%s.%d", s.path, s.keep))
+	for i := s.keep - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+	}
+	if err := os.Rename(s.path, s.path+".1"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return s.open()
+}
+
+// fail records err, if any, for Stats.
+func (s *sink) fail(err error) {
+	if err != nil {
+		s.err.Store(err.Error())
+	}
+}
+
+func (s *sink) stats() *SinkStats {
+	errText, _ := s.err.Load().(string)
+	return &SinkStats{Path: s.path, BytesWritten: s.written.Load(), Error: errText}
+}
@@ -0,0 +1,112 @@
+package logtail
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/appgram/logdump/internal/config"
+)
+
+// stdinPath stands in for the path of the stream reading standard input.
+const stdinPath = "-"
+
+// tailStdin adds the stream reading standard input for cfg. Only one stream
+// can have it.
+func (m *Manager) tailStdin(cfg config.StreamConfig) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stream, ok := m.streams[stdinPath]; ok {
+		if stream.Config.Name == cfg.Name {
+			return nil
+		}
+		return fmt.Errorf("stream %s: stdin is already read by stream %s", cfg.Name, stream.Config.Name)
+	}
+	t, ok := m.tails[cfg.Name]
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(t.ctx)
+	stream, err := m.newStream(cfg, stdinPath, cancel, nil)
+	if err != nil {
+		cancel()
+		return err
+	}
+	stream.WatchMode = WatchStdin
+	m.streams[stdinPath] = stream
+	go stream.readStdin(ctx, m.entries, os.Stdin)
+	return nil
+}
+
+type stdinLine struct {
+	line       string
+	n, dropped int64
+}
+
+// readStdin emits the lines of r until it ends. A read from a terminal or
+// pipe cannot be interrupted, so lines are read by a separate goroutine,
+// which is left blocked if ctx is cancelled first.
+func (s *Stream) readStdin(ctx context.Context, entries chan<- LogEntry, r io.Reader) {
+	defer close(s.Done)
+
+	// Standard input has no history to load
+	s.finishHistory()
+
+	lines := make(chan stdinLine)
+	go func() {
+		defer close(lines)
+		reader := bufio.NewReader(r)
+		for {
+			line, n, dropped, err := readLine(reader, s.maxLine)
+			if line != "" {
+				select {
+				case lines <- stdinLine{line, n, dropped}:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		// Wake up to flush a pending entry if no line follows it
+		var expired <-chan time.Time
+		if remaining, ok := s.pendingWait(time.Now()); ok {
+			expired = time.After(max(remaining, 0))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-expired:
+			if !s.flushExpired(ctx, entries, time.Now()) {
+				return
+			}
+		case l, ok := <-lines:
+			if !ok {
+				if s.flushPending(ctx, entries) {
+					s.emit(ctx, entries, LogEntry{
+						Timestamp: time.Now(),
+						Source:    s.Config.Name,
+						Content:   "--- end of input ---",
+						Tags:      s.Config.Tags,
+					})
+				}
+				return
+			}
+			s.LineNumber++
+			s.countLine(l.n)
+			if !s.admit(ctx, entries, s.newEntry(l.line, l.dropped)) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package logtail
+
+import "context"
+
+// subscriberQueue is how many entries a subscriber may fall behind before
+// entries are dropped for it.
+const subscriberQueue = 10000
+
+type subscriber struct {
+	ch chan LogEntry
+}
+
+// Stats is a snapshot of the Manager's counters.
+type Stats struct {
+	Subscribers     int
+	SubscriberDrops int64 // entries a slow subscriber missed because its queue was full
+	BufferMemory    int64 // estimated bytes held by the buffered entries
+	Streams         map[string]StreamStats
+	Sink            *SinkStats // nil without a sink
+}
+
+// Subscribe returns a channel that receives a copy of every entry read from
+// the streams, until ctx is done or the Manager is closed, when the channel
+// is closed. Each subscriber has its own bounded queue: one that falls behind
+// misses entries rather than stalling the others.
+//
+// Entries are held back until the first subscription, so subscribe before
+// calling Tail to see every line.
+func (m *Manager) Subscribe(ctx context.Context) <-chan LogEntry {
+	sub := &subscriber{ch: make(chan LogEntry, subscriberQueue)}
+
+	m.subsMu.Lock()
+	m.subscribers[sub] = struct{}{}
+	m.subsMu.Unlock()
+
+	m.fanOutOnce.Do(func() {
+		go m.fanOut()
+	})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-m.ctx.Done():
+		}
+		m.subsMu.Lock()
+		delete(m.subscribers, sub)
+		close(sub.ch)
+		m.subsMu.Unlock()
+	}()
+
+	return sub.ch
+}
+
+// fanOut copies each entry that the filters keep to every subscriber's
+// queue.
+func (m *Manager) fanOut() {
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case entry := <-m.entries:
+			entry, keep := m.applyFilters(entry)
+			if !keep {
+				continue
+			}
+			m.subsMu.Lock()
+			for sub := range m.subscribers {
+				select {
+				case sub.ch <- entry:
+				default:
+					m.subscriberDrops.Add(1)
+				}
+			}
+			m.subsMu.Unlock()
+		}
+	}
+}
+
+// Stats returns the Manager's current counters.
+func (m *Manager) Stats() Stats {
+	stats := Stats{
+		SubscriberDrops: m.subscriberDrops.Load(),
+		Streams:         make(map[string]StreamStats),
+	}
+
+	m.subsMu.Lock()
+	stats.Subscribers = len(m.subscribers)
+	m.subsMu.Unlock()
+
+	m.mu.RLock()
+	for _, stream := range m.streams {
+		name := stream.Config.Name
+		st := stats.Streams[name]
+		st.add(stream.Stats())
+		stats.Streams[name] = st
+	}
+
+	if m.sink != nil {
+		stats.Sink = m.sink.stats()
+	}
+	m.mu.RUnlock()
+
+	m.bufferMu.RLock()
+	m.buffer.fillStats(stats.Streams)
+	stats.BufferMemory = m.buffer.memory
+	m.bufferMu.RUnlock()
+
+	return stats
+}
@@ -0,0 +1,47 @@
+package logtail
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestSlowSubscriberStallsNobody(t *testing.T) {
+	m := newTestManager(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	slow := m.Subscribe(ctx) // never read until the end
+	m.StartBuffering()
+	fast := subscribe(t, m)
+
+	// The first lines fill the slow subscriber's queue exactly
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	lines := numberedLines(subscriberQueue)
+	writeFile(t, path, lines...)
+	if err := m.Tail(fileStream("app", dir, "app.log")); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, fast, len(lines))
+	historyLoaded(t, m)
+
+	// The rest are missed by the slow subscriber alone
+	appendFile(t, path, "after 1", "after 2", "after 3")
+	got := receive(t, fast, 3)
+	if got[0] != "after 1" || got[2] != "after 3" {
+		t.Errorf("fast subscriber got %q after the slow one filled up", got)
+	}
+	want := uint64(subscriberQueue + 3)
+	waitFor(t, "the buffer to take every entry", func() bool { return m.Cursor() == want })
+	if drops := m.Stats().SubscriberDrops; drops != 3 {
+		t.Errorf("%d entries dropped for slow subscribers, want 3", drops)
+	}
+
+	// What the slow subscriber had queued is still there, in order
+	if n := len(slow); n != subscriberQueue {
+		t.Fatalf("slow subscriber has %d entries queued, want %d", n, subscriberQueue)
+	}
+	if e := <-slow; e.Content != lines[0] {
+		t.Errorf("slow subscriber's first entry is %q, want %q", e.Content, lines[0])
+	}
+}
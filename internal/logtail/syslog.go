@@ -0,0 +1,384 @@
+package logtail
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appgram/logdump/internal/config"
+)
+
+// syslogRetry is how long to wait before reopening a syslog socket that
+// could not be opened or failed.
+const syslogRetry = 5 * time.Second
+
+// syslogMaxMessage bounds a datagram or an octet-counted TCP message.
+const syslogMaxMessage = 64 * 1024
+
+// syslogLevels maps syslog severities (0 emerg to 7 debug) onto Levels.
+var syslogLevels = [8]string{"FATAL", "FATAL", "FATAL", "ERROR", "WARN", "INFO", "INFO", "DEBUG"}
+
+// syslogMessage is what was understood of a received message.
+type syslogMessage struct {
+	timestamp time.Time // zero if the message had none
+	host      string
+	level     string
+	content   string
+}
+
+// Health returns the state of a syslog stream's socket, such as
+// "listening" or the error it is being retried after, and whether it is
+// receiving. Other streams report "" and true.
+func (s *Stream) Health() (status string, ok bool) {
+	status, _ = s.health.Load().(string)
+	return status, status == "" || status == "listening"
+}
+
+// listenAddress splits a listen setting such as udp://0.0.0.0:5514 into a
+// network and an address.
+func listenAddress(cfg config.StreamConfig) (network, addr string, err error) {
+	u, err := url.Parse(cfg.Listen)
+	if err != nil {
+		return "", "", fmt.Errorf("stream %s: invalid listen address: %w", cfg.Name, err)
+	}
+	switch u.Scheme {
+	case "udp", "tcp":
+	default:
+		return "", "", fmt.Errorf("stream %s: listen address %q must start with udp:// or tcp://", cfg.Name, cfg.Listen)
+	}
+	if u.Host == "" {
+		return "", "", fmt.Errorf("stream %s: listen address %q has no host:port", cfg.Name, cfg.Listen)
+	}
+	return u.Scheme, u.Host, nil
+}
+
+// tailSyslog adds the stream receiving syslog messages on cfg's listen
+// address.
+func (m *Manager) tailSyslog(cfg config.StreamConfig) error {
+	network, addr, err := listenAddress(cfg)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stream, ok := m.streams[cfg.Listen]; ok {
+		if stream.Config.Name == cfg.Name {
+			return nil
+		}
+		return fmt.Errorf("stream %s: %s is already received by stream %s", cfg.Name, cfg.Listen, stream.Config.Name)
+	}
+	t, ok := m.tails[cfg.Name]
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(t.ctx)
+	stream, err := m.newStream(cfg, cfg.Listen, cancel, nil)
+	if err != nil {
+		cancel()
+		return err
+	}
+	stream.WatchMode = WatchSyslog
+	m.streams[cfg.Listen] = stream
+	go stream.receiveSyslog(ctx, m.entries, network, addr)
+	return nil
+}
+
+// receiveSyslog emits the messages received on addr until ctx is
+// cancelled. The socket is reopened whenever it cannot be opened or fails,
+// with the error reported by Health meanwhile.
+func (s *Stream) receiveSyslog(ctx context.Context, entries chan<- LogEntry, network, addr string) {
+	defer close(s.Done)
+
+	// A socket has no history to load
+	s.finishHistory()
+
+	messages := make(chan syslogMessage)
+	go func() {
+		for {
+			err := s.listenSyslog(ctx, network, addr, messages)
+			if ctx.Err() != nil {
+				return
+			}
+			s.health.Store(fmt.Sprintf("%v, retrying every %s", err, syslogRetry))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(syslogRetry):
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-messages:
+			s.LineNumber++
+			s.countLine(int64(len(msg.content)))
+			if !s.admit(ctx, entries, s.syslogEntry(msg)) {
+				return
+			}
+		}
+	}
+}
+
+// syslogEntry builds the entry for a message, tagged with its host.
+func (s *Stream) syslogEntry(msg syslogMessage) LogEntry {
+	content, dropped := msg.content, int64(0)
+	if len(content) > s.maxLine {
+		content, dropped = content[:s.maxLine], int64(len(content)-s.maxLine)
+	}
+	entry := s.newEntry(content, dropped)
+	if !msg.timestamp.IsZero() {
+		entry.Timestamp, entry.Timed = msg.timestamp, true
+	}
+	if entry.Level == "" {
+		entry.Level = msg.level
+	}
+	if msg.host != "" {
+		entry.Tags = append(slices.Clone(entry.Tags), msg.host)
+	}
+	return entry
+}
+
+// listenSyslog opens the socket and passes on what it receives until it
+// fails or ctx is done.
+func (s *Stream) listenSyslog(ctx context.Context, network, addr string, messages chan<- syslogMessage) error {
+	var lc net.ListenConfig
+	if network == "udp" {
+		conn, err := lc.ListenPacket(ctx, network, addr)
+		if err != nil {
+			return err
+		}
+		s.health.Store("listening")
+		stop := context.AfterFunc(ctx, func() { conn.Close() })
+		defer stop()
+		defer conn.Close()
+		return receiveDatagrams(ctx, conn, messages)
+	}
+
+	ln, err := lc.Listen(ctx, network, addr)
+	if err != nil {
+		return err
+	}
+	s.health.Store("listening")
+	stop := context.AfterFunc(ctx, func() { ln.Close() })
+	defer stop()
+	defer ln.Close()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
+			defer conn.Close()
+			receiveStream(ctx, conn, hostOf(conn.RemoteAddr()), messages)
+		}()
+	}
+}
+
+// receiveDatagrams reads one message per datagram.
+func receiveDatagrams(ctx context.Context, conn net.PacketConn, messages chan<- syslogMessage) error {
+	buf := make([]byte, syslogMaxMessage)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		msg := parseSyslog(string(buf[:n]), hostOf(from), time.Now())
+		select {
+		case messages <- msg:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// receiveStream reads the messages of a TCP connection, which are either
+// newline-terminated or prefixed with their length (RFC 6587).
+func receiveStream(ctx context.Context, conn io.Reader, from string, messages chan<- syslogMessage) {
+	reader := bufio.NewReader(conn)
+	for {
+		raw, err := readFramed(reader)
+		if raw != "" {
+			select {
+			case messages <- parseSyslog(raw, from, time.Now()):
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readFramed reads one message of a TCP syslog stream.
+func readFramed(r *bufio.Reader) (string, error) {
+	b, err := r.Peek(1)
+	if err != nil {
+		return "", err
+	}
+	if b[0] >= '1' && b[0] <= '9' {
+		// Octet counting: "<length> <message>"
+		length, err := r.ReadString(' ')
+		if err != nil {
+			return "", err
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+		if err != nil || n > syslogMaxMessage {
+			return "", fmt.Errorf("invalid syslog frame length %q", length)
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(buf), "\r\n"), nil
+	}
+
+	// A longer line is cut, the rest discarded by readLine
+	line, _, _, err := readLine(r, syslogMaxMessage)
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// hostOf returns the IP address of addr.
+func hostOf(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// parseSyslog extracts the timestamp, hostname, severity and message of an
+// RFC 5424 or RFC 3164 message. Whatever it cannot parse is kept as the
+// content, attributed to from, the sender's address.
+func parseSyslog(raw string, from string, now time.Time) syslogMessage {
+	msg := syslogMessage{host: from, content: strings.TrimRight(raw, "\r\n\x00")}
+
+	rest, level, ok := parsePriority(msg.content)
+	if !ok {
+		return msg
+	}
+	msg.level = level
+	msg.content = rest
+
+	if after, ok := strings.CutPrefix(rest, "1 "); ok {
+		parseRFC5424(&msg, after)
+	} else {
+		parseRFC3164(&msg, rest, now)
+	}
+	return msg
+}
+
+// parsePriority strips the "<PRI>" header, returning the level of its
+// severity.
+func parsePriority(s string) (rest, level string, ok bool) {
+	if !strings.HasPrefix(s, "<") {
+		return s, "", false
+	}
+	end := strings.IndexByte(s, '>')
+	if end < 2 || end > 4 {
+		return s, "", false
+	}
+	pri, err := strconv.Atoi(s[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return s, "", false
+	}
+	return s[end+1:], syslogLevels[pri%8], true
+}
+
+// parseRFC5424 parses "TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG",
+// rendering the content as "app[procid]: msg" like RFC 3164.
+func parseRFC5424(msg *syslogMessage, s string) {
+	fields := strings.SplitN(s, " ", 6)
+	if len(fields) < 6 {
+		return
+	}
+	if t, err := time.Parse(time.RFC3339Nano, fields[0]); err == nil {
+		msg.timestamp = t
+	}
+	if fields[1] != "-" {
+		msg.host = fields[1]
+	}
+	app, procid := fields[2], fields[3]
+
+	// Structured data, if any, is kept after the message
+	text, sd := fields[5], ""
+	switch {
+	case text == "-":
+		text = ""
+	case strings.HasPrefix(text, "- "):
+		text = text[2:]
+	default:
+		if end := structuredDataEnd(text); end > 0 {
+			sd, text = text[:end], strings.TrimPrefix(text[end:], " ")
+		}
+	}
+	text = strings.TrimPrefix(text, "\ufeff")
+
+	var prefix string
+	if app != "-" {
+		prefix = app
+		if procid != "-" {
+			prefix += "[" + procid + "]"
+		}
+		prefix += ": "
+	}
+	msg.content = strings.TrimSpace(prefix + text + " " + sd)
+}
+
+// structuredDataEnd returns the length of the structured data elements at
+// the start of s, or 0 if there are none.
+func structuredDataEnd(s string) int {
+	i := 0
+	for i < len(s) && s[i] == '[' {
+		i++
+		for i < len(s) && s[i] != ']' {
+			if s[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(s) {
+			return 0
+		}
+		i++
+	}
+	return i
+}
+
+// parseRFC3164 parses "Mmm dd hh:mm:ss HOSTNAME TAG: MSG". The timestamp
+// has no year, so it is taken to be within the last year of now.
+func parseRFC3164(msg *syslogMessage, s string, now time.Time) {
+	if len(s) < len(time.Stamp)+1 || s[len(time.Stamp)] != ' ' {
+		return
+	}
+	t, err := time.ParseInLocation(time.Stamp, s[:len(time.Stamp)], now.Location())
+	if err != nil {
+		return
+	}
+	t = t.AddDate(now.Year(), 0, 0)
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	msg.timestamp = t
+
+	rest := s[len(time.Stamp)+1:]
+	if host, text, ok := strings.Cut(rest, " "); ok && !strings.HasSuffix(host, ":") {
+		msg.host, rest = host, text
+	}
+	msg.content = rest
+}
@@ -0,0 +1,113 @@
+package logtail
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/appgram/logdump/internal/config"
+)
+
+// leadingTimestamp matches the common timestamp shapes at the start of a
+// line, optionally wrapped in brackets.
+var leadingTimestamp = regexp.MustCompile(`^\[?(` +
+	`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?` +
+	`|\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(?:[.,]\d+)?` +
+	`|[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}(?:[.,]\d+)?` +
+	`)`)
+
+// autoLayouts are tried in order when a stream has no timestamp_format.
+var autoLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05",
+	time.Stamp,
+}
+
+// timestampParser extracts the event time from the start of a log line.
+type timestampParser struct {
+	re     *regexp.Regexp
+	layout string
+}
+
+func newTimestampParser(cfg config.StreamConfig) (*timestampParser, error) {
+	p := &timestampParser{layout: cfg.TimestampFormat}
+	if cfg.TimestampRegex != "" {
+		re, err := regexp.Compile(cfg.TimestampRegex)
+		if err != nil {
+			return nil, fmt.Errorf("stream %s: invalid timestamp_regex: %w", cfg.Name, err)
+		}
+		p.re = re
+	}
+	return p, nil
+}
+
+// parse returns the timestamp found in line, or fallback and false if none
+// parses.
+func (p *timestampParser) parse(line string, fallback time.Time) (time.Time, bool) {
+	if p == nil {
+		return fallback, false
+	}
+
+	candidate, ok := p.extract(line)
+	if !ok {
+		return fallback, false
+	}
+
+	layouts := autoLayouts
+	if p.layout != "" {
+		layouts = []string{p.layout}
+	}
+
+	for _, layout := range layouts {
+		t, err := time.ParseInLocation(layout, candidate, time.Local)
+		if err != nil {
+			continue
+		}
+		// Layouts without a year (syslog) parse as year 0
+		if t.Year() == 0 {
+			t = t.AddDate(fallback.Year(), 0, 0)
+			if t.After(fallback.Add(24 * time.Hour)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+		}
+		return t, true
+	}
+
+	return fallback, false
+}
+
+// extract finds the timestamp text in line using the configured regex, the
+// configured layout's field count, or the built-in leading timestamp regex.
+func (p *timestampParser) extract(line string) (string, bool) {
+	if p.re != nil {
+		match := p.re.FindStringSubmatch(line)
+		if match == nil {
+			return "", false
+		}
+		if len(match) > 1 {
+			return match[1], true
+		}
+		return match[0], true
+	}
+
+	if p.layout != "" {
+		n := len(strings.Fields(p.layout))
+		fields := strings.Fields(line)
+		if len(fields) < n {
+			return "", false
+		}
+		candidate := strings.Join(fields[:n], " ")
+		return strings.TrimSuffix(strings.TrimPrefix(candidate, "["), "]"), true
+	}
+
+	match := leadingTimestamp.FindStringSubmatch(line)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
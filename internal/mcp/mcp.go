@@ -1,22 +1,34 @@
 package mcp
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"maps"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/gorilla/websocket"
 	"github.com/appgram/logdump/internal/config"
 	"github.com/appgram/logdump/internal/logtail"
+	"github.com/gorilla/websocket"
+	"gopkg.in/yaml.v3"
 )
 
 type AgentAccess struct {
@@ -37,23 +49,75 @@ type LogGroup struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// compile returns the group's pattern as a case-insensitive regexp.
+func (g LogGroup) compile() (*regexp.Regexp, error) {
+	re, err := regexp.Compile("(?i)" + g.Pattern)
+	if err != nil {
+		// Quote the pattern as it was given, without the flag
+		if _, plain := regexp.Compile(g.Pattern); plain != nil {
+			err = plain
+		}
+		return nil, fmt.Errorf("group %s has an invalid pattern: %w", g.Name, err)
+	}
+	return re, nil
+}
+
+// covers reports whether the lines of stream belong to the group. A group
+// naming no streams covers all of them.
+func (g LogGroup) covers(stream string) bool {
+	return len(g.Streams) == 0 || slices.Contains(g.Streams, stream)
+}
+
 type Server struct {
-	manager      *logtail.Manager
-	config       *config.Config
-	accessLog    []AgentAccess
-	accessMu     sync.RWMutex
-	logGroups    map[string]LogGroup
-	groupsMu     sync.RWMutex
-	currentAgent string
-	logFile      *os.File
-	logMu        sync.Mutex
+	manager   *logtail.Manager
+	config    *config.Config
+	configMu  sync.RWMutex
+	accessLog []AgentAccess
+	accessMu  sync.RWMutex
+	logGroups map[string]LogGroup
+	groupsMu  sync.RWMutex
+	logFile   *os.File
+	logMu     sync.Mutex
+	transport string // set by RunStdio, RunWebsocket or RunSSE
+	loopback  bool   // the HTTP transport listens on a loopback address
+
+	toolCalls map[string]*atomic.Int64 // calls by tool name, fixed by NewServer
+	wsClients atomic.Int64             // open websocket connections
+	inFlight  chan struct{}            // a slot per stdio request being handled
+}
+
+// currentConfig returns the config the server was last given.
+func (s *Server) currentConfig() *config.Config {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.config
+}
+
+// SetConfig replaces the server's config after it has been reloaded. Log
+// groups are kept as they are, since agents may have changed them.
+func (s *Server) SetConfig(cfg *config.Config) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	s.config = cfg
 }
 
+// maxInFlight is how many stdio requests are handled at once. Reading
+// further requests waits for one of them to finish.
+const maxInFlight = 16
+
+// stdioDrain is how long requests still in flight when stdin ends get to
+// finish before they are cancelled; a logdump_tail wait could otherwise keep
+// the process running for minutes after the client has gone.
+var stdioDrain = 2 * time.Second
+
 type MCPRequest struct {
 	JSONRPC string          `json:"jsonrpc"`
 	Method  string          `json:"method"`
 	Params  json.RawMessage `json:"params,omitempty"`
-	ID      interface{}     `json:"id,omitempty"`
+	// ID is kept as sent, so the response echoes it exactly
+	ID json.RawMessage `json:"id,omitempty"`
+
+	notification bool // the request has no id and gets no response
 }
 
 type MCPResponse struct {
@@ -61,6 +125,8 @@ type MCPResponse struct {
 	Result  interface{} `json:"result,omitempty"`
 	Error   *MCPError   `json:"error,omitempty"`
 	ID      interface{} `json:"id,omitempty"`
+
+	count int // entries or items a tool call returned, for the activity log
 }
 
 type MCPError struct {
@@ -70,9 +136,43 @@ type MCPError struct {
 }
 
 type Tool struct {
-	Name        string      `json:"name"`
-	Description string      `json:"description"`
-	InputSchema InputSchema `json:"inputSchema"`
+	Name         string        `json:"name"`
+	Description  string        `json:"description"`
+	InputSchema  InputSchema   `json:"inputSchema"`
+	OutputSchema *OutputSchema `json:"outputSchema,omitempty"`
+	Examples     []ToolExample `json:"examples,omitempty"`
+}
+
+// OutputSchema describes what a tool returns in its text content, so agents
+// can tell tools apart by their results as well as their arguments.
+type OutputSchema struct {
+	Type        string              `json:"type"`
+	Description string              `json:"description,omitempty"`
+	Properties  map[string]Property `json:"properties,omitempty"`
+	Items       *OutputSchema       `json:"items,omitempty"`
+}
+
+// ToolExample is a sample call with a short note on when to use it.
+type ToolExample struct {
+	Description string                 `json:"description"`
+	Arguments   map[string]interface{} `json:"arguments"`
+}
+
+// entrySchema is the shape of one entry in JSON output (see entryJSON).
+var entrySchema = &OutputSchema{
+	Type: "object",
+	Properties: map[string]Property{
+		"timestamp":    {Type: "string", Description: "RFC3339 time of the entry"},
+		"source":       {Type: "string", Description: "Stream name"},
+		"content":      {Type: "string", Description: "The log line, or lines for multiline entries"},
+		"line_number":  {Type: "integer", Description: "Line number in the source file"},
+		"tags":         {Type: "array", Description: "Stream tags"},
+		"level":        {Type: "string", Description: "Parsed log level, omitted if none"},
+		"fields":       {Type: "object", Description: "Structured fields, when requested"},
+		"seq":          {Type: "integer", Description: "Buffer sequence number, usable as a cursor"},
+		"repeat_count": {Type: "integer", Description: "How many identical lines the entry stands for, omitted unless collapse_repeats folded them"},
+		"truncated":    {Type: "boolean", Description: "True if the line exceeded max_line_length and was cut; content ends with a note of the bytes cut"},
+	},
 }
 
 type InputSchema struct {
@@ -90,13 +190,18 @@ type Property struct {
 func NewServer(manager *logtail.Manager, cfg *config.Config) *Server {
 	groups := make(map[string]LogGroup)
 	for _, g := range cfg.Groups {
-		groups[g.Name] = LogGroup{
+		group := LogGroup{
 			Name:      g.Name,
 			Pattern:   g.Pattern,
 			Color:     g.Color,
 			Streams:   g.Streams,
 			CreatedAt: time.Now(),
 		}
+		// Kept so it can be fixed or deleted; reads of the group fail
+		if _, err := group.compile(); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		groups[g.Name] = group
 	}
 
 	server := &Server{
@@ -104,21 +209,18 @@ func NewServer(manager *logtail.Manager, cfg *config.Config) *Server {
 		config:    cfg,
 		accessLog: make([]AgentAccess, 0, 1000),
 		logGroups: groups,
+		toolCalls: make(map[string]*atomic.Int64),
+		inFlight:  make(chan struct{}, maxInFlight),
+	}
+	for _, name := range toolNames() {
+		server.toolCalls[name] = new(atomic.Int64)
 	}
 
 	// Open MCP activity log file
-	home, _ := os.UserHomeDir()
-	logDir := filepath.Join(home, ".local", "share", "logdump", "logs")
-	_ = os.MkdirAll(logDir, 0755)
-
-	logFile, err := os.OpenFile(
-		filepath.Join(logDir, "mcp-activity.log"),
-		os.O_APPEND|os.O_CREATE|os.O_WRONLY,
-		0644,
-	)
+	logFile, err := openActivityLog()
 	if err == nil {
 		server.logFile = logFile
-		server.logActivity("MCP server started")
+		server.logActivity(context.Background(), "MCP server started")
 	} else {
 		log.Printf("Warning: Could not open MCP activity log: %v", err)
 	}
@@ -126,7 +228,37 @@ func NewServer(manager *logtail.Manager, cfg *config.Config) *Server {
 	return server
 }
 
-func (s *Server) logActivity(message string) {
+// ActivityLogPath returns the path of the MCP activity log, in the default
+// log directory.
+func ActivityLogPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "share", "logdump", "logs", "mcp-activity.log")
+}
+
+// openActivityLog opens the MCP activity log for appending, creating it
+// and its directory if needed.
+func openActivityLog() (*os.File, error) {
+	path := ActivityLogPath()
+	_ = os.MkdirAll(filepath.Dir(path), 0755)
+
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+}
+
+// LogAlert records a problem with a filter's alert in the MCP activity
+// log. It needs no Server, so alerts are logged in TUI mode too.
+func LogAlert(message string) {
+	// Nowhere else to report to: in TUI mode stderr is hidden
+	logFile, err := openActivityLog()
+	if err != nil {
+		return
+	}
+	defer logFile.Close()
+
+	timestamp := time.Now().Format("2006-01-02 15:04:05.000")
+	_, _ = fmt.Fprintf(logFile, "[%s] [ALERT] %s\n", timestamp, message)
+}
+
+func (s *Server) logActivity(ctx context.Context, message string) {
 	if s.logFile == nil {
 		return
 	}
@@ -135,17 +267,33 @@ func (s *Server) logActivity(message string) {
 	defer s.logMu.Unlock()
 
 	timestamp := time.Now().Format("2006-01-02 15:04:05.000")
-	agent := s.currentAgent
-	if agent == "" {
-		agent = "unknown"
-	}
+	agent := s.agent(ctx).id
 
 	line := fmt.Sprintf("[%s] [AGENT: %s] %s\n", timestamp, agent, message)
 	_, _ = s.logFile.WriteString(line)
 	_ = s.logFile.Sync()
 }
 
-func (s *Server) logToolCall(toolName string, args map[string]interface{}, resultCount int) {
+// ToolCalls returns how many times each tool has been called.
+func (s *Server) ToolCalls() map[string]int64 {
+	calls := make(map[string]int64, len(s.toolCalls))
+	for name, n := range s.toolCalls {
+		calls[name] = n.Load()
+	}
+	return calls
+}
+
+// WebsocketClients returns the number of open websocket connections.
+func (s *Server) WebsocketClients() int {
+	return int(s.wsClients.Load())
+}
+
+// logToolCall counts a call of a known tool and writes it to the activity
+// log.
+func (s *Server) logToolCall(ctx context.Context, toolName string, args map[string]interface{}, resultCount int) {
+	if n, ok := s.toolCalls[toolName]; ok {
+		n.Add(1)
+	}
 	if s.logFile == nil {
 		return
 	}
@@ -154,10 +302,7 @@ func (s *Server) logToolCall(toolName string, args map[string]interface{}, resul
 	defer s.logMu.Unlock()
 
 	timestamp := time.Now().Format("2006-01-02 15:04:05.000")
-	agent := s.currentAgent
-	if agent == "" {
-		agent = "unknown"
-	}
+	agent := s.agent(ctx).id
 
 	argsJSON, _ := json.Marshal(args)
 	resultInfo := ""
@@ -171,64 +316,187 @@ func (s *Server) logToolCall(toolName string, args map[string]interface{}, resul
 }
 
 func (s *Server) RunStdio(ctx context.Context) error {
+	s.transport = "stdio"
 	return s.handleStdio(ctx, os.Stdin, os.Stdout)
 }
 
+// handleStdio serves newline-delimited messages from in, writing responses
+// and notifications to out, one per line. Requests are handled
+// concurrently, up to maxInFlight at once, so responses may come out of
+// order; once in ends, the requests still running are finished.
 func (s *Server) handleStdio(ctx context.Context, in io.Reader, out io.Writer) error {
-	decoder := json.NewDecoder(in)
+	reader := bufio.NewReader(in)
 	encoder := json.NewEncoder(out)
 	encoder.SetEscapeHTML(false)
 
+	sess := newSession(func(v interface{}) error {
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+		if f, ok := out.(interface{ Flush() }); ok {
+			f.Flush()
+		}
+		return nil
+	})
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.watchSubscriptions(ctx, sess)
+	ctx = context.WithValue(ctx, sessionKey{}, sess)
+
+	var requests sync.WaitGroup
+	defer func() {
+		done := make(chan struct{})
+		go func() {
+			requests.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(stdioDrain):
+			cancel()
+			<-done
+		}
+	}()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
-			var rawReq map[string]interface{}
-			if err := decoder.Decode(&rawReq); err != nil {
+			line, err := reader.ReadBytes('\n')
+			if err != nil && (err != io.EOF || len(line) == 0) {
 				if err == io.EOF {
 					return nil
 				}
-				log.Printf("Error decoding request: %v", err)
-				continue
+				log.Printf("Error reading request: %v", err)
+				return err
 			}
-
-			var req MCPRequest
-			if data, err := json.Marshal(rawReq); err == nil {
-				_ = json.Unmarshal(data, &req)
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				continue
 			}
 
-			if req.JSONRPC == "" {
-				req.JSONRPC = "2.0"
+			select {
+			case s.inFlight <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
 			}
-
-			resp := s.handleRequest(ctx, req)
-			resp.JSONRPC = "2.0"
-
-			if err := encoder.Encode(resp); err != nil {
-				if err == io.EOF {
-					return nil
+			requests.Add(1)
+			go func() {
+				defer requests.Done()
+				defer func() { <-s.inFlight }()
+
+				resp, ok := s.respond(ctx, line)
+				if !ok || ctx.Err() != nil {
+					return
 				}
-				log.Printf("Error encoding response: %v", err)
-			}
-
-			if f, ok := out.(interface{ Flush() }); ok {
-				f.Flush()
-			}
+				if err := sess.send(resp); err != nil && err != io.EOF {
+					log.Printf("Error encoding response: %v", err)
+				}
+			}()
 		}
 	}
 }
 
 func (s *Server) RunWebsocket(ctx context.Context, addr string) error {
-	http.HandleFunc("/", s.handleWebSocket)
-	server := &http.Server{Addr: addr}
+	ln, err := s.listen("websocket", addr)
+	if err != nil {
+		return err
+	}
+
+	// Upgraded connections are hijacked from the server, so Shutdown does
+	// not wait for them; conns does
+	var conns sync.WaitGroup
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		conns.Add(1)
+		defer conns.Done()
+		s.handleWebSocket(w, r)
+	})
+	return serve(ctx, &http.Server{Handler: mux}, ln, &conns)
+}
 
+// shutdownTimeout bounds how long a stopping HTTP transport waits for
+// requests and connections in flight to finish.
+const shutdownTimeout = 5 * time.Second
+
+// serve runs server on ln until ctx is done, then shuts it down gracefully.
+// Requests are given ctx as their base context, so long-lived ones such as
+// websocket read loops end with it; conns, if not nil, are connections to
+// wait for besides those the server tracks.
+func serve(ctx context.Context, server *http.Server, ln net.Listener, conns *sync.WaitGroup) error {
+	server.BaseContext = func(net.Listener) context.Context { return ctx }
+
+	stopped := make(chan struct{})
 	go func() {
+		defer close(stopped)
 		<-ctx.Done()
-		server.Close()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			server.Close()
+		}
+		if conns == nil {
+			return
+		}
+		done := make(chan struct{})
+		go func() {
+			conns.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-shutdownCtx.Done():
+		}
 	}()
 
-	return server.ListenAndServe()
+	if err := server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	<-stopped
+	return nil
+}
+
+// listen opens addr for an HTTP transport, recording whether only
+// loopback clients can reach it.
+func (s *Server) listen(transport, addr string) (net.Listener, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("invalid listen address %q: %w", addr, err)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	tcpAddr, _ := ln.Addr().(*net.TCPAddr)
+	s.loopback = tcpAddr != nil && tcpAddr.IP.IsLoopback()
+	s.transport = transport + " " + ln.Addr().String()
+	log.Printf("MCP %s server listening on %s", transport, ln.Addr())
+	if !s.loopback && len(s.currentConfig().MCP.AllowedOrigins) == 0 {
+		log.Printf("Browsers may only connect from the server's own origin; set mcp.allowed_origins to allow others")
+	}
+	return ln, nil
+}
+
+// checkOrigin allows requests without an Origin header, which do not come
+// from browsers, and requests from mcp.allowed_origins. Without that
+// setting any origin may reach a loopback address, but only the server's
+// own origin may reach another address.
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if allowed := s.currentConfig().MCP.AllowedOrigins; len(allowed) > 0 {
+		return slices.ContainsFunc(allowed, func(a string) bool {
+			return a == "*" || strings.EqualFold(strings.TrimSuffix(a, "/"), origin)
+		})
+	}
+	if s.loopback {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
 }
 
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
@@ -237,52 +505,230 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     s.checkOrigin,
+	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
 		return
 	}
 	defer conn.Close()
+	s.wsClients.Add(1)
+	defer s.wsClients.Add(-1)
+
+	// An oversized request makes ReadMessage fail, closing the connection
+	// with status 1009 (message too big)
+	limit := s.currentConfig().MCP.MessageLimit()
+	conn.SetReadLimit(limit)
+
+	sess := newSession(func(v interface{}) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if int64(len(data)) > limit {
+			resp, ok := v.(MCPResponse)
+			if !ok {
+				return fmt.Errorf("message of %d bytes exceeds max_message_bytes (%d)", len(data), limit)
+			}
+			if data, err = json.Marshal(oversizedResponse(resp, len(data), limit)); err != nil {
+				return err
+			}
+		}
+		return conn.WriteMessage(websocket.TextMessage, data)
+	})
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go s.watchSubscriptions(ctx, sess)
+	ctx = context.WithValue(ctx, sessionKey{}, sess)
+
+	// ReadMessage does not observe ctx, so once the server is stopping,
+	// say goodbye and close the connection to end it
+	stop := context.AfterFunc(ctx, func() {
+		message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+		_ = conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+		conn.Close()
+	})
+	defer stop()
 
 	for {
-		var rawReq map[string]interface{}
-		if err := conn.ReadJSON(&rawReq); err != nil {
-			if err != io.EOF {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
 				log.Printf("Error reading request: %v", err)
 			}
 			return
 		}
 
-		var req MCPRequest
-		if data, err := json.Marshal(rawReq); err == nil {
-			_ = json.Unmarshal(data, &req)
+		resp, ok := s.respond(ctx, data)
+		if !ok {
+			continue
+		}
+		if err := sess.send(resp); err != nil {
+			log.Printf("Error writing response: %v", err)
+		}
+	}
+}
+
+// oversizedResponse replaces a response too large to send with an error
+// for the same request.
+func oversizedResponse(resp MCPResponse, size int, limit int64) MCPResponse {
+	return MCPResponse{
+		JSONRPC: resp.JSONRPC,
+		Error: &MCPError{
+			Code:    -32603,
+			Message: fmt.Sprintf("Response of %d bytes exceeds max_message_bytes (%d); request fewer entries with limit, source or a narrower pattern", size, limit),
+		},
+		ID: resp.ID,
+	}
+}
+
+// respond handles one message, reporting false for a notification, which
+// is dispatched but gets no response. Malformed JSON gets a parse error
+// with a null id, as no id can be read from it.
+func (s *Server) respond(ctx context.Context, data []byte) (MCPResponse, bool) {
+	var resp MCPResponse
+	if !json.Valid(data) {
+		resp = parseErrorResponse(errors.New("invalid JSON"))
+	} else if req, errResp := s.decodeRequest(data); errResp != nil {
+		resp = *errResp
+	} else {
+		resp = s.handleRequest(ctx, req)
+		if req.notification {
+			return MCPResponse{}, false
 		}
+	}
+	resp.JSONRPC = "2.0"
+	return resp, true
+}
 
-		if req.JSONRPC == "" {
-			req.JSONRPC = "2.0"
+// decodeRequest parses one request. Outside strict mode anything that
+// decodes is accepted, with missing or mistyped members left empty; in
+// strict mode a request that is not valid JSON-RPC 2.0 gets an Invalid
+// Request error response instead.
+func (s *Server) decodeRequest(data []byte) (MCPRequest, *MCPResponse) {
+	if s.currentConfig().MCP.Strict {
+		if err := validateRequest(data); err != nil {
+			return MCPRequest{}, &MCPResponse{
+				Error: &MCPError{
+					Code:    -32600,
+					Message: "Invalid Request: " + err.Error(),
+				},
+				ID: requestID(data),
+			}
 		}
+	}
 
-		resp := s.handleRequest(r.Context(), req)
-		resp.JSONRPC = "2.0"
+	// Mistyped members are left empty; the others are still decoded
+	var req MCPRequest
+	_ = json.Unmarshal(data, &req)
 
-		if err := conn.WriteJSON(resp); err != nil {
-			log.Printf("Error writing response: %v", err)
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err == nil {
+		_, hasID := members["id"]
+		req.notification = !hasID
+	}
+
+	if req.JSONRPC == "" {
+		req.JSONRPC = "2.0"
+	}
+	return req, nil
+}
+
+// requestMembers are the members a JSON-RPC 2.0 request may have.
+var requestMembers = map[string]bool{"jsonrpc": true, "method": true, "params": true, "id": true}
+
+// validateRequest checks data against the JSON-RPC 2.0 request object.
+func validateRequest(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil || members == nil {
+		return errors.New("request must be a JSON object")
+	}
+
+	for name := range members {
+		if !requestMembers[name] {
+			return fmt.Errorf("unexpected member %q", name)
 		}
 	}
+
+	var version string
+	if err := json.Unmarshal(members["jsonrpc"], &version); err != nil || version != "2.0" {
+		return errors.New(`"jsonrpc" must be exactly "2.0"`)
+	}
+
+	var method string
+	if err := json.Unmarshal(members["method"], &method); err != nil || method == "" {
+		return errors.New(`"method" must be a non-empty string`)
+	}
+
+	if params, ok := members["params"]; ok {
+		if c := firstByte(params); c != '{' && c != '[' {
+			return errors.New(`"params" must be an object or an array`)
+		}
+	}
+
+	if id, ok := members["id"]; ok && !validID(id) {
+		return errors.New(`"id" must be a string, a number or null`)
+	}
+
+	return nil
+}
+
+// requestID returns the request's id for an error response, or null if it
+// has none or it is not a valid id.
+func requestID(data []byte) interface{} {
+	var req struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(data, &req); err == nil && len(req.ID) > 0 && validID(req.ID) {
+		return req.ID
+	}
+	return json.RawMessage("null")
+}
+
+func parseErrorResponse(err error) MCPResponse {
+	return MCPResponse{
+		JSONRPC: "2.0",
+		Error: &MCPError{
+			Code:    -32700,
+			Message: "Parse error: " + err.Error(),
+		},
+		ID: json.RawMessage("null"),
+	}
+}
+
+// validID reports whether id is a string, a number or null.
+func validID(id json.RawMessage) bool {
+	c := firstByte(id)
+	return c == '"' || c == '-' || (c >= '0' && c <= '9') || string(id) == "null"
+}
+
+func firstByte(data json.RawMessage) byte {
+	if len(data) == 0 {
+		return 0
+	}
+	return data[0]
 }
 
 func (s *Server) handleRequest(ctx context.Context, req MCPRequest) MCPResponse {
-	id := req.ID
-	if id == nil {
+	var id interface{} = req.ID
+	if len(req.ID) == 0 {
 		id = json.RawMessage("null")
 	}
 
 	// Log the request
-	s.logActivity(fmt.Sprintf("REQUEST: %s (id: %v)", req.Method, id))
+	if req.notification {
+		s.logActivity(ctx, "NOTIFICATION: "+req.Method)
+	} else {
+		s.logActivity(ctx, fmt.Sprintf("REQUEST: %s (id: %s)", req.Method, id))
+	}
 
 	switch req.Method {
 	case "initialize":
-		return s.handleInitialize(req, id)
+		return s.handleInitialize(ctx, req, id)
 	case "tools/list":
 		return s.handleToolsList(req, id)
 	case "tools/call":
@@ -291,6 +737,10 @@ func (s *Server) handleRequest(ctx context.Context, req MCPRequest) MCPResponse
 		return s.handleResourcesList(req, id)
 	case "resources/read":
 		return s.handleResourcesRead(ctx, req, id)
+	case "resources/subscribe":
+		return s.handleSubscribe(ctx, req, id, true)
+	case "resources/unsubscribe":
+		return s.handleSubscribe(ctx, req, id, false)
 	case "logdump/set_agent":
 		return s.handleSetAgent(ctx, req, id)
 	case "logdump/access_log":
@@ -308,7 +758,41 @@ func (s *Server) handleRequest(ctx context.Context, req MCPRequest) MCPResponse
 	}
 }
 
-func (s *Server) handleInitialize(req MCPRequest, id interface{}) MCPResponse {
+// agent returns who accesses on the session a request arrived on are
+// attributed to: the client's own identity, else the configured default,
+// else "unknown".
+func (s *Server) agent(ctx context.Context) identity {
+	var who identity
+	if sess, ok := sessionFrom(ctx); ok {
+		who = sess.identity()
+	}
+	if who.id == "" {
+		who.id = s.currentConfig().MCP.DefaultAgent
+	}
+	if who.id == "" {
+		who.id = "unknown"
+	}
+	return who
+}
+
+func (s *Server) handleInitialize(ctx context.Context, req MCPRequest, id interface{}) MCPResponse {
+	// Most clients never call logdump/set_agent, but do identify themselves
+	var params struct {
+		ClientInfo struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"clientInfo"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err == nil && params.ClientInfo.Name != "" {
+		agent := params.ClientInfo.Name
+		if params.ClientInfo.Version != "" {
+			agent = fmt.Sprintf("%s (%s)", params.ClientInfo.Name, params.ClientInfo.Version)
+		}
+		if sess, ok := sessionFrom(ctx); ok {
+			sess.setIdentity(identity{id: agent, name: params.ClientInfo.Name})
+		}
+	}
+
 	return MCPResponse{
 		Result: map[string]interface{}{
 			"protocolVersion": "2024-11-05",
@@ -320,7 +804,7 @@ func (s *Server) handleInitialize(req MCPRequest, id interface{}) MCPResponse {
 				"resources": map[string]interface{}{
 					"list":      true,
 					"read":      true,
-					"subscribe": false,
+					"subscribe": true,
 				},
 			},
 			"serverInfo": map[string]interface{}{
@@ -332,8 +816,9 @@ func (s *Server) handleInitialize(req MCPRequest, id interface{}) MCPResponse {
 	}
 }
 
-func (s *Server) handleToolsList(req MCPRequest, id interface{}) MCPResponse {
-	tools := []Tool{
+// toolDefinitions returns every tool the server exposes via tools/list.
+func toolDefinitions() []Tool {
+	return []Tool{
 		{
 			Name:        "logdump_read",
 			Description: "Read log entries from active streams",
@@ -352,12 +837,61 @@ func (s *Server) handleToolsList(req MCPRequest, id interface{}) MCPResponse {
 						Type:        "integer",
 						Description: "Maximum number of entries to return (default 100)",
 					},
+					"fields": {
+						Type:        "boolean",
+						Description: "Include structured fields parsed from JSON logs (default false)",
+					},
+					"format": {
+						Type:        "string",
+						Description: "Output format: text lines or a JSON array of entries (default text)",
+						Enum:        []string{"text", "json"},
+					},
+					"since": {
+						Type:        "string",
+						Description: "Only entries at or after this time: RFC3339 or relative like -5m (optional)",
+					},
+					"until": {
+						Type:        "string",
+						Description: "Only entries at or before this time: RFC3339 or relative like -1m (optional)",
+					},
+					"exclude_untimed": {
+						Type:        "boolean",
+						Description: "Leave out entries with no timestamp of their own, which are otherwise timed by when they were read (default false)",
+					},
+					"level": {
+						Type:        "string",
+						Description: "Minimum log level; entries without a level are always included (optional)",
+						Enum:        logtail.Levels,
+					},
+					"tag": {
+						Type:        "string",
+						Description: "Only entries with this tag, such as the sending host of a syslog stream (optional)",
+					},
+					"cursor": {
+						Type:        "string",
+						Description: "Only entries after this cursor, oldest first, for incremental reads; use \"0\" to start from the oldest buffered entry and pass the returned cursor next time (optional)",
+					},
+					"before": {
+						Type:        "string",
+						Description: "Only entries older than this one, to page backwards through the buffer; pass the before value returned by the previous page (optional, not with cursor)",
+					},
 				},
 			},
+			OutputSchema: &OutputSchema{
+				Type:        "array",
+				Description: "With format json, the text content is this array of entries, oldest first; otherwise one \"[HH:MM:SS] [source] content\" line per entry. The result also carries the next cursor, and with a cursor, truncated is true if entries after it were evicted before being read. Without a cursor, has_more is true if older entries remain, and before is the value to pass for the previous page",
+				Items:       entrySchema,
+			},
+			Examples: []ToolExample{
+				{Description: "Latest 50 lines of one stream", Arguments: map[string]interface{}{"source": "app", "limit": 50}},
+				{Description: "Errors from the last 15 minutes as JSON", Arguments: map[string]interface{}{"level": "ERROR", "since": "-15m", "format": "json"}},
+				{Description: "Continue reading where the previous call stopped", Arguments: map[string]interface{}{"cursor": "4127", "limit": 200}},
+				{Description: "The page before one that returned before 3900", Arguments: map[string]interface{}{"before": "3900", "limit": 100}},
+			},
 		},
 		{
 			Name:        "logdump_grep",
-			Description: "Search through log entries with regex pattern",
+			Description: "Search through log entries with regex pattern. A search that runs longer than the timeout (mcp.grep_timeout in the config, default 5s) fails with an error rather than returning partial results",
 			InputSchema: InputSchema{
 				Type: "object",
 				Properties: map[string]Property{
@@ -379,85 +913,382 @@ func (s *Server) handleToolsList(req MCPRequest, id interface{}) MCPResponse {
 					},
 					"case_insensitive": {
 						Type:        "boolean",
-						Description: "Case insensitive search (default false)",
+						Description: "Case insensitive search (default from server config, usually false)",
+					},
+					"literal": {
+						Type:        "boolean",
+						Description: "Match the pattern as plain text instead of a regex (default from server config, usually false)",
+					},
+					"format": {
+						Type:        "string",
+						Description: "Output format: text lines or a JSON array of entries (default text)",
+						Enum:        []string{"text", "json"},
+					},
+					"since": {
+						Type:        "string",
+						Description: "Only entries at or after this time: RFC3339 or relative like -5m (optional)",
+					},
+					"until": {
+						Type:        "string",
+						Description: "Only entries at or before this time: RFC3339 or relative like -1m (optional)",
+					},
+					"exclude_untimed": {
+						Type:        "boolean",
+						Description: "Leave out entries with no timestamp of their own, which are otherwise timed by when they were read (default false)",
+					},
+					"level": {
+						Type:        "string",
+						Description: "Minimum log level; entries without a level are always included (optional)",
+						Enum:        logtail.Levels,
+					},
+					"tag": {
+						Type:        "string",
+						Description: "Only entries with this tag, such as the name of a config filter with the tag action (optional)",
+					},
+					"context_before": {
+						Type:        "integer",
+						Description: "Entries from the same stream to show before each match, like grep -B (default 0)",
+					},
+					"context_after": {
+						Type:        "integer",
+						Description: "Entries from the same stream to show after each match, like grep -A (default 0)",
+					},
+					"timeout": {
+						Type:        "string",
+						Description: "How long the search may run, as a duration such as \"10s\" (default mcp.grep_timeout, else 5s)",
 					},
 				},
 				Required: []string{"pattern"},
 			},
-		},
-		{
-			Name:        "logdump_streams",
-			Description: "List all active log streams",
-			InputSchema: InputSchema{
-				Type:       "object",
-				Properties: map[string]Property{},
+			OutputSchema: &OutputSchema{
+				Type:        "array",
+				Description: "With format json, the text content is this array of matching entries; otherwise the pattern, match count and one line per match",
+				Items:       entrySchema,
 			},
-		},
-		{
-			Name:        "logdump_groups",
-			Description: "List all log groups",
-			InputSchema: InputSchema{
-				Type:       "object",
-				Properties: map[string]Property{},
+			Examples: []ToolExample{
+				{Description: "Find timeouts in one stream", Arguments: map[string]interface{}{"pattern": "timeout|deadline exceeded", "source": "api"}},
+				{Description: "Search for text containing regex characters", Arguments: map[string]interface{}{"pattern": "user[42]", "literal": true}},
 			},
 		},
 		{
-			Name:        "logdump_create_group",
-			Description: "Create a new log group",
+			Name:        "logdump_tail",
+			Description: "Return only entries appended since a cursor, plus the cursor to use next time. With timeout_seconds, instead wait for the next new entry matching pattern and source, and return it",
 			InputSchema: InputSchema{
 				Type: "object",
 				Properties: map[string]Property{
-					"name": {
+					"cursor": {
 						Type:        "string",
-						Description: "Group name",
+						Description: "Cursor from a previous call (omit to start from the most recent entries)",
 					},
-					"pattern": {
+					"source": {
 						Type:        "string",
-						Description: "Regex pattern to match logs",
+						Description: "Filter by stream name (optional)",
 					},
-					"color": {
+					"pattern": {
 						Type:        "string",
-						Description: "Color for display",
-						Enum:        []string{"red", "green", "blue", "yellow", "cyan", "magenta"},
+						Description: "Only entries whose content matches this regex (optional)",
 					},
-					"streams": {
-						Type:        "string",
-						Description: "Comma-separated stream names",
+					"limit": {
+						Type:        "integer",
+						Description: "Maximum number of entries to return (default 100)",
+					},
+					"timeout_seconds": {
+						Type:        "number",
+						Description: "Wait up to this long, at most 300, for the first matching entry read after the call (optional; cursor and limit are then ignored)",
 					},
 				},
-				Required: []string{"name", "pattern"},
 			},
-		},
-		{
-			Name:        "logdump_stats",
-			Description: "Get statistics about log streams and buffer",
-			InputSchema: InputSchema{
-				Type:       "object",
-				Properties: map[string]Property{},
+			OutputSchema: &OutputSchema{
+				Type:        "object",
+				Description: "New entries as text lines, plus the cursor for the next call; when waiting, the matching entry or a note that none came",
+				Properties: map[string]Property{
+					"cursor":    {Type: "string", Description: "Pass as cursor on the next call to get only newer entries"},
+					"timed_out": {Type: "boolean", Description: "When waiting, true if no matching entry arrived in time"},
+				},
+			},
+			Examples: []ToolExample{
+				{Description: "First call: recent entries and a cursor", Arguments: map[string]interface{}{"limit": 20}},
+				{Description: "Later calls: only what arrived since", Arguments: map[string]interface{}{"cursor": "1234"}},
+				{Description: "Wait for the next error after starting a test run", Arguments: map[string]interface{}{"pattern": "ERROR", "source": "api", "timeout_seconds": 60}},
 			},
 		},
 		{
-			Name:        "logdump_access_log",
-			Description: "Get access log showing which agents accessed logs",
+			Name:        "logdump_grep_history",
+			Description: "Search the log files on disk from the beginning, not just the recent in-memory buffer; use to find when something first happened",
 			InputSchema: InputSchema{
 				Type: "object",
 				Properties: map[string]Property{
-					"agent": {
+					"pattern": {
 						Type:        "string",
-						Description: "Filter by agent ID/name",
+						Description: "Regex pattern to search for",
 					},
-					"limit": {
-						Type:        "integer",
+					"source": {
+						Type:        "string",
+						Description: "Filter by stream name (optional)",
+					},
+					"limit": {
+						Type:        "integer",
+						Description: "Maximum number of results (default 100)",
+					},
+					"max_bytes": {
+						Type:        "integer",
+						Description: fmt.Sprintf("Stop after scanning this many bytes of log data (default %d MB)", defaultHistoryMaxBytes>>20),
+					},
+				},
+				Required: []string{"pattern"},
+			},
+			OutputSchema: &OutputSchema{
+				Type:        "string",
+				Description: "The pattern, match count, bytes scanned and one \"[date time] [source:line] content\" line per match, oldest file first",
+			},
+			Examples: []ToolExample{
+				{Description: "Find the first occurrences of an error", Arguments: map[string]interface{}{"pattern": "connection refused", "source": "api", "limit": 5}},
+			},
+		},
+		{
+			Name:        "logdump_streams",
+			Description: "List all active log streams",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"format": {
+						Type:        "string",
+						Description: "Output format: text lines or a JSON array of streams (default text)",
+						Enum:        []string{"text", "json"},
+					},
+				},
+			},
+			OutputSchema: &OutputSchema{
+				Type:        "string",
+				Description: "One line per tailed file: stream name, path, lines read and watch mode; with format json, an array of objects with name, path, lines_read and watch_mode",
+			},
+		},
+		{
+			Name:        "logdump_groups",
+			Description: "List all log groups",
+			InputSchema: InputSchema{
+				Type:       "object",
+				Properties: map[string]Property{},
+			},
+			OutputSchema: &OutputSchema{
+				Type:        "string",
+				Description: "One line per group: name, pattern and streams",
+			},
+		},
+		{
+			Name:        "logdump_create_group",
+			Description: "Create a new log group, saved to the config file",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"name": {
+						Type:        "string",
+						Description: "Group name",
+					},
+					"pattern": {
+						Type:        "string",
+						Description: "Regex pattern to match logs",
+					},
+					"color": {
+						Type:        "string",
+						Description: "Color for display",
+						Enum:        []string{"red", "green", "blue", "yellow", "cyan", "magenta"},
+					},
+					"streams": {
+						Type:        "string",
+						Description: "Comma-separated stream names",
+					},
+				},
+				Required: []string{"name", "pattern"},
+			},
+			OutputSchema: &OutputSchema{
+				Type:        "string",
+				Description: "Confirmation naming the created group, with the number of groups",
+			},
+			Examples: []ToolExample{
+				{Description: "Group database errors across two streams", Arguments: map[string]interface{}{"name": "db-errors", "pattern": "ERROR.*(sql|postgres)", "streams": "api,worker"}},
+			},
+		},
+		{
+			Name:        "logdump_delete_group",
+			Description: "Delete a log group, also from the config file",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"name": {
+						Type:        "string",
+						Description: "Group name",
+					},
+				},
+				Required: []string{"name"},
+			},
+			OutputSchema: &OutputSchema{
+				Type:        "string",
+				Description: "Confirmation naming the deleted group, with the number of groups left",
+			},
+		},
+		{
+			Name:        "logdump_remove_stream",
+			Description: "Stop tailing a stream, or a single file of one, and close its files",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"name": {
+						Type:        "string",
+						Description: "Stream name, or the path of one tailed file",
+					},
+					"purge": {
+						Type:        "boolean",
+						Description: "Also drop the stream's buffered entries (default false)",
+					},
+				},
+				Required: []string{"name"},
+			},
+			OutputSchema: &OutputSchema{
+				Type:        "string",
+				Description: "Confirmation naming the stream and how many files were closed",
+			},
+			Examples: []ToolExample{
+				{Description: "Stop a noisy stream and forget its entries", Arguments: map[string]interface{}{"name": "debug", "purge": true}},
+			},
+		},
+		{
+			Name:        "logdump_export",
+			Description: "Write matching buffered entries to a file on the server host, for results too large to return inline",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"path": {
+						Type:        "string",
+						Description: "File to write, relative to the server's export directory or an absolute path inside it",
+					},
+					"source": {
+						Type:        "string",
+						Description: "Filter by stream name (optional)",
+					},
+					"group": {
+						Type:        "string",
+						Description: "Filter by log group name (optional)",
+					},
+					"pattern": {
+						Type:        "string",
+						Description: "Only entries matching this regex (optional)",
+					},
+					"case_insensitive": {
+						Type:        "boolean",
+						Description: "Case insensitive pattern (default from server config, usually false)",
+					},
+					"literal": {
+						Type:        "boolean",
+						Description: "Match the pattern as plain text instead of a regex (default from server config, usually false)",
+					},
+					"since": {
+						Type:        "string",
+						Description: "Only entries at or after this time: RFC3339 or relative like -5m (optional)",
+					},
+					"until": {
+						Type:        "string",
+						Description: "Only entries at or before this time: RFC3339 or relative like -1m (optional)",
+					},
+					"level": {
+						Type:        "string",
+						Description: "Minimum log level; entries without a level are always included (optional)",
+						Enum:        logtail.Levels,
+					},
+					"tag": {
+						Type:        "string",
+						Description: "Only entries with this tag, such as the sending host of a syslog stream (optional)",
+					},
+					"format": {
+						Type:        "string",
+						Description: "File format: text lines or a JSON array of entries (default text)",
+						Enum:        []string{"text", "json"},
+					},
+					"overwrite": {
+						Type:        "boolean",
+						Description: "Replace the file if it exists (default false)",
+					},
+				},
+				Required: []string{"path"},
+			},
+			OutputSchema: &OutputSchema{
+				Type:        "string",
+				Description: "The absolute path written and the number of entries in it",
+			},
+			Examples: []ToolExample{
+				{Description: "Save the last hour of API errors as JSON", Arguments: map[string]interface{}{"path": "api-errors.json", "source": "api", "level": "ERROR", "since": "-1h", "format": "json"}},
+			},
+		},
+		{
+			Name:        "logdump_stats",
+			Description: "Get statistics about log streams and buffer",
+			InputSchema: InputSchema{
+				Type:       "object",
+				Properties: map[string]Property{},
+			},
+			OutputSchema: &OutputSchema{
+				Type:        "string",
+				Description: "Stream, group, buffer and access log counts",
+			},
+		},
+		{
+			Name:        "logdump_config",
+			Description: "Show the server's effective configuration: streams, groups, theme, buffer settings and transport",
+			InputSchema: InputSchema{
+				Type:       "object",
+				Properties: map[string]Property{},
+			},
+			OutputSchema: &OutputSchema{
+				Type:        "object",
+				Description: "The loaded config with auto-discovered streams and runtime groups, plus a runtime section; secrets are redacted",
+			},
+		},
+		{
+			Name:        "logdump_access_log",
+			Description: "Get access log showing which agents accessed logs",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"agent": {
+						Type:        "string",
+						Description: "Filter by agent ID/name",
+					},
+					"limit": {
+						Type:        "integer",
 						Description: "Maximum entries to return (default 50)",
 					},
+					"format": {
+						Type:        "string",
+						Description: "Output format: text lines, CSV or a JSON array of accesses (default text)",
+						Enum:        []string{"text", "csv", "json"},
+					},
 				},
 			},
+			OutputSchema: &OutputSchema{
+				Type:        "string",
+				Description: "One line per access, newest last; with format csv, a header row and one record per access; with format json, an array of objects with timestamp, agent_id, agent_name, action, source and result_count",
+			},
+			Examples: []ToolExample{
+				{Description: "Export one agent's accesses", Arguments: map[string]interface{}{"agent": "debug-001", "format": "csv"}},
+			},
 		},
 	}
+}
+
+// toolNames lists the names of all tools, in tools/list order.
+func toolNames() []string {
+	tools := toolDefinitions()
+	names := make([]string, 0, len(tools))
+	for _, t := range tools {
+		names = append(names, t.Name)
+	}
+	return names
+}
 
+func (s *Server) handleToolsList(req MCPRequest, id interface{}) MCPResponse {
 	return MCPResponse{
 		Result: map[string]interface{}{
-			"tools": tools,
+			"tools": toolDefinitions(),
 		},
 		ID: id,
 	}
@@ -478,87 +1309,84 @@ func (s *Server) handleToolCall(ctx context.Context, req MCPRequest, id interfac
 		args = make(map[string]interface{})
 	}
 
-	agentID := s.currentAgent
-	if agentID == "" {
-		agentID = "unknown"
-	}
+	agent := s.agent(ctx)
 
 	switch toolName {
 	case "logdump_read":
-		resp := s.toolRead(args, id, agentID)
-		count := 0
-		if r, ok := resp.Result.(map[string]interface{}); ok {
-			if e, ok := r["count"].(float64); ok {
-				count = int(e)
-			}
-		}
-		s.logToolCall(toolName, args, count)
+		resp := s.toolRead(args, id, agent)
+		s.logToolCall(ctx, toolName, args, resp.count)
 		return resp
 	case "logdump_grep":
-		resp := s.toolGrep(ctx, args, id, agentID)
-		count := 0
-		if r, ok := resp.Result.(map[string]interface{}); ok {
-			if e, ok := r["count"].(float64); ok {
-				count = int(e)
-			}
-		}
-		s.logToolCall(toolName, args, count)
+		resp := s.toolGrep(ctx, args, id, agent)
+		s.logToolCall(ctx, toolName, args, resp.count)
+		return resp
+	case "logdump_grep_history":
+		resp := s.toolGrepHistory(ctx, args, id, agent)
+		s.logToolCall(ctx, toolName, args, -1)
 		return resp
 	case "logdump_streams":
-		resp := s.toolStreams(id, agentID)
-		count := 0
-		if r, ok := resp.Result.(map[string]interface{}); ok {
-			if e, ok := r["count"].(float64); ok {
-				count = int(e)
-			}
-		}
-		s.logToolCall(toolName, args, count)
+		resp := s.toolStreams(args, id, agent)
+		s.logToolCall(ctx, toolName, args, resp.count)
 		return resp
 	case "logdump_groups":
-		resp := s.toolGroups(id, agentID)
-		count := 0
-		if r, ok := resp.Result.(map[string]interface{}); ok {
-			if e, ok := r["count"].(float64); ok {
-				count = int(e)
-			}
-		}
-		s.logToolCall(toolName, args, count)
+		resp := s.toolGroups(id, agent)
+		s.logToolCall(ctx, toolName, args, resp.count)
 		return resp
 	case "logdump_create_group":
-		resp := s.toolCreateGroup(args, id, agentID)
-		s.logToolCall(toolName, args, -1)
+		resp := s.toolCreateGroup(args, id, agent)
+		s.logToolCall(ctx, toolName, args, -1)
+		return resp
+	case "logdump_delete_group":
+		resp := s.toolDeleteGroup(args, id, agent)
+		s.logToolCall(ctx, toolName, args, -1)
+		return resp
+	case "logdump_export":
+		resp := s.toolExport(args, id, agent)
+		s.logToolCall(ctx, toolName, args, -1)
+		return resp
+	case "logdump_remove_stream":
+		resp := s.toolRemoveStream(args, id, agent)
+		s.logToolCall(ctx, toolName, args, -1)
+		return resp
+	case "logdump_tail":
+		resp := s.toolTail(ctx, args, id, agent)
+		s.logToolCall(ctx, toolName, args, -1)
 		return resp
 	case "logdump_stats":
-		resp := s.toolStats(id, agentID)
-		s.logToolCall(toolName, args, -1)
+		resp := s.toolStats(id, agent)
+		s.logToolCall(ctx, toolName, args, -1)
+		return resp
+	case "logdump_config":
+		resp := s.toolConfig(id, agent)
+		s.logToolCall(ctx, toolName, args, -1)
 		return resp
 	case "logdump_access_log":
-		resp := s.toolAccessLog(args, id, agentID)
-		count := 0
-		if r, ok := resp.Result.(map[string]interface{}); ok {
-			if e, ok := r["count"].(float64); ok {
-				count = int(e)
-			}
-		}
-		s.logToolCall(toolName, args, count)
+		resp := s.toolAccessLog(args, id, agent)
+		s.logToolCall(ctx, toolName, args, resp.count)
 		return resp
 	default:
+		// tools/call itself is a valid method, so an unknown tool name is
+		// an invalid parameter rather than -32601 Method not found
 		return MCPResponse{
 			Error: &MCPError{
-				Code:    -32601,
-				Message: fmt.Sprintf("Method not found: %s", toolName),
+				Code:    -32602,
+				Message: fmt.Sprintf("Unknown tool: %s", toolName),
+				Data: map[string]interface{}{
+					"available_tools": toolNames(),
+				},
 			},
 			ID: id,
 		}
 	}
 }
 
-func (s *Server) logAccess(agentID, action, source, pattern string, resultCount int) {
+func (s *Server) logAccess(agent identity, action, source, pattern string, resultCount int) {
 	s.accessMu.Lock()
 	defer s.accessMu.Unlock()
 
 	access := AgentAccess{
-		AgentID:     agentID,
+		AgentID:     agent.id,
+		AgentName:   agent.name,
 		Action:      action,
 		Source:      source,
 		Pattern:     pattern,
@@ -572,47 +1400,1004 @@ func (s *Server) logAccess(agentID, action, source, pattern string, resultCount
 	}
 }
 
-func (s *Server) toolRead(params map[string]interface{}, id interface{}, agentID string) MCPResponse {
+// RecentAccess returns up to limit of the most recent access log entries,
+// oldest first. A limit of zero or less returns the whole log.
+func (s *Server) RecentAccess(limit int) []AgentAccess {
+	s.accessMu.RLock()
+	defer s.accessMu.RUnlock()
+
+	entries := s.accessLog
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	result := make([]AgentAccess, len(entries))
+	copy(result, entries)
+	return result
+}
+
+// entryFilter builds the predicate shared by logdump_read and
+// logdump_export from the source, group, tag, since, until, level,
+// exclude_untimed and, if present, pattern arguments.
+func (s *Server) entryFilter(params map[string]interface{}) (func(logtail.LogEntry) bool, error) {
 	source, _ := params["source"].(string)
 	group, _ := params["group"].(string)
-	limit := 100
-	if l, ok := params["limit"].(float64); ok {
-		limit = int(l)
-	}
+	tag, _ := params["tag"].(string)
+	excludeUntimed, _ := params["exclude_untimed"].(bool)
 
-	entries := s.manager.GetEntries(source, limit)
+	since, until, err := timeRangeParams(params, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	minLevel, err := levelParam(params)
+	if err != nil {
+		return nil, err
+	}
 
-	var filtered []logtail.LogEntry
+	var groupRe *regexp.Regexp
 	if group != "" {
 		s.groupsMu.RLock()
 		g, ok := s.logGroups[group]
 		s.groupsMu.RUnlock()
 		if ok && g.Pattern != "" {
-			re := regexp.MustCompile("(?i)" + g.Pattern)
-			for _, e := range entries {
-				if re.MatchString(e.Content) {
-					filtered = append(filtered, e)
-				}
+			if groupRe, err = g.compile(); err != nil {
+				return nil, err
 			}
-			entries = filtered
 		}
 	}
 
-	var lines []string
-	for _, entry := range entries {
-		lines = append(lines, fmt.Sprintf("[%s] [%s] %s",
-			entry.Timestamp.Format("15:04:05"),
-			entry.Source,
-			entry.Content))
-	}
+	var patternRe *regexp.Regexp
+	if pattern, _ := params["pattern"].(string); pattern != "" {
+		patternRe, err = regexp.Compile(s.grepPattern(params))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+
+	return func(e logtail.LogEntry) bool {
+		return (source == "" || e.Source == source) &&
+			(tag == "" || slices.Contains(e.Tags, tag)) &&
+			(e.Timed || !excludeUntimed) &&
+			logtail.InRange(e.Timestamp, since, until) &&
+			logtail.LevelAtLeast(e.Level, minLevel) &&
+			(groupRe == nil || groupRe.MatchString(e.Content)) &&
+			(patternRe == nil || patternRe.MatchString(e.Content))
+	}, nil
+}
+
+// grepPattern returns the pattern argument as a regular expression,
+// applying the case_insensitive and literal arguments or their config
+// defaults.
+func (s *Server) grepPattern(params map[string]interface{}) string {
+	pattern, _ := params["pattern"].(string)
+	caseInsensitive := s.currentConfig().MCP.GrepCaseInsensitive
+	if ci, ok := params["case_insensitive"].(bool); ok {
+		caseInsensitive = ci
+	}
+	literal := s.currentConfig().MCP.GrepLiteral
+	if l, ok := params["literal"].(bool); ok {
+		literal = l
+	}
+
+	flags := ""
+	if caseInsensitive {
+		flags = "(?i)"
+	}
+
+	expr := pattern
+	if literal {
+		expr = regexp.QuoteMeta(pattern)
+	}
+
+	return flags + expr
+}
+
+func (s *Server) toolRead(params map[string]interface{}, id interface{}, agent identity) MCPResponse {
+	source, _ := params["source"].(string)
+	withFields, _ := params["fields"].(bool)
+	format, _ := params["format"].(string)
+	limit := 100
+	if l, ok := params["limit"].(float64); ok {
+		limit = int(l)
+	}
+
+	keep, err := s.entryFilter(params)
+	if err != nil {
+		return MCPResponse{
+			Error: &MCPError{
+				Code:    -32602,
+				Message: err.Error(),
+			},
+			ID: id,
+		}
+	}
+
+	cursor, useCursor, err := cursorParam(params, "cursor")
+	if err == nil && useCursor && params["before"] != nil {
+		err = fmt.Errorf("cursor and before cannot be combined")
+	}
+	var before uint64
+	var useBefore bool
+	if err == nil {
+		before, useBefore, err = cursorParam(params, "before")
+	}
+	if err != nil {
+		return MCPResponse{
+			Error: &MCPError{
+				Code:    -32602,
+				Message: err.Error(),
+			},
+			ID: id,
+		}
+	}
+
+	var entries []logtail.LogEntry
+	var next uint64
+	var truncated, more bool
+	if useCursor {
+		entries, next, truncated = s.manager.GetEntriesSinceFunc(cursor, limit, keep)
+	} else {
+		next = s.manager.Cursor()
+		entries, more = s.manager.GetEntriesBeforeFunc(before, limit, keep)
+		if len(entries) > 0 {
+			next = max(next, entries[len(entries)-1].Seq)
+		}
+	}
+
+	var lines []string
+	for _, entry := range entries {
+		line := fmt.Sprintf("[%s] [%s] %s%s",
+			entry.Timestamp.Format("15:04:05"),
+			entry.Source,
+			entry.Content,
+			repeatSuffix(entry))
+		if withFields && len(entry.Fields) > 0 {
+			line += " " + formatFields(entry.Fields)
+		}
+		lines = append(lines, line)
+	}
+
+	text := strings.Join(lines, "\n")
+	if len(entries) == 0 {
+		text = "No log entries found"
+	}
+	if useCursor {
+		header := fmt.Sprintf("Cursor: %d\n", next)
+		if truncated {
+			header += "Truncated: entries after the given cursor were evicted from the buffer before being read\n"
+		}
+		text = header + "\n" + text
+	} else if useBefore || more {
+		header := "No older entries\n"
+		if more {
+			header = fmt.Sprintf("Older entries remain; pass before=%d for the previous page\n", entries[0].Seq)
+		}
+		text = header + "\n" + text
+	}
+	if format == "json" {
+		text = entriesJSON(entries, withFields)
+	}
+
+	s.logAccess(agent, "read", source, "", len(entries))
+
+	result := map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": text,
+			},
+		},
+		"cursor": strconv.FormatUint(next, 10),
+	}
+	if useCursor {
+		result["truncated"] = truncated
+	} else {
+		result["has_more"] = more
+		if more {
+			result["before"] = strconv.FormatUint(entries[0].Seq, 10)
+		}
+	}
+
+	return MCPResponse{
+		Result: result,
+		ID:     id,
+		count:  len(entries),
+	}
+}
+
+// cursorParam reads a cursor argument such as cursor or before, given as a
+// string or a number. ok is false if there is none.
+func cursorParam(params map[string]interface{}, name string) (cursor uint64, ok bool, err error) {
+	switch v := params[name].(type) {
+	case string:
+		if v == "" {
+			return 0, false, nil
+		}
+		cursor, err = strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("Invalid %s: %s", name, v)
+		}
+		return cursor, true, nil
+	case float64:
+		if v < 0 || v != float64(uint64(v)) {
+			return 0, false, fmt.Errorf("Invalid %s: %v", name, v)
+		}
+		return uint64(v), true, nil
+	}
+	return 0, false, nil
+}
+
+// defaultHistoryMaxBytes bounds logdump_grep_history scans, so one call
+// cannot read through gigabytes of logs.
+const defaultHistoryMaxBytes = 256 << 20
+
+func (s *Server) toolGrepHistory(ctx context.Context, params map[string]interface{}, id interface{}, agent identity) MCPResponse {
+	pattern, _ := params["pattern"].(string)
+	source, _ := params["source"].(string)
+	limit := 100
+	if l, ok := params["limit"].(float64); ok {
+		limit = int(l)
+	}
+	maxBytes := int64(defaultHistoryMaxBytes)
+	if b, ok := params["max_bytes"].(float64); ok && b > 0 {
+		maxBytes = int64(b)
+	}
+
+	matches, err := s.manager.SearchFiles(ctx, pattern, source, limit, maxBytes)
+	if err != nil {
+		return MCPResponse{
+			Error: &MCPError{
+				Code:    -32603,
+				Message: err.Error(),
+			},
+			ID: id,
+		}
+	}
+
+	var lines []string
+	for entry := range matches.Entries {
+		lines = append(lines, fmt.Sprintf("[%s] [%s:%d] %s",
+			entry.Timestamp.Format("2006-01-02 15:04:05"),
+			entry.Source,
+			entry.LineNumber,
+			entry.Content))
+	}
+
+	scanned := fmt.Sprintf("Scanned: %d bytes", matches.Scanned())
+	if matches.Truncated() {
+		scanned += fmt.Sprintf(" (stopped at max_bytes %d; narrow the source or raise max_bytes to search further)", maxBytes)
+	}
+	text := fmt.Sprintf("Pattern: %s\nMatches: %d\n%s\n\n%s", pattern, len(lines), scanned, strings.Join(lines, "\n"))
+	if len(lines) == 0 {
+		text = fmt.Sprintf("Pattern: %s\nNo matches found\n%s", pattern, scanned)
+	}
+
+	s.logAccess(agent, "grep_history", source, pattern, len(lines))
+
+	return MCPResponse{
+		Result: map[string]interface{}{
+			"content": []map[string]interface{}{
+				{
+					"type": "text",
+					"text": text,
+				},
+			},
+		},
+		ID: id,
+	}
+}
+
+// grepTimeout reads the optional timeout argument, defaulting to the
+// configured grep timeout.
+func (s *Server) grepTimeout(params map[string]interface{}) (time.Duration, error) {
+	v, _ := params["timeout"].(string)
+	if v == "" {
+		return s.currentConfig().MCP.SearchTimeout(), nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q, expected a duration such as 10s", v)
+	}
+	return d, nil
+}
+
+func grepTimeoutResponse(id interface{}, timeout time.Duration) MCPResponse {
+	return MCPResponse{
+		Error: &MCPError{
+			Code:    -32603,
+			Message: fmt.Sprintf("search timed out after %s; narrow it with source, since or a more specific pattern, or pass a longer timeout", timeout),
+		},
+		ID: id,
+	}
+}
+
+// timeRangeParams reads the optional since and until arguments, relative
+// to now. A missing argument is returned as the zero time.
+func timeRangeParams(params map[string]interface{}, now time.Time) (since, until time.Time, err error) {
+	if v, _ := params["since"].(string); v != "" {
+		if since, err = parseTimeParam(v, now); err != nil {
+			return since, until, fmt.Errorf("invalid since: %w", err)
+		}
+	}
+	if v, _ := params["until"].(string); v != "" {
+		if until, err = parseTimeParam(v, now); err != nil {
+			return since, until, fmt.Errorf("invalid until: %w", err)
+		}
+	}
+	return since, until, nil
+}
+
+// levelParam reads the optional minimum level argument.
+func levelParam(params map[string]interface{}) (string, error) {
+	v, _ := params["level"].(string)
+	if v == "" {
+		return "", nil
+	}
+	level := logtail.NormalizeLevel(v)
+	if logtail.LevelRank(level) < 0 {
+		return "", fmt.Errorf("invalid level %q, expected one of %s", v, strings.Join(logtail.Levels, ", "))
+	}
+	return level, nil
+}
+
+// parseTimeParam accepts an RFC3339 time or a duration relative to now,
+// such as "-5m". A duration without a sign also counts back from now.
+func parseTimeParam(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither RFC3339 nor a duration like -5m", value)
+	}
+	if d > 0 {
+		d = -d
+	}
+	return now.Add(d), nil
+}
+
+// entryJSON is the shape of a log entry in JSON tool output.
+type entryJSON struct {
+	Timestamp   string            `json:"timestamp"`
+	Source      string            `json:"source"`
+	Content     string            `json:"content"`
+	LineNumber  int               `json:"line_number"`
+	Tags        []string          `json:"tags"`
+	Level       string            `json:"level,omitempty"`
+	Fields      map[string]string `json:"fields,omitempty"`
+	Context     bool              `json:"context,omitempty"`
+	Seq         uint64            `json:"seq"`
+	RepeatCount int               `json:"repeat_count,omitempty"`
+	Truncated   bool              `json:"truncated,omitempty"`
+}
+
+func newEntryJSON(e logtail.LogEntry, withFields bool) entryJSON {
+	item := entryJSON{
+		Timestamp:   e.Timestamp.Format(time.RFC3339),
+		Source:      e.Source,
+		Content:     e.Content,
+		LineNumber:  e.LineNumber,
+		Tags:        e.Tags,
+		Level:       e.Level,
+		Seq:         e.Seq,
+		RepeatCount: e.RepeatCount,
+		Truncated:   e.Truncated,
+	}
+	if item.Tags == nil {
+		item.Tags = []string{}
+	}
+	if withFields {
+		item.Fields = e.Fields
+	}
+	return item
+}
+
+// entriesJSON renders entries as a JSON array. An empty result is "[]".
+func entriesJSON(entries []logtail.LogEntry, withFields bool) string {
+	out := make([]entryJSON, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, newEntryJSON(e, withFields))
+	}
+	return toJSON(out)
+}
+
+// toJSON renders a slice of tool results as a JSON array, empty rather than
+// null when there are none.
+func toJSON[T any](items []T) string {
+	if items == nil {
+		items = []T{}
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// searchResultsJSON renders grep results with context as a JSON array of
+// groups, each an array of entries with context lines flagged.
+func searchResultsJSON(results []logtail.SearchResult) string {
+	out := make([][]entryJSON, 0, len(results))
+	for _, r := range results {
+		group := make([]entryJSON, 0, len(r.Entries))
+		for i, e := range r.Entries {
+			item := newEntryJSON(e, false)
+			item.Context = !r.Matched[i]
+			group = append(group, item)
+		}
+		out = append(out, group)
+	}
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// repeatSuffix marks an entry that stands for several identical lines.
+func repeatSuffix(entry logtail.LogEntry) string {
+	if entry.RepeatCount == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (x%d)", entry.RepeatCount)
+}
+
+// formatFields renders structured fields as {key=value ...} in key order.
+func formatFields(fields map[string]string) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%q", k, fields[k]))
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
+
+func (s *Server) toolGrep(ctx context.Context, params map[string]interface{}, id interface{}, agent identity) MCPResponse {
+	pattern, _ := params["pattern"].(string)
+	source, _ := params["source"].(string)
+	group, _ := params["group"].(string)
+	limit := 100
+	if l, ok := params["limit"].(float64); ok {
+		limit = int(l)
+	}
+	excludeUntimed, _ := params["exclude_untimed"].(bool)
+	tag, _ := params["tag"].(string)
+	var minLevel string
+	var timeout time.Duration
+	since, until, err := timeRangeParams(params, time.Now())
+	if err == nil {
+		minLevel, err = levelParam(params)
+	}
+	if err == nil {
+		timeout, err = s.grepTimeout(params)
+	}
+	if err != nil {
+		return MCPResponse{
+			Error: &MCPError{
+				Code:    -32602,
+				Message: err.Error(),
+			},
+			ID: id,
+		}
+	}
+
+	fullPattern := s.grepPattern(params)
+
+	// Also stops the search when the limit is reached first
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var searchSource string
+	if group != "" {
+		s.groupsMu.RLock()
+		g := s.logGroups[group]
+		s.groupsMu.RUnlock()
+		searchSource = strings.Join(g.Streams, ",")
+	} else {
+		searchSource = source
+	}
+
+	before, _ := params["context_before"].(float64)
+	after, _ := params["context_after"].(float64)
+	if before > 0 || after > 0 {
+		results, count, err := s.manager.SearchContext(ctx, logtail.ContextSearch{
+			Pattern: fullPattern,
+			Source:  searchSource,
+			Before:  int(before),
+			After:   int(after),
+			Limit:   limit,
+			Filter: func(entry logtail.LogEntry) bool {
+				return logtail.InRange(entry.Timestamp, since, until) &&
+					logtail.LevelAtLeast(entry.Level, minLevel) &&
+					(!excludeUntimed || entry.Timed) &&
+					(tag == "" || slices.Contains(entry.Tags, tag))
+			},
+		})
+		if errors.Is(err, context.DeadlineExceeded) {
+			return grepTimeoutResponse(id, timeout)
+		}
+		if err != nil {
+			return MCPResponse{
+				Error: &MCPError{
+					Code:    -32603,
+					Message: err.Error(),
+				},
+				ID: id,
+			}
+		}
+
+		text := fmt.Sprintf("Pattern: %s\nNo matches found", pattern)
+		if format, _ := params["format"].(string); format == "json" {
+			text = searchResultsJSON(results)
+		} else if count > 0 {
+			text = fmt.Sprintf("Pattern: %s\nMatches: %d\n\n%s", pattern, count, formatSearchResults(results))
+		}
+
+		s.logAccess(agent, "grep", searchSource, pattern, count)
+
+		return MCPResponse{
+			Result: map[string]interface{}{
+				"content": []map[string]interface{}{
+					{
+						"type": "text",
+						"text": text,
+					},
+				},
+			},
+			ID:    id,
+			count: count,
+		}
+	}
+
+	// The time, level and tag filters are applied below, so Search can only
+	// stop at the limit itself when none of them is set
+	searchLimit := limit
+	if !since.IsZero() || !until.IsZero() || minLevel != "" || excludeUntimed || tag != "" {
+		searchLimit = 0
+	}
+
+	results, err := s.manager.Search(ctx, fullPattern, searchSource, searchLimit)
+	if err != nil {
+		return MCPResponse{
+			Error: &MCPError{
+				Code:    -32603,
+				Message: err.Error(),
+			},
+			ID: id,
+		}
+	}
+
+	var lines []string
+	var matched []logtail.LogEntry
+	count := 0
+	for entry := range results {
+		if count >= limit {
+			break
+		}
+		if !logtail.InRange(entry.Timestamp, since, until) || !logtail.LevelAtLeast(entry.Level, minLevel) {
+			continue
+		}
+		if excludeUntimed && !entry.Timed {
+			continue
+		}
+		if tag != "" && !slices.Contains(entry.Tags, tag) {
+			continue
+		}
+
+		// Search only returns entries matching the pattern
+		lines = append(lines, fmt.Sprintf("[%s] [%s] %s%s",
+			entry.Timestamp.Format("15:04:05"),
+			entry.Source,
+			entry.Content,
+			repeatSuffix(entry)))
+		matched = append(matched, entry)
+		count++
+	}
+	if count < limit && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return grepTimeoutResponse(id, timeout)
+	}
+
+	text := fmt.Sprintf("Pattern: %s\nMatches: %d\n\n%s", pattern, count, strings.Join(lines, "\n"))
+	if count == 0 {
+		text = fmt.Sprintf("Pattern: %s\nNo matches found", pattern)
+	}
+	if format, _ := params["format"].(string); format == "json" {
+		text = entriesJSON(matched, false)
+	}
+
+	s.logAccess(agent, "grep", searchSource, pattern, count)
+
+	return MCPResponse{
+		Result: map[string]interface{}{
+			"content": []map[string]interface{}{
+				{
+					"type": "text",
+					"text": text,
+				},
+			},
+		},
+		ID:    id,
+		count: count,
+	}
+}
+
+// formatSearchResults renders grep results with context in grep's style:
+// matches marked with ">", context lines indented and groups separated by
+// "--".
+func formatSearchResults(results []logtail.SearchResult) string {
+	var b strings.Builder
+	for i, r := range results {
+		if i > 0 {
+			b.WriteString("--\n")
+		}
+		for j, entry := range r.Entries {
+			marker := "  "
+			if r.Matched[j] {
+				marker = "> "
+			}
+			fmt.Fprintf(&b, "%s[%s] [%s] %s%s\n",
+				marker,
+				entry.Timestamp.Format("15:04:05"),
+				entry.Source,
+				entry.Content,
+				repeatSuffix(entry))
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// maxTailWait bounds how long logdump_tail waits for a matching entry.
+const maxTailWait = 5 * time.Minute
+
+func (s *Server) toolTail(ctx context.Context, params map[string]interface{}, id interface{}, agent identity) MCPResponse {
+	source, _ := params["source"].(string)
+	pattern, _ := params["pattern"].(string)
+	limit := 100
+	if l, ok := params["limit"].(float64); ok {
+		limit = int(l)
+	}
+	cursor, useCursor, err := cursorParam(params, "cursor")
+	if err != nil {
+		return MCPResponse{
+			Error: &MCPError{
+				Code:    -32602,
+				Message: err.Error(),
+			},
+			ID: id,
+		}
+	}
+	var re *regexp.Regexp
+	if pattern != "" {
+		if re, err = regexp.Compile(pattern); err != nil {
+			return MCPResponse{
+				Error: &MCPError{
+					Code:    -32602,
+					Message: fmt.Sprintf("Invalid regex pattern: %v", err),
+				},
+				ID: id,
+			}
+		}
+	}
+	keep := func(e logtail.LogEntry) bool {
+		return (source == "" || e.Source == source) && (re == nil || re.MatchString(e.Content))
+	}
+
+	if seconds, ok := params["timeout_seconds"].(float64); ok {
+		timeout := time.Duration(seconds * float64(time.Second))
+		if timeout <= 0 || timeout > maxTailWait {
+			return MCPResponse{
+				Error: &MCPError{
+					Code:    -32602,
+					Message: fmt.Sprintf("timeout_seconds must be more than 0 and at most %d", int(maxTailWait.Seconds())),
+				},
+				ID: id,
+			}
+		}
+		return s.waitForEntry(ctx, keep, timeout, source, pattern, id, agent)
+	}
+
+	var entries []logtail.LogEntry
+	var next uint64
+	var truncated bool
+	if !useCursor {
+		// No cursor yet: show the latest entries and where they end
+		next = s.manager.Cursor()
+		if re == nil {
+			entries = s.manager.GetEntries(source, limit)
+		} else {
+			entries = s.manager.GetEntriesFunc(limit, keep)
+		}
+		if len(entries) > 0 {
+			next = entries[len(entries)-1].Seq
+		}
+	} else {
+		entries, next, truncated = s.manager.GetEntriesSinceFunc(cursor, limit, keep)
+	}
+
+	var lines []string
+	for _, entry := range entries {
+		lines = append(lines, fmt.Sprintf("[%s] [%s] %s%s",
+			entry.Timestamp.Format("15:04:05"),
+			entry.Source,
+			entry.Content,
+			repeatSuffix(entry)))
+	}
+
+	text := fmt.Sprintf("Cursor: %d\nNew entries: %d\n\n%s", next, len(entries), strings.Join(lines, "\n"))
+	if len(entries) == 0 {
+		text = fmt.Sprintf("Cursor: %d\nNo new entries", next)
+	}
+	if truncated {
+		text = "Truncated: entries after the given cursor were evicted from the buffer before being read\n" + text
+	}
+
+	s.logAccess(agent, "tail", source, pattern, len(entries))
+
+	return MCPResponse{
+		Result: map[string]interface{}{
+			"content": []map[string]interface{}{
+				{
+					"type": "text",
+					"text": text,
+				},
+			},
+			"cursor":    strconv.FormatUint(next, 10),
+			"truncated": truncated,
+		},
+		ID: id,
+	}
+}
+
+// waitForEntry returns the first entry read after the call that keep
+// accepts, or reports that none came within timeout. Each call has its own
+// subscription, so concurrent waits do not take entries from each other.
+func (s *Server) waitForEntry(ctx context.Context, keep func(logtail.LogEntry) bool, timeout time.Duration, source, pattern string, id interface{}, agent identity) MCPResponse {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for entry := range s.manager.Subscribe(waitCtx) {
+		if entry.IsHeartbeat() || !keep(entry) {
+			continue
+		}
+		s.logAccess(agent, "tail", source, pattern, 1)
+		return MCPResponse{
+			Result: map[string]interface{}{
+				"content": []map[string]interface{}{
+					{
+						"type": "text",
+						"text": fmt.Sprintf("[%s] [%s] %s%s",
+							entry.Timestamp.Format("15:04:05"),
+							entry.Source,
+							entry.Content,
+							repeatSuffix(entry)),
+					},
+				},
+				"timed_out": false,
+			},
+			ID: id,
+		}
+	}
+
+	// The subscription ends when the wait times out, the client goes away
+	// or the server stops
+	if ctx.Err() != nil || !errors.Is(waitCtx.Err(), context.DeadlineExceeded) {
+		return MCPResponse{
+			Error: &MCPError{
+				Code:    -32603,
+				Message: "wait cancelled",
+			},
+			ID: id,
+		}
+	}
+	s.logAccess(agent, "tail", source, pattern, 0)
+	return MCPResponse{
+		Result: map[string]interface{}{
+			"content": []map[string]interface{}{
+				{
+					"type": "text",
+					"text": fmt.Sprintf("No matching entry within %s", timeout),
+				},
+			},
+			"timed_out": true,
+		},
+		ID: id,
+	}
+}
+
+func (s *Server) toolStreams(params map[string]interface{}, id interface{}, agent identity) MCPResponse {
+	streams := s.manager.GetStreams()
+	format, _ := params["format"].(string)
+
+	var lines []string
+	var items []streamJSON
+	for _, path := range slices.Sorted(maps.Keys(streams)) {
+		stream := streams[path]
+		item := streamJSON{
+			Name:      stream.Config.Name,
+			Path:      path,
+			LinesRead: stream.LinesRead(),
+			WatchMode: stream.WatchMode,
+			Dropped:   stream.Dropped(),
+		}
+		item.Status, _ = stream.Health()
+
+		line := fmt.Sprintf("- %s: %s (%d lines read, %s)",
+			stream.Config.Name, path, item.LinesRead, stream.WatchMode)
+		if stream.WatchMode == logtail.WatchCommand {
+			pid, restarts := stream.Process()
+			item.PID, item.Restarts = pid, restarts
+			line += fmt.Sprintf(" [pid %d, %d restarts]", pid, restarts)
+		}
+		if item.Status != "" {
+			line += fmt.Sprintf(" [%s]", item.Status)
+		}
+		if item.Dropped > 0 {
+			line += fmt.Sprintf(" [%d dropped]", item.Dropped)
+		}
+		lines = append(lines, line)
+		items = append(items, item)
+	}
+
+	text := fmt.Sprintf("Active Streams: %d\n\n%s", len(streams), strings.Join(lines, "\n"))
+	if len(streams) == 0 {
+		text = "No active streams"
+	}
+	if format == "json" {
+		text = toJSON(items)
+	}
+
+	s.logAccess(agent, "list_streams", "", "", len(streams))
+
+	return MCPResponse{
+		Result: map[string]interface{}{
+			"content": []map[string]interface{}{
+				{
+					"type": "text",
+					"text": text,
+				},
+			},
+		},
+		ID:    id,
+		count: len(streams),
+	}
+}
+
+// streamJSON is a tailed file as listed by logdump_streams with format json.
+type streamJSON struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	LinesRead int64  `json:"lines_read"`
+	WatchMode string `json:"watch_mode"`
+	PID       int    `json:"pid,omitempty"`
+	Restarts  int64  `json:"restarts,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Dropped   int64  `json:"dropped,omitempty"`
+}
+
+func (s *Server) toolGroups(id interface{}, agent identity) MCPResponse {
+	s.groupsMu.RLock()
+	defer s.groupsMu.RUnlock()
+
+	var lines []string
+	for _, group := range s.logGroups {
+		lines = append(lines, fmt.Sprintf("- %s: pattern=%q streams=%v",
+			group.Name, group.Pattern, group.Streams))
+	}
+
+	text := fmt.Sprintf("Log Groups: %d\n\n%s", len(s.logGroups), strings.Join(lines, "\n"))
+	if len(s.logGroups) == 0 {
+		text = "No log groups defined"
+	}
+
+	s.logAccess(agent, "list_groups", "", "", len(s.logGroups))
+
+	return MCPResponse{
+		Result: map[string]interface{}{
+			"content": []map[string]interface{}{
+				{
+					"type": "text",
+					"text": text,
+				},
+			},
+		},
+		ID:    id,
+		count: len(s.logGroups),
+	}
+}
+
+func (s *Server) toolCreateGroup(params map[string]interface{}, id interface{}, agent identity) MCPResponse {
+	name, _ := params["name"].(string)
+	pattern, _ := params["pattern"].(string)
+	color, _ := params["color"].(string)
+	streamsStr, _ := params["streams"].(string)
+
+	var streams []string
+	if streamsStr != "" {
+		parts := strings.Split(streamsStr, ",")
+		for _, p := range parts {
+			streams = append(streams, strings.TrimSpace(p))
+		}
+	}
+
+	if color == "" {
+		color = "cyan"
+	}
+
+	group := LogGroup{
+		Name:      name,
+		Pattern:   pattern,
+		Color:     color,
+		Streams:   streams,
+		CreatedAt: time.Now(),
+	}
+	if _, err := group.compile(); err != nil {
+		return MCPResponse{
+			Error: &MCPError{
+				Code:    -32602,
+				Message: err.Error(),
+			},
+			ID: id,
+		}
+	}
+
+	s.groupsMu.Lock()
+	s.logGroups[name] = group
+	count := len(s.logGroups)
+	err := s.saveGroups()
+	s.groupsMu.Unlock()
+
+	s.logAccess(agent, "create_group", name, pattern, 1)
+
+	text := fmt.Sprintf("Created group '%s' with pattern '%s' (%d groups)", name, pattern, count)
+	if err != nil {
+		text += fmt.Sprintf("; not saved to the config file: %v", err)
+	}
+
+	return MCPResponse{
+		Result: map[string]interface{}{
+			"content": []map[string]interface{}{
+				{
+					"type": "text",
+					"text": text,
+				},
+			},
+		},
+		ID: id,
+	}
+}
+
+func (s *Server) toolDeleteGroup(params map[string]interface{}, id interface{}, agent identity) MCPResponse {
+	name, _ := params["name"].(string)
+
+	s.groupsMu.Lock()
+	if _, ok := s.logGroups[name]; !ok {
+		s.groupsMu.Unlock()
+		return MCPResponse{
+			Error: &MCPError{
+				Code:    -32602,
+				Message: fmt.Sprintf("Unknown group: %s", name),
+			},
+			ID: id,
+		}
+	}
+	delete(s.logGroups, name)
+	count := len(s.logGroups)
+	err := s.saveGroups()
+	s.groupsMu.Unlock()
+
+	s.logAccess(agent, "delete_group", name, "", 1)
 
-	text := strings.Join(lines, "\n")
-	if len(entries) == 0 {
-		text = "No log entries found"
+	text := fmt.Sprintf("Deleted group '%s' (%d groups left)", name, count)
+	if err != nil {
+		text += fmt.Sprintf("; not saved to the config file: %v", err)
 	}
 
-	s.logAccess(agentID, "read", source, "", len(entries))
-
 	return MCPResponse{
 		Result: map[string]interface{}{
 			"content": []map[string]interface{}{
@@ -626,133 +2411,215 @@ func (s *Server) toolRead(params map[string]interface{}, id interface{}, agentID
 	}
 }
 
-func (s *Server) toolGrep(ctx context.Context, params map[string]interface{}, id interface{}, agentID string) MCPResponse {
-	pattern, _ := params["pattern"].(string)
-	source, _ := params["source"].(string)
-	group, _ := params["group"].(string)
-	limit := 100
-	if l, ok := params["limit"].(float64); ok {
-		limit = int(l)
-	}
-	caseInsensitive := false
-	if ci, ok := params["case_insensitive"].(bool); ok {
-		caseInsensitive = ci
+// saveGroups writes the groups to the config file they were loaded from,
+// or to the global config if logdump started without one, so groups
+// created by agents survive a restart. groupsMu must be held.
+func (s *Server) saveGroups() error {
+	groups := make([]config.GroupConfig, 0, len(s.logGroups))
+	for _, g := range s.logGroups {
+		groups = append(groups, config.GroupConfig{
+			Name:    g.Name,
+			Pattern: g.Pattern,
+			Color:   g.Color,
+			Streams: g.Streams,
+		})
 	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
 
-	flags := ""
-	if caseInsensitive {
-		flags = "(?i)"
+	path := s.currentConfig().Path
+	if path == "" {
+		path = config.GlobalConfigPath()
 	}
+	return config.SaveGroups(path, groups)
+}
 
-	fullPattern := flags + pattern
-
-	var searchSource string
-	if group != "" {
-		s.groupsMu.RLock()
-		g := s.logGroups[group]
-		s.groupsMu.RUnlock()
-		searchSource = strings.Join(g.Streams, ",")
-	} else {
-		searchSource = source
-	}
+func (s *Server) toolExport(params map[string]interface{}, id interface{}, agent identity) MCPResponse {
+	format, _ := params["format"].(string)
+	overwrite, _ := params["overwrite"].(bool)
+	source, _ := params["source"].(string)
 
-	results, err := s.manager.Search(ctx, fullPattern, searchSource)
+	keep, err := s.entryFilter(params)
 	if err != nil {
 		return MCPResponse{
 			Error: &MCPError{
-				Code:    -32603,
+				Code:    -32602,
 				Message: err.Error(),
 			},
 			ID: id,
 		}
 	}
 
-	var lines []string
-	count := 0
-	for entry := range results {
-		if count >= limit {
-			break
-		}
-
-		re, err := regexp.Compile(fullPattern)
-		if err != nil {
-			continue
-		}
-
-		if re.MatchString(entry.Content) {
-			lines = append(lines, fmt.Sprintf("[%s] [%s] %s",
-				entry.Timestamp.Format("15:04:05"),
-				entry.Source,
-				entry.Content))
-			count++
+	path, err := s.exportPath(params)
+	if err != nil {
+		return MCPResponse{
+			Error: &MCPError{
+				Code:    -32602,
+				Message: err.Error(),
+			},
+			ID: id,
 		}
 	}
 
-	text := fmt.Sprintf("Pattern: %s\nMatches: %d\n\n%s", pattern, count, strings.Join(lines, "\n"))
-	if count == 0 {
-		text = fmt.Sprintf("Pattern: %s\nNo matches found", pattern)
+	count, err := writeExport(path, s.manager.GetEntriesFunc(0, keep), format, overwrite)
+	if err != nil {
+		return MCPResponse{
+			Error: &MCPError{
+				Code:    -32603,
+				Message: err.Error(),
+			},
+			ID: id,
+		}
 	}
 
-	s.logAccess(agentID, "grep", searchSource, pattern, count)
+	s.logAccess(agent, "export", source, path, count)
 
 	return MCPResponse{
 		Result: map[string]interface{}{
 			"content": []map[string]interface{}{
 				{
 					"type": "text",
-					"text": text,
+					"text": fmt.Sprintf("Exported %d entries to %s", count, path),
 				},
 			},
+			"path":  path,
+			"count": count,
 		},
 		ID: id,
 	}
 }
 
-func (s *Server) toolStreams(id interface{}, agentID string) MCPResponse {
-	streams := s.manager.GetStreams()
-
-	var lines []string
-	for path, stream := range streams {
-		lines = append(lines, fmt.Sprintf("- %s: %s (%d lines read)",
-			stream.Config.Name, path, stream.LineNumber))
+// exportPath resolves the path argument against the export directory and
+// refuses anything that would land outside it, including via symlinks.
+func (s *Server) exportPath(params map[string]interface{}) (string, error) {
+	name, _ := params["path"].(string)
+	if name == "" {
+		return "", errors.New("path is required")
 	}
 
-	text := fmt.Sprintf("Active Streams: %d\n\n%s", len(streams), strings.Join(lines, "\n"))
-	if len(streams) == 0 {
-		text = "No active streams"
+	dir, err := filepath.Abs(s.currentConfig().MCP.ExportDirectory())
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("export directory %s: %w", dir, err)
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
 	}
 
-	s.logAccess(agentID, "list_streams", "", "", len(streams))
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	path = filepath.Clean(path)
+	if !within(dir, path) {
+		return "", fmt.Errorf("path %s is outside the export directory %s", name, dir)
+	}
 
-	return MCPResponse{
-		Result: map[string]interface{}{
-			"content": []map[string]interface{}{
-				{
-					"type": "text",
-					"text": text,
-				},
-			},
-		},
-		ID: id,
+	// Resolve the deepest existing directory before creating any, so a
+	// symlink cannot lead the writes elsewhere
+	parent := filepath.Dir(path)
+	existing := parent
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+	realParent, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", err
+	}
+	if !within(realDir, realParent) {
+		return "", fmt.Errorf("path %s is outside the export directory %s", name, dir)
 	}
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", err
+	}
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return "", fmt.Errorf("path %s is a symlink", name)
+	}
+
+	return path, nil
 }
 
-func (s *Server) toolGroups(id interface{}, agentID string) MCPResponse {
-	s.groupsMu.RLock()
-	defer s.groupsMu.RUnlock()
+// within reports whether path is dir or inside it.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
 
-	var lines []string
-	for _, group := range s.logGroups {
-		lines = append(lines, fmt.Sprintf("- %s: pattern=%q streams=%v",
-			group.Name, group.Pattern, group.Streams))
+// writeExport writes entries to path as text lines or JSON, refusing to
+// replace an existing file unless overwrite is set.
+func writeExport(path string, entries []logtail.LogEntry, format string, overwrite bool) (int, error) {
+	var data string
+	if format == "json" {
+		data = entriesJSON(entries, true) + "\n"
+	} else {
+		var b strings.Builder
+		for _, entry := range entries {
+			fmt.Fprintf(&b, "[%s] [%s] %s%s\n",
+				entry.Timestamp.Format(time.RFC3339),
+				entry.Source,
+				entry.Content,
+				repeatSuffix(entry))
+		}
+		data = b.String()
 	}
 
-	text := fmt.Sprintf("Log Groups: %d\n\n%s", len(s.logGroups), strings.Join(lines, "\n"))
-	if len(s.logGroups) == 0 {
-		text = "No log groups defined"
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return 0, fmt.Errorf("%s already exists; set overwrite to replace it", path)
+		}
+		return 0, err
+	}
+	if _, err := file.WriteString(data); err != nil {
+		file.Close()
+		return 0, err
+	}
+	return len(entries), file.Close()
+}
+
+func (s *Server) toolRemoveStream(params map[string]interface{}, id interface{}, agent identity) MCPResponse {
+	name, _ := params["name"].(string)
+	purge, _ := params["purge"].(bool)
+
+	var text string
+	if _, ok := s.manager.GetStreams()[name]; ok {
+		if err := s.manager.StopStream(name, purge); err != nil {
+			return MCPResponse{
+				Error: &MCPError{
+					Code:    -32602,
+					Message: err.Error(),
+				},
+				ID: id,
+			}
+		}
+		text = fmt.Sprintf("Stopped tailing %s", name)
+	} else {
+		closed, err := s.manager.RemoveStream(name, purge)
+		if err != nil {
+			return MCPResponse{
+				Error: &MCPError{
+					Code:    -32602,
+					Message: err.Error(),
+				},
+				ID: id,
+			}
+		}
+		text = fmt.Sprintf("Removed stream '%s' (%d files closed)", name, closed)
+		if purge {
+			text += "; buffered entries purged"
+		}
 	}
 
-	s.logAccess(agentID, "list_groups", "", "", len(s.logGroups))
+	s.logAccess(agent, "remove_stream", name, "", 1)
 
 	return MCPResponse{
 		Result: map[string]interface{}{
@@ -767,37 +2634,62 @@ func (s *Server) toolGroups(id interface{}, agentID string) MCPResponse {
 	}
 }
 
-func (s *Server) toolCreateGroup(params map[string]interface{}, id interface{}, agentID string) MCPResponse {
-	name, _ := params["name"].(string)
-	pattern, _ := params["pattern"].(string)
-	color, _ := params["color"].(string)
-	streamsStr, _ := params["streams"].(string)
+func (s *Server) toolStats(id interface{}, agent identity) MCPResponse {
+	streams := s.manager.GetStreams()
+	streamCount := len(streams)
 
-	var streams []string
-	if streamsStr != "" {
-		parts := strings.Split(streamsStr, ",")
-		for _, p := range parts {
-			streams = append(streams, strings.TrimSpace(p))
-		}
-	}
+	s.groupsMu.RLock()
+	groupCount := len(s.logGroups)
+	s.groupsMu.RUnlock()
 
-	if color == "" {
-		color = "cyan"
+	buffered := s.manager.BufferLen()
+	capacity := "unlimited"
+	if limit := s.manager.BufferPolicy().MaxEntries; limit > 0 {
+		capacity = "max " + strconv.Itoa(limit) + " per stream"
 	}
 
-	s.groupsMu.Lock()
-	s.logGroups[name] = LogGroup{
-		Name:      name,
-		Pattern:   pattern,
-		Color:     color,
-		Streams:   streams,
-		CreatedAt: time.Now(),
-	}
-	s.groupsMu.Unlock()
+	s.logAccess(agent, "stats", "", "", 0)
+
+	s.accessMu.RLock()
+	accessCount := len(s.accessLog)
+	s.accessMu.RUnlock()
+
+	text := fmt.Sprintf("Logdump Statistics:\n- Active streams: %d\n- Log groups: %d\n- Buffer size: %d entries (%s)\n- Access log: %d entries",
+		streamCount, groupCount, buffered, capacity, accessCount)
 
-	s.logAccess(agentID, "create_group", name, pattern, 1)
+	stats := s.manager.Stats()
+	memory := fmt.Sprintf("%.1f MiB", float64(stats.BufferMemory)/(1<<20))
+	if limit := s.manager.BufferPolicy().MaxMemory; limit > 0 {
+		memory += fmt.Sprintf(" of %d MiB", limit>>20)
+	}
+	text += "\n- Buffer memory (estimated): " + memory
+	if stats.SubscriberDrops > 0 {
+		text += fmt.Sprintf("\n- Missed by slow subscribers: %d entries", stats.SubscriberDrops)
+	}
+	if sink := stats.Sink; sink != nil {
+		text += fmt.Sprintf("\n- Sink: %s, %d bytes written", sink.Path, sink.BytesWritten)
+		if sink.Error != "" {
+			text += " (last error: " + sink.Error + ")"
+		}
+	}
 
-	text := fmt.Sprintf("Created group '%s' with pattern '%s'", name, pattern)
+	names := make([]string, 0, len(stats.Streams))
+	for name := range stats.Streams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) > 0 {
+		text += "\n\nStreams:"
+	}
+	for _, name := range names {
+		st := stats.Streams[name]
+		last := "never"
+		if !st.LastLine.IsZero() {
+			last = st.LastLine.Format(time.RFC3339)
+		}
+		text += fmt.Sprintf("\n- %s: %.1f lines/s (10s), %.1f lines/s (60s), %d lines, %d bytes read, %d dropped, %d excluded, %d overflowed, %d buffered, %d evicted, last line %s",
+			name, st.Rate10s, st.Rate60s, st.LinesRead, st.BytesRead, st.Dropped, st.Excluded, st.Overflowed, st.Buffered, st.Evicted, last)
+	}
 
 	return MCPResponse{
 		Result: map[string]interface{}{
@@ -812,27 +2704,60 @@ func (s *Server) toolCreateGroup(params map[string]interface{}, id interface{},
 	}
 }
 
-func (s *Server) toolStats(id interface{}, agentID string) MCPResponse {
-	streams := s.manager.GetStreams()
-	streamCount := len(streams)
+// secretKey matches config keys whose values are redacted by logdump_config.
+// Webhook URLs count: Slack and PagerDuty hooks carry their key in the URL.
+var secretKey = regexp.MustCompile(`(?i)token|secret|password|api_?key|auth|webhook|url`)
+
+func (s *Server) toolConfig(id interface{}, agent identity) MCPResponse {
+	// Round-trip through YAML so the output uses the config file's keys
+	var tree map[string]interface{}
+	data, err := yaml.Marshal(s.currentConfig())
+	if err == nil {
+		err = yaml.Unmarshal(data, &tree)
+	}
+	if err != nil {
+		return MCPResponse{
+			Error: &MCPError{
+				Code:    -32603,
+				Message: fmt.Sprintf("Failed to encode config: %v", err),
+			},
+			ID: id,
+		}
+	}
+	redactSecrets(tree)
 
+	// Groups can be created at runtime, so report the live set
 	s.groupsMu.RLock()
-	groupCount := len(s.logGroups)
+	groups := make([]LogGroup, 0, len(s.logGroups))
+	for _, g := range s.logGroups {
+		groups = append(groups, g)
+	}
 	s.groupsMu.RUnlock()
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	tree["groups"] = groups
+
+	policy := s.manager.BufferPolicy()
+	tree["runtime"] = map[string]interface{}{
+		"transport": s.transport,
+		"buffer": map[string]interface{}{
+			"max_entries": policy.MaxEntries,
+			"max_bytes":   policy.MaxBytes,
+			"max_age":     policy.MaxAge.String(),
+			"max_memory":  policy.MaxMemory,
+		},
+		"files_tailed": len(s.manager.GetStreams()),
+	}
 
-	bufferSize := len(s.manager.GetBuffer())
-
-	s.logAccess(agentID, "stats", "", "", 0)
+	out, _ := json.MarshalIndent(tree, "", "  ")
 
-	text := fmt.Sprintf("Logdump Statistics:\n- Active streams: %d\n- Log groups: %d\n- Buffer size: %d entries\n- Access log: %d entries",
-		streamCount, groupCount, bufferSize, len(s.accessLog))
+	s.logAccess(agent, "config", "", "", 0)
 
 	return MCPResponse{
 		Result: map[string]interface{}{
 			"content": []map[string]interface{}{
 				{
 					"type": "text",
-					"text": text,
+					"text": string(out),
 				},
 			},
 		},
@@ -840,8 +2765,27 @@ func (s *Server) toolStats(id interface{}, agentID string) MCPResponse {
 	}
 }
 
-func (s *Server) toolAccessLog(params map[string]interface{}, id interface{}, agentID string) MCPResponse {
+// redactSecrets replaces the values of secret-looking keys, recursively.
+func redactSecrets(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if _, nested := child.(map[string]interface{}); !nested && secretKey.MatchString(k) {
+				v[k] = "[redacted]"
+				continue
+			}
+			redactSecrets(child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			redactSecrets(child)
+		}
+	}
+}
+
+func (s *Server) toolAccessLog(params map[string]interface{}, id interface{}, agent identity) MCPResponse {
 	filterAgent, _ := params["agent"].(string)
+	format, _ := params["format"].(string)
 	limit := 50
 	if l, ok := params["limit"].(float64); ok {
 		limit = int(l)
@@ -865,6 +2809,46 @@ func (s *Server) toolAccessLog(params map[string]interface{}, id interface{}, ag
 		filtered = filtered[len(filtered)-limit:]
 	}
 
+	if format == "json" {
+		return MCPResponse{
+			Result: map[string]interface{}{
+				"content": []map[string]interface{}{
+					{
+						"type": "text",
+						"text": toJSON(filtered),
+					},
+				},
+			},
+			ID:    id,
+			count: len(filtered),
+		}
+	}
+
+	if format == "csv" {
+		text, err := accessLogCSV(filtered)
+		if err != nil {
+			return MCPResponse{
+				Error: &MCPError{
+					Code:    -32603,
+					Message: err.Error(),
+				},
+				ID: id,
+			}
+		}
+		return MCPResponse{
+			Result: map[string]interface{}{
+				"content": []map[string]interface{}{
+					{
+						"type": "text",
+						"text": text,
+					},
+				},
+			},
+			ID:    id,
+			count: len(filtered),
+		}
+	}
+
 	var lines []string
 	for _, a := range filtered {
 		lines = append(lines, fmt.Sprintf("[%s] %s: %s (results: %d)",
@@ -885,8 +2869,39 @@ func (s *Server) toolAccessLog(params map[string]interface{}, id interface{}, ag
 				},
 			},
 		},
-		ID: id,
+		ID:    id,
+		count: len(filtered),
+	}
+}
+
+// accessLogCSV renders access log entries as CSV with a header row.
+func accessLogCSV(entries []AgentAccess) (string, error) {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+
+	if err := w.Write([]string{"timestamp", "agent_id", "agent_name", "action", "source", "pattern", "result_count"}); err != nil {
+		return "", err
+	}
+	for _, a := range entries {
+		record := []string{
+			a.Timestamp.Format(time.RFC3339),
+			a.AgentID,
+			a.AgentName,
+			a.Action,
+			a.Source,
+			a.Pattern,
+			fmt.Sprintf("%d", a.ResultCount),
+		}
+		if err := w.Write(record); err != nil {
+			return "", err
+		}
 	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
 }
 
 func (s *Server) handleSetAgent(ctx context.Context, req MCPRequest, id interface{}) MCPResponse {
@@ -904,7 +2919,20 @@ func (s *Server) handleSetAgent(ctx context.Context, req MCPRequest, id interfac
 		}
 	}
 
-	s.currentAgent = fmt.Sprintf("%s (%s)", params.AgentName, params.AgentID)
+	sess, ok := sessionFrom(ctx)
+	if !ok {
+		return MCPResponse{
+			Error: &MCPError{
+				Code:    -32603,
+				Message: "Setting the agent is not supported on this connection",
+			},
+			ID: id,
+		}
+	}
+	sess.setIdentity(identity{
+		id:   fmt.Sprintf("%s (%s)", params.AgentName, params.AgentID),
+		name: params.AgentName,
+	})
 
 	return MCPResponse{
 		Result: map[string]interface{}{
@@ -915,7 +2943,7 @@ func (s *Server) handleSetAgent(ctx context.Context, req MCPRequest, id interfac
 }
 
 func (s *Server) handleAccessLog(req MCPRequest, id interface{}) MCPResponse {
-	return s.toolAccessLog(make(map[string]interface{}), id, "ui")
+	return s.toolAccessLog(make(map[string]interface{}), id, identity{id: "ui"})
 }
 
 func (s *Server) handleResourcesList(req MCPRequest, id interface{}) MCPResponse {
@@ -932,7 +2960,7 @@ func (s *Server) handleResourcesList(req MCPRequest, id interface{}) MCPResponse
 	}
 	s.groupsMu.RUnlock()
 
-	for _, stream := range s.config.Streams {
+	for _, stream := range s.currentConfig().Streams {
 		resources = append(resources, map[string]interface{}{
 			"uri":         fmt.Sprintf("logdump://stream/%s", strings.ToLower(stream.Name)),
 			"name":        stream.Name,
@@ -980,15 +3008,21 @@ func (s *Server) handleResourcesRead(ctx context.Context, req MCPRequest, id int
 		group, ok := s.logGroups[groupName]
 		s.groupsMu.RUnlock()
 		if ok {
-			re := regexp.MustCompile("(?i)" + group.Pattern)
+			re, err := group.compile()
+			if err != nil {
+				return MCPResponse{
+					Error: &MCPError{
+						Code:    -32603,
+						Message: err.Error(),
+					},
+					ID: id,
+				}
+			}
 			entries := s.manager.GetEntries("", 100)
 			var lines []string
 			for _, e := range entries {
-				for _, stream := range group.Streams {
-					if e.Source == stream && re.MatchString(e.Content) {
-						lines = append(lines, fmt.Sprintf("[%s] %s | %s", e.Timestamp.Format("15:04:05.000"), e.Source, e.Content))
-						break
-					}
+				if group.covers(e.Source) && re.MatchString(e.Content) {
+					lines = append(lines, fmt.Sprintf("[%s] %s | %s", e.Timestamp.Format("15:04:05.000"), e.Source, e.Content))
 				}
 			}
 			text = strings.Join(lines, "\n")
@@ -1024,11 +3058,3 @@ func (s *Server) handleResourcesRead(ctx context.Context, req MCPRequest, id int
 		ID: id,
 	}
 }
-
-var upgrader = &websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
@@ -0,0 +1,660 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/appgram/logdump/internal/config"
+	"github.com/appgram/logdump/internal/logtail"
+	"github.com/gorilla/websocket"
+)
+
+// newTestServer returns a Server for cfg whose manager buffers entries.
+// HOME is pointed at a temporary directory, so the activity log is written
+// there.
+func newTestServer(t *testing.T, cfg *config.Config) *Server {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	m := logtail.NewManager()
+	m.StartBuffering()
+	t.Cleanup(func() {
+		m.Close()
+		m.Wait()
+	})
+	s := NewServer(m, cfg)
+	t.Cleanup(func() {
+		if s.logFile != nil {
+			s.logFile.Close()
+		}
+	})
+	return s
+}
+
+// tailFile writes lines to a file in a temporary directory and tails it
+// as the stream name.
+func tailFile(t *testing.T, s *Server, name string, lines ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, name+".log")
+	var data string
+	for _, line := range lines {
+		data += line + "\n"
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.manager.Tail(config.StreamConfig{Name: name, Path: dir, Patterns: []string{name + ".log"}}); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// waitFor polls cond until it holds, failing the test after five seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// callTool calls a tool as a tools/call request on ctx would.
+func callTool(t *testing.T, s *Server, ctx context.Context, name string, args map[string]interface{}) MCPResponse {
+	t.Helper()
+	params, err := json.Marshal(map[string]interface{}{"name": name, "arguments": args})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.handleRequest(ctx, MCPRequest{JSONRPC: "2.0", Method: "tools/call", Params: params, ID: json.RawMessage("1")})
+}
+
+// resultText returns the text content of a tool result.
+func resultText(t *testing.T, resp MCPResponse) string {
+	t.Helper()
+	if resp.Error != nil {
+		t.Fatalf("error %d: %s", resp.Error.Code, resp.Error.Message)
+	}
+	result, ok := resp.Result.(map[string]interface{})
+	if !ok {
+		t.Fatalf("result is %T", resp.Result)
+	}
+	content, ok := result["content"].([]map[string]interface{})
+	if !ok || len(content) == 0 {
+		t.Fatalf("result has no content: %v", result)
+	}
+	text, _ := content[0]["text"].(string)
+	return text
+}
+
+func TestConfigRedactsSecrets(t *testing.T) {
+	hook := "https://hooks.slack.com/services/T000/B000/secretpart"
+	cfg := &config.Config{
+		Filters: []config.FilterConfig{{Name: "fatal", Pattern: "FATAL", Actions: []string{"alert"}, Webhook: hook}},
+	}
+	s := newTestServer(t, cfg)
+
+	text := resultText(t, callTool(t, s, context.Background(), "logdump_config", nil))
+	if strings.Contains(text, "secretpart") {
+		t.Errorf("logdump_config shows the webhook URL:\n%s", text)
+	}
+	if !strings.Contains(text, `"webhook": "[redacted]"`) {
+		t.Errorf("logdump_config does not show the webhook as redacted:\n%s", text)
+	}
+}
+
+// serveStdio runs handleStdio over pipes, returning the writer for requests,
+// the reader for what it writes back and a channel receiving handleStdio's
+// result.
+func serveStdio(t *testing.T, s *Server) (io.WriteCloser, io.Reader, <-chan error) {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		done <- s.handleStdio(context.Background(), inR, outW)
+		outW.Close()
+	}()
+	t.Cleanup(func() {
+		inW.Close()
+		go io.Copy(io.Discard, outR)
+		<-stopped
+	})
+	return inW, outR, done
+}
+
+func TestStdioAnswersWhileWaiting(t *testing.T) {
+	s := newTestServer(t, &config.Config{})
+	in, out, _ := serveStdio(t, s)
+	responses := json.NewDecoder(out)
+
+	io.WriteString(in, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"logdump_tail","arguments":{"timeout_seconds":1}}}`+"\n")
+	io.WriteString(in, `{"jsonrpc":"2.0","id":2,"method":"ping"}`+"\n")
+
+	var first, second struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if err := responses.Decode(&first); err != nil {
+		t.Fatal(err)
+	}
+	if err := responses.Decode(&second); err != nil {
+		t.Fatal(err)
+	}
+	if string(first.ID) != "2" || string(second.ID) != "1" {
+		t.Errorf("responses came for ids %s then %s, want the ping (2) before the wait (1)", first.ID, second.ID)
+	}
+}
+
+func TestStdioEOFCancelsWaits(t *testing.T) {
+	drain := stdioDrain
+	stdioDrain = 50 * time.Millisecond
+	t.Cleanup(func() { stdioDrain = drain })
+
+	s := newTestServer(t, &config.Config{})
+	in, _, done := serveStdio(t, s)
+
+	io.WriteString(in, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"logdump_tail","arguments":{"timeout_seconds":60}}}`+"\n")
+	in.Close()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("handleStdio: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handleStdio still waiting for logdump_tail after the end of input")
+	}
+}
+
+func TestSetConfigAppliesStrict(t *testing.T) {
+	s := newTestServer(t, &config.Config{})
+	request := []byte(`{"id":1,"method":"ping"}`)
+
+	if _, errResp := s.decodeRequest(request); errResp != nil {
+		t.Fatalf("request without jsonrpc rejected before strict mode: %s", errResp.Error.Message)
+	}
+	cfg := &config.Config{}
+	cfg.MCP.Strict = true
+	s.SetConfig(cfg)
+	if _, errResp := s.decodeRequest(request); errResp == nil {
+		t.Error("request without jsonrpc accepted after the reloaded config turned on strict mode")
+	}
+}
+
+func TestStreamsReportsLinesRead(t *testing.T) {
+	s := newTestServer(t, &config.Config{})
+	path := tailFile(t, s, "app", "one", "two", "three")
+	waitFor(t, "the history to be read", func() bool { return s.manager.BufferLen() == 3 })
+
+	// Listing streams while they read lines must not race with the readers
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		io.WriteString(f, "more\n")
+		callTool(t, s, context.Background(), "logdump_streams", nil)
+	}
+	f.Close()
+	waitFor(t, "the appended lines", func() bool { return s.manager.BufferLen() == 23 })
+
+	text := resultText(t, callTool(t, s, context.Background(), "logdump_streams", map[string]interface{}{"format": "json"}))
+	var streams []streamJSON
+	if err := json.Unmarshal([]byte(text), &streams); err != nil {
+		t.Fatalf("%v in %s", err, text)
+	}
+	if len(streams) != 1 || streams[0].LinesRead != 23 {
+		t.Errorf("logdump_streams lists %+v, want app with 23 lines read", streams)
+	}
+
+	text = resultText(t, callTool(t, s, context.Background(), "logdump_streams", nil))
+	if !strings.Contains(text, "(23 lines read,") {
+		t.Errorf("logdump_streams text does not show 23 lines read:\n%s", text)
+	}
+}
+
+func TestUnknownToolListsAvailableTools(t *testing.T) {
+	s := newTestServer(t, &config.Config{})
+
+	resp := callTool(t, s, context.Background(), "logdump_nope", nil)
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Fatalf("unknown tool gave %+v, want error -32602", resp)
+	}
+	data, ok := resp.Error.Data.(map[string]interface{})
+	if !ok {
+		t.Fatalf("error data is %T", resp.Error.Data)
+	}
+	tools, _ := data["available_tools"].([]string)
+	if !slices.Equal(tools, toolNames()) || !slices.Contains(tools, "logdump_read") {
+		t.Errorf("available_tools is %v, want %v", data["available_tools"], toolNames())
+	}
+}
+
+func TestReadWithFields(t *testing.T) {
+	s := newTestServer(t, &config.Config{})
+	s.manager.AddEntry(logtail.LogEntry{Timestamp: time.Now(), Source: "app", Content: "first", Fields: map[string]string{"req.id": "r1", "level": "error"}})
+
+	text := resultText(t, callTool(t, s, context.Background(), "logdump_read", map[string]interface{}{"fields": true}))
+	if !strings.Contains(text, `first {level="error" req.id="r1"}`) {
+		t.Errorf("logdump_read with fields does not show them:\n%s", text)
+	}
+	text = resultText(t, callTool(t, s, context.Background(), "logdump_read", nil))
+	if strings.Contains(text, "req.id") {
+		t.Errorf("logdump_read shows fields without being asked:\n%s", text)
+	}
+}
+
+func TestInvalidGroupPattern(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		s := newTestServer(t, &config.Config{})
+		resp := callTool(t, s, context.Background(), "logdump_create_group", map[string]interface{}{"name": "bad", "pattern": "("})
+		if resp.Error == nil || resp.Error.Code != -32602 || !strings.Contains(resp.Error.Message, "invalid pattern") {
+			t.Fatalf("creating a group with pattern ( gave %+v, want an invalid pattern error", resp)
+		}
+		if len(s.logGroups) != 0 {
+			t.Errorf("the group was stored: %v", s.logGroups)
+		}
+	})
+
+	// A bad pattern already in the config fails reads of the group
+	// instead of taking the server down
+	s := newTestServer(t, &config.Config{Groups: []config.GroupConfig{{Name: "bad", Pattern: "("}}})
+	s.manager.AddEntry(logtail.LogEntry{Timestamp: time.Now(), Source: "app", Content: "line"})
+	t.Run("logdump_read", func(t *testing.T) {
+		resp := callTool(t, s, context.Background(), "logdump_read", map[string]interface{}{"group": "bad"})
+		if resp.Error == nil || resp.Error.Code != -32602 {
+			t.Errorf("reading the group gave %+v, want error -32602", resp)
+		}
+	})
+	t.Run("resources/read", func(t *testing.T) {
+		params, _ := json.Marshal(map[string]string{"uri": "logdump://group/bad"})
+		resp := s.handleRequest(context.Background(), MCPRequest{Method: "resources/read", Params: params, ID: json.RawMessage("1")})
+		if resp.Error == nil {
+			t.Errorf("reading the group resource gave %+v, want an error", resp.Result)
+		}
+	})
+}
+
+// freeAddr returns a loopback address with a port nothing listens on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// runWebsocket starts the websocket transport, returning its address and
+// a channel receiving RunWebsocket's result once ctx is done.
+func runWebsocket(t *testing.T, ctx context.Context, s *Server) (string, <-chan error) {
+	t.Helper()
+	addr := freeAddr(t)
+	done := make(chan error, 1)
+	go func() { done <- s.RunWebsocket(ctx, addr) }()
+	waitFor(t, "the websocket server to listen", func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	})
+	return addr, done
+}
+
+// dialWebsocket connects a websocket client to addr, closed when the test
+// ends.
+func dialWebsocket(t *testing.T, addr string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+addr+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// roundTrip sends request over conn and decodes the next message.
+func roundTrip(t *testing.T, conn *websocket.Conn, request string) map[string]interface{} {
+	t.Helper()
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(request)); err != nil {
+		t.Fatal(err)
+	}
+	var resp map[string]interface{}
+	if err := conn.ReadJSON(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestWebsocketShutdown(t *testing.T) {
+	s := newTestServer(t, &config.Config{})
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addr, done := runWebsocket(t, ctx, s)
+
+	idle := dialWebsocket(t, addr)
+	roundTrip(t, idle, `{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+	waiting := dialWebsocket(t, addr)
+	if err := waiting.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"logdump_tail","arguments":{"timeout_seconds":60}}}`)); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "both clients to connect", func() bool { return s.wsClients.Load() == 2 })
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("RunWebsocket: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("RunWebsocket still running after its context was cancelled")
+	}
+
+	// Clients are told the server is going away
+	_, _, err := idle.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("idle client read %v, want a going away close", err)
+	}
+	if n := s.wsClients.Load(); n != 0 {
+		t.Errorf("%d websocket clients still counted", n)
+	}
+	waitFor(t, "the server's goroutines to return", func() bool { return runtime.NumGoroutine() <= before })
+}
+
+func TestGrep(t *testing.T) {
+	s := newTestServer(t, &config.Config{})
+	for _, e := range []struct{ source, content string }{
+		{"api", "ERROR disk full"},
+		{"api", "request ok"},
+		{"api", "error: retry a.b"},
+		{"api", "axb done"},
+		{"worker", "error in job"},
+	} {
+		s.manager.AddEntry(logtail.LogEntry{Timestamp: time.Now(), Source: e.source, Content: e.content})
+	}
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want []string
+	}{
+		{"case sensitive", map[string]interface{}{"pattern": "error"}, []string{"error: retry a.b", "error in job"}},
+		{"case insensitive", map[string]interface{}{"pattern": "error", "case_insensitive": true}, []string{"ERROR disk full", "error: retry a.b", "error in job"}},
+		{"source", map[string]interface{}{"pattern": "error", "case_insensitive": true, "source": "api"}, []string{"ERROR disk full", "error: retry a.b"}},
+		{"regex", map[string]interface{}{"pattern": "a.b"}, []string{"error: retry a.b", "axb done"}},
+		{"literal", map[string]interface{}{"pattern": "a.b", "literal": true}, []string{"error: retry a.b"}},
+		{"limit", map[string]interface{}{"pattern": "error", "case_insensitive": true, "limit": float64(1)}, []string{"ERROR disk full"}},
+		{"no match", map[string]interface{}{"pattern": "zzz"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text := resultText(t, callTool(t, s, context.Background(), "logdump_grep", tt.args))
+			var got []string
+			for _, line := range strings.Split(text, "\n") {
+				if _, content, ok := strings.Cut(line, "] ["); ok {
+					_, content, _ = strings.Cut(content, "] ")
+					got = append(got, content)
+				}
+			}
+			// Each matching entry is listed once, and counted once
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("grep found %q, want %q", got, tt.want)
+			}
+			if len(tt.want) > 0 && !strings.Contains(text, fmt.Sprintf("Matches: %d\n", len(tt.want))) {
+				t.Errorf("grep output does not count %d matches:\n%s", len(tt.want), text)
+			}
+		})
+	}
+}
+
+// startTail calls logdump_tail with args in the background, returning the
+// channel its response arrives on once its subscription is in place.
+func startTail(t *testing.T, s *Server, ctx context.Context, args map[string]interface{}) <-chan MCPResponse {
+	t.Helper()
+	subscribers := s.manager.Stats().Subscribers
+	resp := make(chan MCPResponse, 1)
+	go func() { resp <- callTool(t, s, ctx, "logdump_tail", args) }()
+	waitFor(t, "logdump_tail to subscribe", func() bool { return s.manager.Stats().Subscribers > subscribers })
+	return resp
+}
+
+// awaitResponse returns the response sent on resp, failing the test after
+// five seconds.
+func awaitResponse(t *testing.T, resp <-chan MCPResponse) MCPResponse {
+	t.Helper()
+	select {
+	case r := <-resp:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for logdump_tail")
+		return MCPResponse{}
+	}
+}
+
+func TestTailWaits(t *testing.T) {
+	s := newTestServer(t, &config.Config{})
+	path := tailFile(t, s, "api", "ERROR before the call")
+	tailFile(t, s, "worker")
+	waitFor(t, "the history to be read", func() bool { return s.manager.BufferLen() == 1 })
+
+	// Concurrent waits each get the next line matching their own arguments,
+	// never one from before the call
+	apiErrors := startTail(t, s, context.Background(), map[string]interface{}{"pattern": "ERROR", "source": "api", "timeout_seconds": float64(5)})
+	anyLine := startTail(t, s, context.Background(), map[string]interface{}{"timeout_seconds": float64(5)})
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(f, "INFO request\nERROR after the call\n")
+	f.Close()
+
+	if text := resultText(t, awaitResponse(t, apiErrors)); !strings.HasSuffix(text, "[api] ERROR after the call") {
+		t.Errorf("waiting for an api error returned %q", text)
+	}
+	if text := resultText(t, awaitResponse(t, anyLine)); !strings.HasSuffix(text, "[api] INFO request") {
+		t.Errorf("waiting for any line returned %q", text)
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		resp := callTool(t, s, context.Background(), "logdump_tail", map[string]interface{}{"source": "worker", "timeout_seconds": 0.1})
+		result, _ := resp.Result.(map[string]interface{})
+		if resp.Error != nil || result["timed_out"] != true {
+			t.Errorf("wait with nothing arriving gave %+v, want timed_out", resp)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		resp := startTail(t, s, ctx, map[string]interface{}{"timeout_seconds": float64(60)})
+		cancel()
+		if r := awaitResponse(t, resp); r.Error == nil || r.Error.Code != -32603 {
+			t.Errorf("cancelled wait gave %+v, want error -32603", r)
+		}
+	})
+
+	for _, timeout := range []float64{0, -1, maxTailWait.Seconds() + 1} {
+		resp := callTool(t, s, context.Background(), "logdump_tail", map[string]interface{}{"timeout_seconds": timeout})
+		if resp.Error == nil || resp.Error.Code != -32602 {
+			t.Errorf("timeout_seconds %v gave %+v, want error -32602", timeout, resp)
+		}
+	}
+}
+
+// idCases are requests as a client might send them, with the id and error
+// code of the response each should get, or none for notifications.
+var idCases = []struct {
+	name    string
+	request string
+	none    bool   // no response at all
+	id      string // the response's id as JSON
+	code    int    // the response's error code, 0 for a result
+}{
+	{name: "notification", request: `{"jsonrpc":"2.0","method":"notifications/initialized"}`, none: true},
+	{name: "cancel notification", request: `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":5}}`, none: true},
+	{name: "unknown notification", request: `{"jsonrpc":"2.0","method":"notifications/whatever"}`, none: true},
+	{name: "string id", request: `{"jsonrpc":"2.0","id":"req-1","method":"ping"}`, id: `"req-1"`},
+	{name: "numeric id", request: `{"jsonrpc":"2.0","id":42,"method":"ping"}`, id: `42`},
+	{name: "zero id", request: `{"jsonrpc":"2.0","id":0,"method":"ping"}`, id: `0`},
+	{name: "unknown method", request: `{"jsonrpc":"2.0","id":"x","method":"nope"}`, id: `"x"`, code: -32600},
+	{name: "malformed", request: `{"jsonrpc":"2.0","id":7,"method":`, id: `null`, code: -32700},
+	{name: "not JSON", request: `hello`, id: `null`, code: -32700},
+}
+
+// checkIDCases sends each of idCases with send, then a ping with the id
+// "end", checking what arrives on messages before the ping's answer.
+func checkIDCases(t *testing.T, send func(string), messages <-chan message) {
+	for _, tt := range idCases {
+		t.Run(tt.name, func(t *testing.T) {
+			send(tt.request)
+			send(`{"jsonrpc":"2.0","id":"end","method":"ping"}`)
+
+			// Requests may be answered in any order
+			var got []message
+			ended := false
+			for !ended || (!tt.none && len(got) == 0) {
+				m := next(t, messages)
+				if string(m.fields["id"]) == `"end"` {
+					ended = true
+					continue
+				}
+				got = append(got, m)
+			}
+			if tt.none {
+				if len(got) > 0 {
+					t.Errorf("notification answered with %s", got[0].line)
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("got %d responses", len(got))
+			}
+			var resp struct {
+				JSONRPC string          `json:"jsonrpc"`
+				ID      json.RawMessage `json:"id"`
+				Error   *MCPError       `json:"error"`
+			}
+			if err := json.Unmarshal([]byte(got[0].line), &resp); err != nil {
+				t.Fatal(err)
+			}
+			code := 0
+			if resp.Error != nil {
+				code = resp.Error.Code
+			}
+			if resp.JSONRPC != "2.0" || string(resp.ID) != tt.id || code != tt.code {
+				t.Errorf("response %s, want id %s and code %d", got[0].line, tt.id, tt.code)
+			}
+		})
+	}
+}
+
+func TestStdioIDs(t *testing.T) {
+	s := newTestServer(t, &config.Config{})
+	in, out, _ := serveStdio(t, s)
+	messages := readMessages(t, out)
+	checkIDCases(t, func(request string) { io.WriteString(in, request+"\n") }, messages)
+}
+
+func TestWebsocketIDs(t *testing.T) {
+	s := newTestServer(t, &config.Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addr, _ := runWebsocket(t, ctx, s)
+	conn := dialWebsocket(t, addr)
+
+	messages := make(chan message, 100)
+	go func() {
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			m := message{at: time.Now(), line: string(data)}
+			json.Unmarshal(data, &m.fields)
+			messages <- m
+		}
+	}()
+	checkIDCases(t, func(request string) {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(request)); err != nil {
+			t.Fatal(err)
+		}
+	}, messages)
+}
+
+func TestAgentPerConnection(t *testing.T) {
+	s := newTestServer(t, &config.Config{})
+	tailFile(t, s, "alpha", "from alpha")
+	tailFile(t, s, "beta", "from beta")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addr, _ := runWebsocket(t, ctx, s)
+
+	// Each client names itself after the stream it reads, over and over,
+	// so a shared identity would be overwritten between a client's
+	// set_agent and its reads
+	const rounds = 50
+	agents := []string{"alpha", "beta"}
+	errs := make(chan error, len(agents))
+	for _, agent := range agents {
+		conn := dialWebsocket(t, addr)
+		go func() {
+			errs <- func() error {
+				for i := 0; i < rounds; i++ {
+					for _, request := range []string{
+						fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"logdump/set_agent","params":{"agent_id":"%s-id","agent_name":"%s"}}`, 2*i, agent, agent),
+						fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":"logdump_read","arguments":{"source":"%s"}}}`, 2*i+1, agent),
+					} {
+						if err := conn.WriteMessage(websocket.TextMessage, []byte(request)); err != nil {
+							return err
+						}
+						var resp MCPResponse
+						if err := conn.ReadJSON(&resp); err != nil {
+							return err
+						}
+						if resp.Error != nil {
+							return fmt.Errorf("%s: %s", agent, resp.Error.Message)
+						}
+					}
+				}
+				return nil
+			}()
+		}()
+	}
+	for range agents {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reads := map[string]int{}
+	for _, access := range s.RecentAccess(0) {
+		if access.Action != "read" {
+			continue
+		}
+		if access.AgentName != access.Source || access.AgentID != access.Source+" ("+access.Source+"-id)" {
+			t.Fatalf("read of %s attributed to %s (%s)", access.Source, access.AgentName, access.AgentID)
+		}
+		reads[access.Source]++
+	}
+	for _, agent := range agents {
+		if reads[agent] != rounds {
+			t.Errorf("%d reads logged for %s, want %d", reads[agent], agent, rounds)
+		}
+	}
+}
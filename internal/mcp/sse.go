@@ -0,0 +1,176 @@
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// sseQueueSize is how many messages may wait for an SSE client to read
+// them before sends to it block.
+const sseQueueSize = 64
+
+// sseTransport serves the HTTP+SSE transport: a client opens an event
+// stream with GET /sse, is told where to POST its requests in an
+// "endpoint" event, and receives responses and notifications as "message"
+// events on the stream.
+type sseTransport struct {
+	server *Server
+
+	mu       sync.Mutex
+	sessions map[string]*sseSession
+}
+
+type sseSession struct {
+	*session
+	events chan []byte
+	done   chan struct{} // closed when the event stream ends
+	ctx    context.Context
+}
+
+// RunSSE serves the HTTP+SSE transport on addr until ctx is cancelled.
+func (s *Server) RunSSE(ctx context.Context, addr string) error {
+	ln, err := s.listen("sse", addr)
+	if err != nil {
+		return err
+	}
+	t := &sseTransport{server: s, sessions: make(map[string]*sseSession)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sse", t.handleStream)
+	mux.HandleFunc("POST /message", t.handleMessage)
+	return serve(ctx, &http.Server{Handler: mux}, ln, nil)
+}
+
+// handleStream holds a client's event stream open, writing its messages
+// until the client goes away.
+func (t *sseTransport) handleStream(w http.ResponseWriter, r *http.Request) {
+	if !t.server.checkOrigin(r) {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	sess := &sseSession{
+		events: make(chan []byte, sseQueueSize),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+	}
+	sess.session = newSession(func(v interface{}) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		select {
+		case sess.events <- data:
+			return nil
+		case <-sess.done:
+			return io.EOF
+		}
+	})
+
+	t.mu.Lock()
+	t.sessions[id] = sess
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.sessions, id)
+		t.mu.Unlock()
+		close(sess.done)
+	}()
+
+	go t.server.watchSubscriptions(ctx, sess.session)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "event: endpoint\ndata: /message?sessionId=%s\n\n", id)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data := <-sess.events:
+			// Marshalled JSON holds no newlines, so it fits one data line
+			if _, err := fmt.Fprintf(w, "event: message\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// handleMessage accepts a request for the session named by the sessionId
+// query parameter. Its response is sent on the session's event stream.
+func (t *sseTransport) handleMessage(w http.ResponseWriter, r *http.Request) {
+	if !t.server.checkOrigin(r) {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return
+	}
+	t.mu.Lock()
+	sess, ok := t.sessions[r.URL.Query().Get("sessionId")]
+	t.mu.Unlock()
+	if !ok {
+		http.Error(w, "Unknown session", http.StatusNotFound)
+		return
+	}
+
+	s := t.server
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.currentConfig().MCP.MessageLimit()))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("Request exceeds max_message_bytes (%d)", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Subscriptions made by the request belong to the event stream, which
+	// outlives this POST
+	ctx := context.WithValue(sess.ctx, sessionKey{}, sess.session)
+	resp, ok := s.respond(ctx, data)
+	if !ok {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if err := sess.send(resp); err != nil {
+		log.Printf("Error writing response: %v", err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// newSessionID returns a random identifier for an SSE session.
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
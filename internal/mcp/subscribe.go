@@ -0,0 +1,239 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/appgram/logdump/internal/logtail"
+)
+
+// subscriptionInterval is how often new entries are checked against a
+// session's resource subscriptions.
+const subscriptionInterval = 250 * time.Millisecond
+
+// notifyInterval is the shortest time between two notifications for the
+// same resource on a session. Updates in between are sent together once
+// it has passed.
+const notifyInterval = time.Second
+
+// MCPNotification is a JSON-RPC message that expects no response.
+type MCPNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// session is one client connection. Responses and notifications share the
+// connection, so writes are serialized.
+type session struct {
+	writeMu sync.Mutex
+	write   func(v interface{}) error
+
+	subsMu sync.Mutex
+	subs   map[string]bool // subscribed resource URIs
+
+	whoMu sync.RWMutex
+	who   identity // set by initialize or logdump/set_agent
+}
+
+// identity is who a client says it is, for the activity and access logs.
+type identity struct {
+	id   string // e.g. "cursor (1.2.0)"
+	name string
+}
+
+type sessionKey struct{}
+
+func newSession(write func(v interface{}) error) *session {
+	return &session{write: write, subs: make(map[string]bool)}
+}
+
+// sessionFrom returns the session a request arrived on, if any.
+func sessionFrom(ctx context.Context) (*session, bool) {
+	sess, ok := ctx.Value(sessionKey{}).(*session)
+	return sess, ok
+}
+
+func (s *session) send(v interface{}) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.write(v)
+}
+
+func (s *session) identity() identity {
+	s.whoMu.RLock()
+	defer s.whoMu.RUnlock()
+	return s.who
+}
+
+func (s *session) setIdentity(who identity) {
+	s.whoMu.Lock()
+	defer s.whoMu.Unlock()
+	s.who = who
+}
+
+func (s *session) setSubscribed(uri string, subscribed bool) {
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+	if subscribed {
+		s.subs[uri] = true
+	} else {
+		delete(s.subs, uri)
+	}
+}
+
+func (s *session) subscriptions() []string {
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+	uris := make([]string, 0, len(s.subs))
+	for uri := range s.subs {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	return uris
+}
+
+func (s *Server) handleSubscribe(ctx context.Context, req MCPRequest, id interface{}, subscribe bool) MCPResponse {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+		return MCPResponse{
+			Error: &MCPError{
+				Code:    -32602,
+				Message: "Invalid params: uri is required",
+			},
+			ID: id,
+		}
+	}
+
+	if !strings.HasPrefix(params.URI, "logdump://stream/") && !strings.HasPrefix(params.URI, "logdump://group/") {
+		return MCPResponse{
+			Error: &MCPError{
+				Code:    -32602,
+				Message: "Unknown resource URI: " + params.URI,
+			},
+			ID: id,
+		}
+	}
+
+	sess, ok := sessionFrom(ctx)
+	if !ok {
+		return MCPResponse{
+			Error: &MCPError{
+				Code:    -32603,
+				Message: "Subscriptions are not supported on this connection",
+			},
+			ID: id,
+		}
+	}
+
+	sess.setSubscribed(params.URI, subscribe)
+	action := "UNSUBSCRIBE"
+	if subscribe {
+		action = "SUBSCRIBE"
+	}
+	s.logActivity(ctx, action+": "+params.URI)
+
+	return MCPResponse{Result: map[string]interface{}{}, ID: id}
+}
+
+// watchSubscriptions sends notifications/resources/updated to sess whenever
+// new buffered entries match one of its subscriptions, at most once per
+// notifyInterval for each, until ctx is done.
+func (s *Server) watchSubscriptions(ctx context.Context, sess *session) {
+	ticker := time.NewTicker(subscriptionInterval)
+	defer ticker.Stop()
+
+	cursor := s.manager.Cursor()
+	sent := make(map[string]time.Time) // when each resource was last notified
+	pending := make(map[string]bool)   // resources updated since then
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		uris := sess.subscriptions()
+		for uri := range sent {
+			if !slices.Contains(uris, uri) {
+				delete(sent, uri)
+			}
+		}
+		for uri := range pending {
+			if !slices.Contains(uris, uri) {
+				delete(pending, uri)
+			}
+		}
+		if len(uris) == 0 {
+			cursor = s.manager.Cursor()
+			continue
+		}
+
+		var entries []logtail.LogEntry
+		entries, cursor = s.manager.GetEntriesSince("", cursor, 0)
+		if len(entries) > 0 {
+			for _, uri := range uris {
+				if s.resourceMatches(uri, entries) {
+					pending[uri] = true
+				}
+			}
+		}
+
+		now := time.Now()
+		for uri := range pending {
+			if now.Sub(sent[uri]) < notifyInterval {
+				continue
+			}
+			err := sess.send(MCPNotification{
+				JSONRPC: "2.0",
+				Method:  "notifications/resources/updated",
+				Params:  map[string]interface{}{"uri": uri},
+			})
+			if err != nil {
+				return
+			}
+			sent[uri] = now
+			delete(pending, uri)
+		}
+	}
+}
+
+// resourceMatches reports whether any of entries belongs to the resource.
+func (s *Server) resourceMatches(uri string, entries []logtail.LogEntry) bool {
+	if name, ok := strings.CutPrefix(uri, "logdump://stream/"); ok {
+		for _, e := range entries {
+			if strings.EqualFold(e.Source, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	name, ok := strings.CutPrefix(uri, "logdump://group/")
+	if !ok {
+		return false
+	}
+	s.groupsMu.RLock()
+	group, ok := s.logGroups[name]
+	s.groupsMu.RUnlock()
+	if !ok {
+		return false
+	}
+	re, err := group.compile()
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if group.covers(e.Source) && re.MatchString(e.Content) {
+			return true
+		}
+	}
+	return false
+}
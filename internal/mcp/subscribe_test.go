@@ -0,0 +1,151 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/appgram/logdump/internal/config"
+	"github.com/appgram/logdump/internal/logtail"
+)
+
+func TestGroupResourceWithoutStreams(t *testing.T) {
+	s := newTestServer(t, &config.Config{Groups: []config.GroupConfig{
+		{Name: "errors", Pattern: "error"},
+		{Name: "api-errors", Pattern: "error", Streams: []string{"api"}},
+	}})
+	entries := []logtail.LogEntry{
+		{Timestamp: time.Now(), Source: "worker", Content: "error: job failed"},
+		{Timestamp: time.Now(), Source: "api", Content: "request ok"},
+	}
+	for _, e := range entries {
+		s.manager.AddEntry(e)
+	}
+
+	tests := []struct {
+		uri     string
+		matches bool
+	}{
+		{"logdump://group/errors", true},
+		{"logdump://group/api-errors", false},
+	}
+	for _, tt := range tests {
+		if got := s.resourceMatches(tt.uri, entries); got != tt.matches {
+			t.Errorf("resourceMatches(%s) = %v, want %v", tt.uri, got, tt.matches)
+		}
+
+		params, _ := json.Marshal(map[string]string{"uri": tt.uri})
+		resp := s.handleRequest(context.Background(), MCPRequest{Method: "resources/read", Params: params, ID: json.RawMessage("1")})
+		data, _ := json.Marshal(resp.Result)
+		if got := strings.Contains(string(data), "job failed"); got != tt.matches {
+			t.Errorf("resources/read %s = %s, want the worker line: %v", tt.uri, data, tt.matches)
+		}
+	}
+}
+
+// message is one line written by the stdio transport, and when it arrived.
+type message struct {
+	at     time.Time
+	line   string
+	fields map[string]json.RawMessage
+}
+
+// readMessages sends each line read from out, decoded, on the returned
+// channel.
+func readMessages(t *testing.T, out io.Reader) <-chan message {
+	messages := make(chan message, 100)
+	go func() {
+		scanner := bufio.NewScanner(out)
+		for scanner.Scan() {
+			m := message{at: time.Now(), line: scanner.Text()}
+			if err := json.Unmarshal(scanner.Bytes(), &m.fields); err != nil {
+				t.Errorf("line %q is not a JSON object: %v", m.line, err)
+			}
+			messages <- m
+		}
+	}()
+	return messages
+}
+
+// next returns the next message, failing the test if none arrives within
+// three seconds.
+func next(t *testing.T, messages <-chan message) message {
+	t.Helper()
+	select {
+	case m := <-messages:
+		return m
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for a message")
+		return message{}
+	}
+}
+
+func TestStdioNotifications(t *testing.T) {
+	s := newTestServer(t, &config.Config{})
+	in, out, _ := serveStdio(t, s)
+	messages := readMessages(t, out)
+	add := func(source, content string) {
+		s.manager.AddEntry(logtail.LogEntry{Timestamp: time.Now(), Source: source, Content: content})
+	}
+
+	io.WriteString(in, `{"jsonrpc":"2.0","id":1,"method":"resources/subscribe","params":{"uri":"logdump://stream/api"}}`+"\n")
+	if m := next(t, messages); string(m.fields["id"]) != "1" || m.fields["error"] != nil {
+		t.Fatalf("subscribing gave %s", m.line)
+	}
+
+	// Entries of other streams notify nothing
+	add("worker", "job done")
+	select {
+	case m := <-messages:
+		t.Fatalf("got %s for an entry of another stream", m.line)
+	case <-time.After(2 * subscriptionInterval):
+	}
+
+	add("api", "request 1")
+	first := next(t, messages)
+	want := `{"jsonrpc":"2.0","method":"notifications/resources/updated","params":{"uri":"logdump://stream/api"}}`
+	if first.line != want {
+		t.Fatalf("notification is %s, want %s", first.line, want)
+	}
+
+	// Further updates within notifyInterval make one more notification,
+	// once it has passed; responses are not held up meanwhile
+	for i := range 3 {
+		add("api", fmt.Sprintf("request %d", i+2))
+		time.Sleep(subscriptionInterval)
+	}
+	io.WriteString(in, `{"jsonrpc":"2.0","id":2,"method":"ping"}`+"\n")
+	var pong message
+	second := next(t, messages)
+	if _, ok := second.fields["id"]; ok {
+		pong, second = second, next(t, messages)
+	}
+	if string(pong.fields["id"]) != "2" {
+		t.Errorf("ping answered with %q", pong.line)
+	}
+	if second.line != want {
+		t.Errorf("second notification is %s, want %s", second.line, want)
+	}
+	if gap := second.at.Sub(first.at); gap < notifyInterval-subscriptionInterval/2 {
+		t.Errorf("notifications %v apart, want at least %v", gap, notifyInterval)
+	}
+	select {
+	case m := <-messages:
+		t.Errorf("got %s after the updates were notified", m.line)
+	case <-time.After(notifyInterval + subscriptionInterval):
+	}
+
+	io.WriteString(in, `{"jsonrpc":"2.0","id":3,"method":"resources/unsubscribe","params":{"uri":"logdump://stream/api"}}`+"\n")
+	next(t, messages)
+	add("api", "after unsubscribing")
+	select {
+	case m := <-messages:
+		t.Errorf("got %s after unsubscribing", m.line)
+	case <-time.After(2 * subscriptionInterval):
+	}
+}
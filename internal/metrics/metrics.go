@@ -0,0 +1,135 @@
+// Package metrics serves logdump's stream, buffer and MCP counters in the
+// Prometheus text exposition format.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/appgram/logdump/internal/logtail"
+	"github.com/appgram/logdump/internal/mcp"
+)
+
+// Handler returns the /metrics handler for manager and, unless it is nil,
+// server. Every scrape reads the counters afresh, so there is nothing to
+// register and any number of handlers may share a process.
+func Handler(manager *logtail.Manager, server *mcp.Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		write(w, manager, server)
+	})
+}
+
+// Serve serves Handler at /metrics on addr until ctx is cancelled. The
+// address is bound before Serve returns, so a bad one is reported at once;
+// errors after that are sent on the returned channel, which is closed once
+// the server has stopped.
+func Serve(ctx context.Context, addr string, manager *logtail.Manager, server *mcp.Server) (<-chan error, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", Handler(manager, server))
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- err
+		}
+	}()
+	context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	})
+	return errs, nil
+}
+
+func write(w io.Writer, manager *logtail.Manager, server *mcp.Server) {
+	stats := manager.Stats()
+	names := make([]string, 0, len(stats.Streams))
+	for name := range stats.Streams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	family(w, "logdump_lines_read_total", "counter", "Lines read, by stream.")
+	for _, name := range names {
+		sample(w, "logdump_lines_read_total", stats.Streams[name].LinesRead, "stream", name)
+	}
+	family(w, "logdump_bytes_read_total", "counter", "Bytes read, by stream.")
+	for _, name := range names {
+		sample(w, "logdump_bytes_read_total", stats.Streams[name].BytesRead, "stream", name)
+	}
+	family(w, "logdump_entries_dropped_total", "counter",
+		"Entries discarded before buffering, by stream and reason: sampling or rate limits, overflow, or include/exclude patterns.")
+	for _, name := range names {
+		st := stats.Streams[name]
+		sample(w, "logdump_entries_dropped_total", st.Dropped, "stream", name, "reason", "rate_limit")
+		sample(w, "logdump_entries_dropped_total", st.Overflowed, "stream", name, "reason", "overflow")
+		sample(w, "logdump_entries_dropped_total", st.Excluded, "stream", name, "reason", "excluded")
+	}
+	family(w, "logdump_entries_evicted_total", "counter", "Entries evicted from the buffer to stay within its limits, by stream.")
+	for _, name := range names {
+		sample(w, "logdump_entries_evicted_total", stats.Streams[name].Evicted, "stream", name)
+	}
+	family(w, "logdump_subscriber_drops_total", "counter", "Entries a slow consumer missed because its queue was full.")
+	sample(w, "logdump_subscriber_drops_total", stats.SubscriberDrops)
+
+	family(w, "logdump_buffer_entries", "gauge", "Entries held in the buffer.")
+	sample(w, "logdump_buffer_entries", int64(manager.BufferLen()))
+	family(w, "logdump_buffer_memory_bytes", "gauge", "Estimated memory held by the buffered entries.")
+	sample(w, "logdump_buffer_memory_bytes", stats.BufferMemory)
+
+	if stats.Sink != nil {
+		family(w, "logdump_sink_bytes_written_total", "counter", "Bytes written to the sink file.")
+		sample(w, "logdump_sink_bytes_written_total", stats.Sink.BytesWritten)
+	}
+
+	if server == nil {
+		return
+	}
+	calls := server.ToolCalls()
+	tools := make([]string, 0, len(calls))
+	for tool := range calls {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	family(w, "logdump_mcp_tool_calls_total", "counter", "MCP tool calls, by tool.")
+	for _, tool := range tools {
+		sample(w, "logdump_mcp_tool_calls_total", calls[tool], "tool", tool)
+	}
+	family(w, "logdump_mcp_websocket_clients", "gauge", "Open MCP websocket connections.")
+	sample(w, "logdump_mcp_websocket_clients", int64(server.WebsocketClients()))
+}
+
+// family writes the HELP and TYPE lines of a metric.
+func family(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// sample writes one value of a metric, labels being name, value pairs.
+func sample(w io.Writer, name string, value int64, labels ...string) {
+	if len(labels) == 0 {
+		fmt.Fprintf(w, "%s %d\n", name, value)
+		return
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", labels[i], labelEscaper.Replace(labels[i+1])))
+	}
+	fmt.Fprintf(w, "%s{%s} %d\n", name, strings.Join(pairs, ","), value)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
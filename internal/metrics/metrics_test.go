@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/appgram/logdump/internal/config"
+	"github.com/appgram/logdump/internal/logtail"
+	"github.com/appgram/logdump/internal/mcp"
+	"github.com/gorilla/websocket"
+)
+
+// scrape fetches url and returns its samples by metric name and labels,
+// as in logdump_lines_read_total{stream="app"}.
+func scrape(t *testing.T, url string) map[string]int64 {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type is %q", ct)
+	}
+
+	samples := make(map[string]int64)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		value, err := strconv.ParseInt(line[i+1:], 10, 64)
+		if err != nil {
+			t.Fatalf("bad sample %q: %v", line, err)
+		}
+		samples[line[:i]] = value
+	}
+	return samples
+}
+
+// freeAddr returns a loopback address with a port nothing listens on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// waitFor polls cond until it holds, failing the test after five seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestScrapeFollowsStreams(t *testing.T) {
+	manager := logtail.NewManager()
+	manager.StartBuffering()
+	t.Cleanup(func() {
+		manager.Close()
+		manager.Wait()
+	})
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, []byte("one\ntwo\nthree\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.Tail(config.StreamConfig{Name: "app", Path: dir, Patterns: []string{"app.log"}}); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(Handler(manager, nil))
+	defer srv.Close()
+
+	waitFor(t, "the history to be buffered", func() bool { return manager.BufferLen() == 3 })
+	samples := scrape(t, srv.URL)
+	if got := samples[`logdump_lines_read_total{stream="app"}`]; got != 3 {
+		t.Errorf("lines read %d, want 3", got)
+	}
+	if got := samples[`logdump_bytes_read_total{stream="app"}`]; got != 14 {
+		t.Errorf("bytes read %d, want 14", got)
+	}
+
+	// The counters move as lines are appended
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("four\nfive\n")
+	f.Close()
+	waitFor(t, "the new lines to be buffered", func() bool { return manager.BufferLen() == 5 })
+	samples = scrape(t, srv.URL)
+	for name, want := range map[string]int64{
+		`logdump_lines_read_total{stream="app"}`:                        5,
+		`logdump_bytes_read_total{stream="app"}`:                        24,
+		`logdump_buffer_entries`:                                        5,
+		`logdump_entries_dropped_total{stream="app",reason="overflow"}`: 0,
+	} {
+		if got, ok := samples[name]; !ok || got != want {
+			t.Errorf("%s is %d (present %v), want %d", name, got, ok, want)
+		}
+	}
+	if _, ok := samples["logdump_mcp_websocket_clients"]; ok {
+		t.Error("MCP metrics served without an MCP server")
+	}
+}
+
+func TestScrapeFollowsMCP(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	manager := logtail.NewManager()
+	manager.StartBuffering()
+	t.Cleanup(func() {
+		manager.Close()
+		manager.Wait()
+	})
+	server := mcp.NewServer(manager, &config.Config{})
+
+	// Metrics served next to the websocket transport, as with -mcp-addr
+	// and -metrics-addr in one process
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, metricsAddr := freeAddr(t), freeAddr(t)
+	wsDone := make(chan error, 1)
+	go func() { wsDone <- server.RunWebsocket(ctx, addr) }()
+	errs, err := Serve(ctx, metricsAddr, manager, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cancel()
+		<-wsDone
+		for err := range errs {
+			t.Errorf("metrics server: %v", err)
+		}
+	}()
+
+	var conn *websocket.Conn
+	waitFor(t, "the websocket server", func() bool {
+		conn, _, err = websocket.DefaultDialer.Dial("ws://"+addr+"/", nil)
+		return err == nil
+	})
+	defer conn.Close()
+	for range 2 {
+		if err := conn.WriteJSON(map[string]interface{}{
+			"jsonrpc": "2.0", "id": 1, "method": "tools/call",
+			"params": map[string]interface{}{"name": "logdump_stats"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		var resp map[string]interface{}
+		if err := conn.ReadJSON(&resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	samples := scrape(t, "http://"+metricsAddr+"/metrics")
+	if got := samples[`logdump_mcp_tool_calls_total{tool="logdump_stats"}`]; got != 2 {
+		t.Errorf("logdump_stats calls %d, want 2", got)
+	}
+	if got := samples["logdump_mcp_websocket_clients"]; got != 1 {
+		t.Errorf("websocket clients %d, want 1", got)
+	}
+}
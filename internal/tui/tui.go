@@ -1,27 +1,36 @@
 package tui
 
 import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"regexp"
+	"regexp/syntax"
+	"slices"
+	"sort"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/appgram/logdump/internal/config"
 	"github.com/appgram/logdump/internal/logtail"
+	"github.com/appgram/logdump/internal/mcp"
 )
 
 var (
-	headerBg    = lipgloss.NewStyle().Background(lipgloss.Color("#3d3d5c")).Foreground(lipgloss.Color("#ffffff"))
-	headerCell  = lipgloss.NewStyle().Width(13).Background(lipgloss.Color("#3d3d5c")).Foreground(lipgloss.Color("#ffffff")).Bold(true).Align(lipgloss.Center)
 	borderStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("#4d4d6a"))
 	helpBar     = lipgloss.NewStyle().Background(lipgloss.Color("#2d2d44")).Foreground(lipgloss.Color("#888888")).Padding(0, 1)
-	titleStyle  = lipgloss.NewStyle().Background(lipgloss.Color("#00d9ff")).Foreground(lipgloss.Color("#1a1a2e")).Bold(true).Padding(0, 1)
 
 	errorColor   = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff5555"))
 	cyanColor    = lipgloss.NewStyle().Foreground(lipgloss.Color("#00d9ff"))
@@ -42,22 +51,76 @@ var (
 	teeBoth  = "┼"
 )
 
+// LogEntry is a logtail.LogEntry as the TUI keeps it. Timestamp stays a
+// time.Time and is only formatted when rendered, so times can be compared
+// and shown in other forms.
 type LogEntry struct {
-	Timestamp  string
+	Timestamp  time.Time
 	Source     string
 	Content    string
 	Tags       []string
 	LineNumber int
+	Fields     map[string]string
+	Level      string
+	Heartbeat  bool // logdump's own status entry, shown whatever the filters
+	// RepeatCount is how many identical lines the entry stands for, 0 if
+	// it was not collapsed.
+	RepeatCount int
+	Truncated   bool // the line was cut at the stream's max_line_length
+	// Highlight and HighlightColor are the name and color of the filter
+	// that highlighted the entry, "" if none did.
+	Highlight      string
+	HighlightColor string
+}
+
+func newLogEntry(entry logtail.LogEntry) LogEntry {
+	return LogEntry{
+		Timestamp:   entry.Timestamp,
+		Source:      entry.Source,
+		Content:     entry.Content,
+		Tags:        entry.Tags,
+		LineNumber:  entry.LineNumber,
+		Fields:      entry.Fields,
+		Level:       entry.Level,
+		Heartbeat:   entry.IsHeartbeat(),
+		RepeatCount: entry.RepeatCount,
+		Truncated:   entry.Truncated,
+
+		Highlight:      entry.Highlight,
+		HighlightColor: entry.HighlightColor,
+	}
+}
+
+// ActivitySource provides recent MCP agent accesses for the activity panel.
+// It is only set when the MCP server runs in the same process as the TUI.
+type ActivitySource interface {
+	RecentAccess(limit int) []mcp.AgentAccess
 }
 
 type Model struct {
 	manager         *logtail.Manager
+	entries         <-chan logtail.LogEntry
+	activity        ActivitySource
+	reload          func() ConfigReloadedMsg // rereads the config on Ctrl+r, nil if it cannot
 	config          *config.Config
 	viewport        viewport.Model
-	logBuffer       []LogEntry
+	logBuffer       *logtail.Ring[LogEntry]
+	bufferSize      int
+	queued          int    // entries still waiting after the last tick
+	minLevel        string // hide entries below this level, "" shows all
+	watchStream     string // stream whose newest line is pinned above the footer
+	watchEntry      *LogEntry
+	colorByLevel    bool      // color content by level instead of by stream
+	notice          string    // short confirmation shown in the footer
+	noticeUntil     time.Time // when notice stops being shown
+	noticeErr       bool      // notice reports a failure
+	output          []string  // printed once the TUI exits, when the clipboard is unavailable
 	filteredBuffer  []LogEntry
 	searchQuery     string
 	searchMode      bool
+	searchRe        *regexp.Regexp // compiled searchQuery, nil when not searching
+	searchRegex     bool           // treat searchQuery as a regex rather than literal text
+	searchErr       error          // why searchQuery does not compile, shown in the footer
 	streams         []string
 	selectedStreams map[string]bool
 	width           int
@@ -68,12 +131,50 @@ type Model struct {
 	selectedIdx     int
 	detailMode      bool
 	reverseOrder    bool
+	rankResults     bool // order search results by relevance rather than time
 	showStreamList  bool
+	streamIdx       int // highlighted stream in the stream list
+	groups          []groupFilter
+	styles          styles
+	group           *groupFilter // show only lines of this group, nil for all
+	showGroupList   bool
+	groupIdx        int // highlighted row in the group list, 0 being all lines
+	showFilterList  bool
+	filterIdx       int // highlighted filter in the filter list
+	showActivity    bool
+	showHelp        bool
+	helpOffset      int // first help line shown
 	confirmDelete   bool
 	splashScreen    bool
 	asciiArt        string
 }
 
+// styles are the Model's styles that follow the config's theme.
+type styles struct {
+	header     lipgloss.Style // title and table header bars
+	headerCell lipgloss.Style // the timestamp column heading
+	title      lipgloss.Style
+	accent     lipgloss.Style // headings, labels and the selection marker
+	text       lipgloss.Style // values and other plain text
+}
+
+// newStyles derives styles from theme: background and foreground color the
+// header bars, accent the title and headings, and foreground the text.
+// Colors theme leaves unset keep their defaults.
+func newStyles(theme config.ThemeConfig) styles {
+	bg := themeColor(theme.Background, "#3d3d5c")
+	fg := themeColor(theme.Foreground, "#ffffff")
+	accent := themeColor(theme.Accent, "#00d9ff")
+	header := lipgloss.NewStyle().Background(bg).Foreground(fg)
+	return styles{
+		header:     header,
+		headerCell: header.Width(13).Bold(true).Align(lipgloss.Center),
+		title:      lipgloss.NewStyle().Background(accent).Foreground(themeColor(theme.Background, "#1a1a2e")).Bold(true).Padding(0, 1),
+		accent:     lipgloss.NewStyle().Foreground(accent),
+		text:       lipgloss.NewStyle().Foreground(themeColor(theme.Foreground, "#e0e0e0")),
+	}
+}
+
 func New(manager *logtail.Manager, cfg *config.Config) *Model {
 	vp := viewport.New(80, 20)
 	vp.Style = lipgloss.NewStyle()
@@ -87,17 +188,169 @@ func New(manager *logtail.Manager, cfg *config.Config) *Model {
 
 	asciiArt := loadASCIIArt()
 
+	bufferSize := cfg.EntryLimit()
+
 	return &Model{
 		manager:         manager,
+		entries:         manager.Subscribe(context.Background()),
 		config:          cfg,
 		viewport:        vp,
-		logBuffer:       make([]LogEntry, 0, 1000),
-		filteredBuffer:  make([]LogEntry, 0, 1000),
+		logBuffer:       logtail.NewRing[LogEntry](bufferSize),
+		bufferSize:      bufferSize,
+		filteredBuffer:  make([]LogEntry, 0),
 		streams:         streams,
 		selectedStreams: selectedStreams,
 		autoScroll:      true,
 		splashScreen:    true,
 		asciiArt:        asciiArt,
+		colorByLevel:    cfg.Theme.ColorBy == "level",
+		groups:          compileGroups(cfg.Groups),
+		styles:          newStyles(cfg.Theme),
+	}
+}
+
+// groupFilter is a configured group with its pattern compiled, matching
+// lines of its streams, or of every stream if it names none.
+type groupFilter struct {
+	name    string
+	pattern string
+	re      *regexp.Regexp // nil matches every line
+	streams map[string]bool
+}
+
+// compileGroups compiles the groups' patterns case-insensitively, as the
+// MCP group argument does. Groups with an invalid pattern are left out.
+func compileGroups(groups []config.GroupConfig) []groupFilter {
+	var filters []groupFilter
+	for _, g := range groups {
+		filter := groupFilter{name: g.Name, pattern: g.Pattern, streams: make(map[string]bool)}
+		if g.Pattern != "" {
+			re, err := regexp.Compile("(?i)" + g.Pattern)
+			if err != nil {
+				continue
+			}
+			filter.re = re
+		}
+		for _, s := range g.Streams {
+			filter.streams[s] = true
+		}
+		filters = append(filters, filter)
+	}
+	return filters
+}
+
+func (g *groupFilter) matches(entry LogEntry) bool {
+	return (len(g.streams) == 0 || g.streams[entry.Source]) &&
+		(g.re == nil || g.re.MatchString(entry.Content))
+}
+
+// SetActivitySource enables the agent activity panel, fed from source.
+func (m *Model) SetActivitySource(source ActivitySource) {
+	m.activity = source
+}
+
+// ConfigReloadedMsg reports a reload of the config file. The manager has
+// already been brought in line with the new streams and filters; the
+// model takes its groups, theme and stream colors.
+type ConfigReloadedMsg struct {
+	Config *config.Config // nil if the file could not be read
+	// Added, Removed and Restarted name the streams tailed, stopped and
+	// tailed again with new settings.
+	Added     []string
+	Removed   []string
+	Restarted []string
+	Err       error // why the reload failed, or which part of it did
+}
+
+// Summary describes the reload in one line.
+func (msg ConfigReloadedMsg) Summary() string {
+	if msg.Config == nil {
+		return "Config reload failed: " + oneLine(msg.Err)
+	}
+	var changes []string
+	if len(msg.Added) > 0 {
+		changes = append(changes, "added "+strings.Join(msg.Added, ", "))
+	}
+	if len(msg.Removed) > 0 {
+		changes = append(changes, "removed "+strings.Join(msg.Removed, ", "))
+	}
+	if len(msg.Restarted) > 0 {
+		changes = append(changes, "restarted "+strings.Join(msg.Restarted, ", "))
+	}
+	summary := "Config reloaded, streams unchanged"
+	if len(changes) > 0 {
+		summary = "Config reloaded: " + strings.Join(changes, "; ")
+	}
+	if msg.Err != nil {
+		summary += " (" + oneLine(msg.Err) + ")"
+	}
+	return summary
+}
+
+// oneLine joins the lines of err's message, as errors.Join puts each
+// error on its own.
+func oneLine(err error) string {
+	return strings.ReplaceAll(err.Error(), "\n", "; ")
+}
+
+// SetReloader enables reloading the config with Ctrl+r. reload runs in
+// the background and its result is handled as if sent to the program.
+func (m *Model) SetReloader(reload func() ConfigReloadedMsg) {
+	m.reload = reload
+}
+
+// applyConfig takes the groups, theme and stream colors of a reloaded
+// config, updates the stream list and reports the changes in the footer.
+func (m *Model) applyConfig(msg ConfigReloadedMsg) {
+	if msg.Config == nil {
+		m.setError(msg.Summary())
+		return
+	}
+
+	if msg.Config.Theme.ColorBy != m.config.Theme.ColorBy {
+		m.colorByLevel = msg.Config.Theme.ColorBy == "level"
+	}
+	m.config = msg.Config
+	m.styles = newStyles(msg.Config.Theme)
+
+	m.groups = compileGroups(msg.Config.Groups)
+	if m.group != nil {
+		name := m.group.name
+		m.group = nil
+		for i := range m.groups {
+			if m.groups[i].name == name {
+				m.group = &m.groups[i]
+			}
+		}
+	}
+	m.groupIdx = min(m.groupIdx, len(m.groups))
+	m.showGroupList = m.showGroupList && len(m.groups) > 0
+	m.filterIdx = min(m.filterIdx, max(0, len(m.manager.Filters())-1))
+
+	for _, name := range msg.Removed {
+		m.streams = slices.DeleteFunc(m.streams, func(s string) bool { return s == name })
+		delete(m.selectedStreams, name)
+		if m.watchStream == name {
+			m.watchStream = ""
+			m.watchEntry = nil
+			m.resizeViewport()
+		}
+	}
+	for _, name := range msg.Added {
+		if !slices.Contains(m.streams, name) {
+			m.streams = append(m.streams, name)
+			m.selectedStreams[name] = true
+		}
+	}
+	m.streamIdx = min(m.streamIdx, max(0, len(m.streams)-1))
+
+	m.applyFilters()
+	m.selectedIdx = min(m.selectedIdx, max(0, len(m.filteredBuffer)-1))
+	m.viewport.SetContent(m.renderTable())
+	if msg.Err != nil {
+		m.setError(msg.Summary())
+	} else {
+		m.setNotice(msg.Summary())
 	}
 }
 
@@ -126,7 +379,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 		m.viewport.Width = msg.Width - 4
-		m.viewport.Height = msg.Height - 8
+		m.resizeViewport()
 		m.viewport.SetContent(m.renderTable())
 
 	case splashTimeoutMsg:
@@ -141,11 +394,16 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "esc":
 				m.searchMode = false
 				m.searchQuery = ""
-				m.filteredBuffer = m.logBuffer
+				m.searchRe = nil
+				m.searchErr = nil
+				m.applyFilters()
 				m.viewport.SetContent(m.renderTable())
 			case "enter":
 				m.searchMode = false
 				m.applySearch(m.searchQuery)
+			case "tab":
+				m.searchRegex = !m.searchRegex
+				m.applySearch(m.searchQuery)
 			case "backspace":
 				if len(m.searchQuery) > 0 {
 					m.searchQuery = m.searchQuery[:len(m.searchQuery)-1]
@@ -160,13 +418,36 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		if m.showHelp {
+			switch msg.String() {
+			case "q", "ctrl+c":
+				return m, tea.Quit
+			case "?", "esc":
+				m.showHelp = false
+			case "up", "k":
+				m.helpOffset = max(0, m.helpOffset-1)
+			case "down", "j":
+				m.helpOffset = min(m.helpOffset+1, m.maxHelpOffset())
+			case "pgup", "ctrl+u":
+				m.helpOffset = max(0, m.helpOffset-m.helpHeight())
+			case "pgdown", "ctrl+d":
+				m.helpOffset = min(m.helpOffset+m.helpHeight(), m.maxHelpOffset())
+			case "home", "g":
+				m.helpOffset = 0
+			case "end", "G":
+				m.helpOffset = m.maxHelpOffset()
+			}
+			return m, nil
+		}
+
 		// Normal mode key handling
 		switch msg.String() {
 		case "q", "ctrl+c":
 			return m, tea.Quit
 
 		case "?":
-			m.viewport.SetContent(m.renderTable())
+			m.showHelp = true
+			m.helpOffset = 0
 
 		case "/":
 			m.searchMode = true
@@ -174,21 +455,28 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "esc":
 			if m.confirmDelete {
 				m.confirmDelete = false
+			} else if m.showGroupList {
+				m.showGroupList = false
+			} else if m.showFilterList {
+				m.showFilterList = false
 			} else if m.detailMode {
 				m.detailMode = false
 				m.viewport.SetContent(m.renderTable())
 			} else if m.showStreamList {
 				m.showStreamList = false
+			} else if m.showActivity {
+				m.showActivity = false
 			}
 
 		case "enter":
 			if m.confirmDelete {
-				m.deleteLogFiles()
+				m.clearDeleted(m.deleteLogFiles())
 				m.confirmDelete = false
-				m.logBuffer = make([]LogEntry, 0, 1000)
-				m.filteredBuffer = m.logBuffer
-				m.scrollOffset = 0
 				m.viewport.SetContent(m.renderTable())
+			} else if m.showGroupList {
+				m.selectGroup()
+			} else if m.showFilterList {
+				m.toggleFilter()
 			} else if len(m.filteredBuffer) > 0 && m.selectedIdx < len(m.filteredBuffer) {
 				m.detailMode = !m.detailMode
 			}
@@ -197,7 +485,13 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.confirmDelete = true
 
 		case "up", "k":
-			if m.selectedIdx > 0 {
+			if m.showGroupList {
+				m.groupIdx = max(0, m.groupIdx-1)
+			} else if m.showFilterList {
+				m.filterIdx = max(0, m.filterIdx-1)
+			} else if m.showStreamList {
+				m.streamIdx = max(0, m.streamIdx-1)
+			} else if m.selectedIdx > 0 {
 				m.selectedIdx--
 				// Scroll up if selection goes above visible area
 				if m.selectedIdx < m.scrollOffset {
@@ -208,7 +502,13 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 		case "down", "j":
-			if m.selectedIdx < len(m.filteredBuffer)-1 {
+			if m.showGroupList {
+				m.groupIdx = min(len(m.groups), m.groupIdx+1)
+			} else if m.showFilterList {
+				m.filterIdx = min(max(0, len(m.manager.Filters())-1), m.filterIdx+1)
+			} else if m.showStreamList {
+				m.streamIdx = min(max(0, len(m.streams)-1), m.streamIdx+1)
+			} else if m.selectedIdx < len(m.filteredBuffer)-1 {
 				m.selectedIdx++
 				// Scroll down if selection goes below visible area
 				visibleEnd := m.scrollOffset + m.viewport.Height - 1
@@ -227,7 +527,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.autoScroll = false // disable auto-scroll when scrolling up
 			m.viewport.SetContent(m.renderTable())
 
-		case "pgdn", "ctrl+d":
+		case "pgdown", "ctrl+d":
 			maxScroll := max(0, len(m.filteredBuffer)-m.viewport.Height)
 			m.scrollOffset = min(m.scrollOffset+m.viewport.Height, maxScroll)
 			// re-enable auto-scroll if at bottom
@@ -247,16 +547,33 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.viewport.SetContent(m.renderTable())
 
 		case "c":
-			m.logBuffer = make([]LogEntry, 0, 1000)
-			m.filteredBuffer = m.logBuffer
+			m.logBuffer.Clear()
+			m.filteredBuffer = make([]LogEntry, 0)
 			m.scrollOffset = 0
 			m.viewport.SetContent(m.renderTable())
 
 		case "p", " ":
-			m.paused = !m.paused
+			if m.showFilterList && msg.String() == " " {
+				m.toggleFilter()
+			} else {
+				m.paused = !m.paused
+			}
 
 		case "r":
 			m.reverseOrder = !m.reverseOrder
+			if m.ranked() {
+				m.applyFilters()
+			}
+			m.scrollOffset = 0
+			m.selectedIdx = 0
+			m.viewport.SetContent(m.renderTable())
+
+		case "R":
+			m.rankResults = !m.rankResults
+			if m.rankResults && m.searchRe == nil {
+				m.setNotice("Search results will be ranked by relevance")
+			}
+			m.applyFilters()
 			m.scrollOffset = 0
 			m.selectedIdx = 0
 			m.viewport.SetContent(m.renderTable())
@@ -286,8 +603,70 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case "s":
 			m.showStreamList = !m.showStreamList
+
+		case "f":
+			if len(m.groups) == 0 {
+				m.setNotice("No groups configured")
+			} else {
+				m.showGroupList = !m.showGroupList
+			}
+
+		case "F":
+			if len(m.manager.Filters()) == 0 {
+				m.setNotice("No filters configured")
+			} else {
+				m.showFilterList = !m.showFilterList
+			}
+
+		case "x":
+			if m.showStreamList {
+				m.removeStream()
+			}
+
+		case "L":
+			m.minLevel = nextLevel(m.minLevel)
+			m.applyFilters()
+			m.viewport.SetContent(m.renderTable())
+
+		case "C":
+			m.colorByLevel = !m.colorByLevel
+			m.viewport.SetContent(m.renderTable())
+
+		case "w":
+			m.cycleWatchStream()
+			m.viewport.SetContent(m.renderTable())
+
+		case "y":
+			if m.showStreamList {
+				m.copyStreamConfig()
+			} else {
+				m.copySelected(false)
+			}
+
+		case "Y":
+			m.copySelected(true)
+
+		case "e":
+			m.exportView(false)
+
+		case "E":
+			m.exportView(true)
+
+		case "A":
+			if m.activity != nil {
+				m.showActivity = !m.showActivity
+			}
+
+		case "ctrl+r":
+			if m.reload != nil {
+				m.setNotice("Reloading config…")
+				return m, func() tea.Msg { return m.reload() }
+			}
 		}
 
+	case ConfigReloadedMsg:
+		m.applyConfig(msg)
+
 	case tickMsg:
 		if !m.paused {
 			m.updateLogs()
@@ -307,6 +686,10 @@ func (m *Model) View() string {
 		return m.renderSplashScreen()
 	}
 
+	if m.showHelp {
+		return m.renderHelp()
+	}
+
 	if m.confirmDelete {
 		return m.renderDeleteConfirm()
 	}
@@ -315,13 +698,35 @@ func (m *Model) View() string {
 		return m.renderDetailView()
 	}
 
+	if m.showGroupList {
+		return m.renderGroupList()
+	}
+
+	if m.showFilterList {
+		return m.renderFilterList()
+	}
+
 	if m.showStreamList {
 		return m.renderStreamList()
 	}
 
+	if m.showActivity && m.activity != nil {
+		return m.renderActivityPanel()
+	}
+
 	table := m.renderTable()
 	footer := m.renderFooter()
 
+	if m.watchStream != "" {
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			m.renderTitleBar(),
+			borderStyle.Render(table),
+			m.renderWatchLine(),
+			footer,
+		)
+	}
+
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
 		m.renderTitleBar(),
@@ -330,6 +735,53 @@ func (m *Model) View() string {
 	)
 }
 
+// resizeViewport fits the log table between the title bar, the watch
+// line when one is shown, and the footer.
+func (m *Model) resizeViewport() {
+	m.viewport.Height = m.height - 8
+	if m.watchStream != "" {
+		m.viewport.Height--
+	}
+}
+
+// cycleWatchStream pins the next stream's newest line, or none after the
+// last stream.
+func (m *Model) cycleWatchStream() {
+	next := ""
+	if i := slices.Index(m.streams, m.watchStream); i+1 < len(m.streams) {
+		next = m.streams[i+1]
+	}
+	m.watchStream = next
+	m.watchEntry = nil
+
+	if next != "" {
+		m.logBuffer.Reverse(func(entry LogEntry) bool {
+			if entry.Source != next {
+				return true
+			}
+			m.watchEntry = &entry
+			return false
+		})
+	}
+	m.resizeViewport()
+}
+
+// renderWatchLine shows the newest line of the watched stream, whatever
+// the scroll position and filters.
+func (m *Model) renderWatchLine() string {
+	label := m.sourceColor(m.watchStream).Render("▶ " + m.watchStream + " ")
+	text := grayColor.Render("waiting for lines…")
+	if m.watchEntry != nil {
+		first, _, _ := strings.Cut(m.watchEntry.Content, "\n")
+		width := max(0, m.width-lipgloss.Width(label)-17)
+		if len([]rune(first)) > width {
+			first = string([]rune(first)[:width])
+		}
+		text = grayColor.Render(m.watchEntry.Timestamp.Format("15:04:05.000")) + " " + m.styles.text.Render(first)
+	}
+	return helpBar.Width(m.width).Render(label + text)
+}
+
 func (m *Model) renderSplashScreen() string {
 	lines := strings.Split(m.asciiArt, "\n")
 
@@ -354,7 +806,7 @@ func (m *Model) renderSplashScreen() string {
 			spaces = 0
 		}
 		content.WriteString(strings.Repeat(" ", spaces))
-		content.WriteString(cyanColor.Render(line))
+		content.WriteString(m.styles.accent.Render(line))
 		content.WriteString("\n")
 	}
 
@@ -373,14 +825,43 @@ func (m *Model) renderSplashScreen() string {
 }
 
 func (m *Model) renderStreamList() string {
-	title := titleStyle.Render(" STREAMS ")
-	header := headerBg.Width(m.width).Render(title + strings.Repeat(" ", max(0, m.width-lipgloss.Width(title))))
+	title := m.styles.title.Render(" STREAMS ")
+	header := m.styles.header.Width(m.width).Render(title + strings.Repeat(" ", max(0, m.width-lipgloss.Width(title))))
 
 	var content strings.Builder
 	content.WriteString("\n")
-	content.WriteString(cyanColor.Render("  Press number key to toggle stream on/off:\n\n"))
+	content.WriteString(m.styles.accent.Render("  Press number key to toggle stream on/off:\n\n"))
+
+	stats := m.manager.Stats().Streams
+	dropped := make(map[string]int64)
+	historyRead := make(map[string]int64)
+	historySize := make(map[string]int64)
+	commands := make(map[string]string)
+	health := make(map[string]string)
+	for path, stream := range m.manager.GetStreams() {
+		if stream.WatchMode == logtail.WatchCommand {
+			pid, restarts := stream.Process()
+			commands[stream.Config.Name] = fmt.Sprintf("%s  pid %d, %d restarts", path, pid, restarts)
+		}
+		if stream.WatchMode == logtail.WatchSyslog {
+			status, ok := stream.Health()
+			commands[stream.Config.Name] = path
+			if !ok {
+				health[stream.Config.Name] = status
+			}
+		}
+		dropped[stream.Config.Name] += stream.Dropped()
+		if read, total, done := stream.HistoryProgress(); !done {
+			historyRead[stream.Config.Name] += read
+			historySize[stream.Config.Name] += total
+		}
+	}
 
 	for i, s := range m.streams {
+		cursor := "  "
+		if i == m.streamIdx {
+			cursor = m.styles.accent.Render("> ")
+		}
 		var indicator string
 		var status string
 		if m.selectedStreams[s] {
@@ -392,28 +873,253 @@ func (m *Model) renderStreamList() string {
 		}
 
 		keyNum := i + 1
-		keyStyle := cyanColor.Bold(true)
+		keyStyle := m.styles.accent.Bold(true)
 		if keyNum > 9 {
 			keyStyle = grayColor // Can't toggle with single key
 		}
 
-		line := fmt.Sprintf("  %s  %s %s  %s\n",
+		line := fmt.Sprintf("%s%s  %s %s  %s",
+			cursor,
 			keyStyle.Render(fmt.Sprintf("[%d]", keyNum)),
 			indicator,
 			status,
 			m.sourceColor(s).Render(s))
-		content.WriteString(line)
+		if st, ok := stats[s]; ok {
+			line += grayColor.Render(fmt.Sprintf("  %.1f/s", st.Rate10s))
+		}
+		if command, ok := commands[s]; ok {
+			line += grayColor.Render("  " + command)
+		}
+		if status, ok := health[s]; ok {
+			line += errorColor.Render("  " + status)
+		}
+		if n := dropped[s]; n > 0 {
+			line += yellowColor.Render(fmt.Sprintf("  (%d dropped)", n))
+		}
+		if st, ok := stats[s]; ok && st.Excluded > 0 {
+			line += grayColor.Render(fmt.Sprintf("  (%d excluded)", st.Excluded))
+		}
+		if total, ok := historySize[s]; ok {
+			line += grayColor.Render("  reading history " + formatProgress(historyRead[s], total))
+		}
+		content.WriteString(line + "\n")
+	}
+
+	content.WriteString("\n")
+	content.WriteString(grayColor.Render("  [a] Select all  [n] Select none  [↑/↓] Highlight  [x] Stop tailing  [y] Copy config  [ESC/s] Close\n"))
+
+	listBox := lipgloss.NewStyle().
+		Width(m.width - 4).
+		Height(m.height - 6).
+		Render(content.String())
+
+	footer := helpBar.Render(grayColor.Render(fmt.Sprintf("Total: %d streams", len(m.streams))) + m.renderNotice())
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		header,
+		borderStyle.Render(listBox),
+		footer,
+	)
+}
+
+func (m *Model) renderGroupList() string {
+	title := m.styles.title.Render(" GROUPS ")
+	header := m.styles.header.Width(m.width).Render(title + strings.Repeat(" ", max(0, m.width-lipgloss.Width(title))))
+
+	var content strings.Builder
+	content.WriteString("\n")
+	content.WriteString(m.styles.accent.Render("  Show only the lines of a group:\n\n"))
+
+	for i := 0; i <= len(m.groups); i++ {
+		cursor := "  "
+		if i == m.groupIdx {
+			cursor = m.styles.accent.Render("> ")
+		}
+		if i == 0 {
+			marker := grayColor.Render("○")
+			if m.group == nil {
+				marker = greenColor.Render("●")
+			}
+			content.WriteString(fmt.Sprintf("%s%s  %s\n", cursor, marker, m.styles.text.Render("All lines")))
+			continue
+		}
+
+		g := &m.groups[i-1]
+		marker := grayColor.Render("○")
+		if m.group != nil && m.group.name == g.name {
+			marker = greenColor.Render("●")
+		}
+		line := fmt.Sprintf("%s%s  %s", cursor, marker, m.styles.text.Render(g.name))
+		if g.pattern != "" {
+			line += grayColor.Render("  /" + g.pattern + "/")
+		}
+		if len(g.streams) > 0 {
+			streams := slices.Sorted(maps.Keys(g.streams))
+			line += grayColor.Render("  in " + strings.Join(streams, ", "))
+		}
+		content.WriteString(line + "\n")
 	}
 
 	content.WriteString("\n")
-	content.WriteString(grayColor.Render("  [a] Select all  [n] Select none  [ESC/s] Close\n"))
+	content.WriteString(grayColor.Render("  [↑/↓] Highlight  [Enter] Apply  [ESC/f] Close\n"))
+
+	listBox := lipgloss.NewStyle().
+		Width(m.width - 4).
+		Height(m.height - 6).
+		Render(content.String())
+
+	footer := helpBar.Render(grayColor.Render(fmt.Sprintf("Total: %d groups", len(m.groups))) + m.renderNotice())
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		header,
+		borderStyle.Render(listBox),
+		footer,
+	)
+}
+
+// selectGroup filters the view to the highlighted group, or clears the
+// group filter on the first row, and closes the list.
+func (m *Model) selectGroup() {
+	m.group = nil
+	if m.groupIdx > 0 && m.groupIdx <= len(m.groups) {
+		m.group = &m.groups[m.groupIdx-1]
+	}
+	m.showGroupList = false
+	m.applyFilters()
+	m.selectedIdx = min(m.selectedIdx, max(0, len(m.filteredBuffer)-1))
+	m.scrollOffset = min(m.scrollOffset, max(0, len(m.filteredBuffer)-m.viewport.Height))
+	m.viewport.SetContent(m.renderTable())
+}
+
+func (m *Model) renderFilterList() string {
+	title := m.styles.title.Render(" FILTERS ")
+	header := m.styles.header.Width(m.width).Render(title + strings.Repeat(" ", max(0, m.width-lipgloss.Width(title))))
+
+	var content strings.Builder
+	content.WriteString("\n")
+	content.WriteString(m.styles.accent.Render("  Filters apply to lines as they are read, not to those already shown:") + "\n\n")
+
+	filters := m.manager.Filters()
+	for i, f := range filters {
+		cursor := "  "
+		if i == m.filterIdx {
+			cursor = m.styles.accent.Render("> ")
+		}
+		indicator, status := grayColor.Render("○"), grayColor.Render("OFF")
+		if f.Enabled {
+			indicator, status = highlightColor(f.Color).Render("●"), greenColor.Render("ON ")
+		}
+		actions := f.Actions
+		if len(actions) == 0 {
+			actions = []string{logtail.FilterHighlight}
+		}
+		line := fmt.Sprintf("%s%s %s  %s", cursor, indicator, status, highlightColor(f.Color).Render(f.Name))
+		line += grayColor.Render(fmt.Sprintf("  /%s/  %s  %d matched", f.Pattern, strings.Join(actions, ", "), f.Matches))
+		content.WriteString(line + "\n")
+	}
+
+	content.WriteString("\n")
+	content.WriteString(grayColor.Render("  [↑/↓] Highlight  [Enter/Space] Turn on/off  [ESC/F] Close\n"))
+
+	listBox := lipgloss.NewStyle().
+		Width(m.width - 4).
+		Height(m.height - 6).
+		Render(content.String())
+
+	footer := helpBar.Render(grayColor.Render(fmt.Sprintf("Total: %d filters", len(filters))) + m.renderNotice())
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		header,
+		borderStyle.Render(listBox),
+		footer,
+	)
+}
+
+// toggleFilter turns the highlighted filter on or off.
+func (m *Model) toggleFilter() {
+	filters := m.manager.Filters()
+	if m.filterIdx >= len(filters) {
+		return
+	}
+	f := filters[m.filterIdx]
+	if err := m.manager.SetFilterEnabled(f.Name, !f.Enabled); err != nil {
+		m.setError(err.Error())
+		return
+	}
+	if f.Enabled {
+		m.setNotice("Filter " + f.Name + " off")
+	} else {
+		m.setNotice("Filter " + f.Name + " on")
+	}
+}
+
+// removeStream stops tailing the highlighted stream and drops it from the
+// list. Lines already shown are kept but hidden with the stream.
+func (m *Model) removeStream() {
+	if m.streamIdx >= len(m.streams) {
+		return
+	}
+	name := m.streams[m.streamIdx]
+	if _, err := m.manager.RemoveStream(name, false); err != nil {
+		m.setError(err.Error())
+		return
+	}
+
+	m.streams = slices.Delete(m.streams, m.streamIdx, m.streamIdx+1)
+	delete(m.selectedStreams, name)
+	if m.watchStream == name {
+		m.watchStream = ""
+		m.watchEntry = nil
+		m.resizeViewport()
+	}
+	m.streamIdx = min(m.streamIdx, max(0, len(m.streams)-1))
+	m.applyFilters()
+	m.viewport.SetContent(m.renderTable())
+	m.setNotice("Stopped tailing " + name)
+}
+
+func (m *Model) renderActivityPanel() string {
+	title := m.styles.title.Render(" AGENT ACTIVITY ")
+	header := m.styles.header.Width(m.width).Render(title + strings.Repeat(" ", max(0, m.width-lipgloss.Width(title))))
+
+	// Leave room for the border, footer and the intro line
+	visible := max(1, m.height-10)
+	accesses := m.activity.RecentAccess(visible)
+
+	var content strings.Builder
+	content.WriteString("\n")
+	content.WriteString(m.styles.accent.Render("  Recent MCP agent accesses (newest last):\n\n"))
+
+	if len(accesses) == 0 {
+		content.WriteString(grayColor.Render("  No agent activity yet\n"))
+	}
+
+	for _, a := range accesses {
+		target := a.Source
+		if target == "" {
+			target = "*"
+		}
+		line := fmt.Sprintf("  %s  %s  %s %s",
+			grayColor.Render(a.Timestamp.Format("15:04:05")),
+			magentaColor.Render(a.AgentID),
+			m.styles.accent.Render(a.Action),
+			m.styles.text.Render(target))
+		if a.Pattern != "" {
+			line += grayColor.Render(fmt.Sprintf(" /%s/", a.Pattern))
+		}
+		line += grayColor.Render(fmt.Sprintf(" → %d results", a.ResultCount))
+		content.WriteString(line + "\n")
+	}
 
 	listBox := lipgloss.NewStyle().
 		Width(m.width - 4).
 		Height(m.height - 6).
 		Render(content.String())
 
-	footer := helpBar.Render(grayColor.Render(fmt.Sprintf("Total: %d streams", len(m.streams))))
+	footer := helpBar.Render(grayColor.Render(fmt.Sprintf("Showing %d accesses  [ESC/A] Close", len(accesses))))
 
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
@@ -423,14 +1129,130 @@ func (m *Model) renderStreamList() string {
 	)
 }
 
+// keyBinding is a row of the help screen.
+type keyBinding struct {
+	keys        string
+	description string
+}
+
+// keyBindings are the keys the help screen lists, by category. Keep them in
+// step with Update.
+var keyBindings = []struct {
+	category string
+	bindings []keyBinding
+}{
+	{"Navigation", []keyBinding{
+		{"↑/↓, k/j", "Select the previous/next line, or row in a list"},
+		{"PgUp/PgDn, Ctrl+u/Ctrl+d", "Scroll a page up/down"},
+		{"Home/End, g/G", "Go to the top/bottom (bottom follows new lines)"},
+		{"Enter", "Show the selected line in detail"},
+		{"Esc", "Close the open view"},
+	}},
+	{"Search", []keyBinding{
+		{"/", "Search as you type"},
+		{"Tab", "While typing, switch between plain text and regex"},
+		{"Enter / Esc", "While typing, keep the search / clear it"},
+		{"R", "Rank search results by relevance instead of time"},
+	}},
+	{"Filters", []keyBinding{
+		{"1-9", "Show/hide a stream"},
+		{"a / n", "Show all streams / none"},
+		{"f", "Show only the lines of a group"},
+		{"F", "Turn config filters on/off (Enter or Space in the list)"},
+		{"L", "Cycle the minimum level: all, DEBUG … FATAL"},
+	}},
+	{"View", []keyBinding{
+		{"s", "List the streams"},
+		{"A", "Show agent activity (with -mcp-websocket)"},
+		{"w", "Pin the newest line of a stream (cycles streams, then off)"},
+		{"C", "Color lines by stream or by level"},
+		{"r", "Reverse the order"},
+		{"p, Space", "Pause/resume"},
+		{"c", "Clear the lines shown"},
+	}},
+	{"Actions", []keyBinding{
+		{"y / Y", "Copy the selected line (Y: with its timestamp and source)"},
+		{"e / E", "Export the filtered view as text / JSON"},
+		{"x", "In the stream list, stop tailing the highlighted stream"},
+		{"y", "In the stream list, copy the highlighted stream's config"},
+		{"D", "Clear the log files of the shown streams (asks first)"},
+		{"Ctrl+r", "Reload the config file (as does SIGHUP)"},
+		{"?", "Show/close this help"},
+		{"q, Ctrl+c", "Quit"},
+	}},
+}
+
+// helpLines renders keyBindings one line each, a heading per category.
+func (m *Model) helpLines() []string {
+	width := 0
+	for _, c := range keyBindings {
+		for _, b := range c.bindings {
+			width = max(width, lipgloss.Width(b.keys))
+		}
+	}
+
+	var lines []string
+	for i, c := range keyBindings {
+		if i > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, m.styles.accent.Render("  "+c.category))
+		for _, b := range c.bindings {
+			keys := b.keys + strings.Repeat(" ", width-lipgloss.Width(b.keys))
+			lines = append(lines, "    "+m.styles.text.Render(keys)+"  "+grayColor.Render(b.description))
+		}
+	}
+	return lines
+}
+
+// helpHeight is how many help lines fit on the screen.
+func (m *Model) helpHeight() int {
+	return max(1, m.height-8)
+}
+
+func (m *Model) maxHelpOffset() int {
+	return max(0, len(m.helpLines())-m.helpHeight())
+}
+
+func (m *Model) renderHelp() string {
+	title := m.styles.title.Render(" HELP ")
+	header := m.styles.header.Width(m.width).Render(title + strings.Repeat(" ", max(0, m.width-lipgloss.Width(title))))
+
+	lines := m.helpLines()
+	offset := min(m.helpOffset, m.maxHelpOffset())
+	end := min(len(lines), offset+m.helpHeight())
+
+	var content strings.Builder
+	content.WriteString("\n")
+	content.WriteString(strings.Join(lines[offset:end], "\n"))
+
+	helpBox := lipgloss.NewStyle().
+		Width(m.width - 4).
+		Height(m.height - 6).
+		Render(content.String())
+
+	hint := "[?/ESC] Close"
+	if len(lines) > m.helpHeight() {
+		hint = fmt.Sprintf("[↑/↓] Scroll  [?/ESC] Close  Lines %d-%d of %d", offset+1, end, len(lines))
+	}
+	footer := helpBar.Render(grayColor.Render(hint))
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		header,
+		borderStyle.Render(helpBox),
+		footer,
+	)
+}
+
 func (m *Model) renderDeleteConfirm() string {
-	title := titleStyle.Render(" DELETE LOGS ")
-	header := headerBg.Width(m.width).Render(title + strings.Repeat(" ", max(0, m.width-lipgloss.Width(title))))
+	title := m.styles.title.Render(" DELETE LOGS ")
+	header := m.styles.header.Width(m.width).Render(title + strings.Repeat(" ", max(0, m.width-lipgloss.Width(title))))
 
 	var content strings.Builder
 	content.WriteString("\n\n")
 	content.WriteString(errorColor.Render("  ⚠ WARNING: This will permanently delete log file contents!\n\n"))
-	content.WriteString(cyanColor.Render("  The following log files will be cleared:\n\n"))
+	content.WriteString(m.styles.accent.Render("  The following log files will be cleared:\n\n"))
 
 	for _, stream := range m.config.Streams {
 		if m.selectedStreams[stream.Name] {
@@ -439,7 +1261,7 @@ func (m *Model) renderDeleteConfirm() string {
 	}
 
 	content.WriteString("\n")
-	content.WriteString(whiteColor.Render("  Press ENTER to confirm, ESC to cancel\n"))
+	content.WriteString(m.styles.text.Render("  Press ENTER to confirm, ESC to cancel\n"))
 
 	confirmBox := lipgloss.NewStyle().
 		Width(m.width - 4).
@@ -459,26 +1281,51 @@ func (m *Model) renderDeleteConfirm() string {
 func (m *Model) renderDetailView() string {
 	entry := m.filteredBuffer[m.selectedIdx]
 
-	title := titleStyle.Render(" LOG DETAIL ")
-	header := headerBg.Width(m.width).Render(title + strings.Repeat(" ", max(0, m.width-lipgloss.Width(title))))
+	title := m.styles.title.Render(" LOG DETAIL ")
+	header := m.styles.header.Width(m.width).Render(title + strings.Repeat(" ", max(0, m.width-lipgloss.Width(title))))
 
 	// Build detail content
 	var content strings.Builder
 	content.WriteString("\n")
-	content.WriteString(cyanColor.Render("  Source:     ") + m.sourceColor(entry.Source).Render(entry.Source) + "\n")
-	content.WriteString(cyanColor.Render("  Timestamp:  ") + whiteColor.Render(entry.Timestamp) + "\n")
-	content.WriteString(cyanColor.Render("  Line:       ") + whiteColor.Render(fmt.Sprintf("%d", entry.LineNumber)) + "\n")
+	content.WriteString(m.styles.accent.Render("  Source:     ") + m.sourceColor(entry.Source).Render(entry.Source) + "\n")
+	content.WriteString(m.styles.accent.Render("  Timestamp:  ") + m.styles.text.Render(entry.Timestamp.Format(time.RFC3339Nano)) +
+		grayColor.Render(" ("+formatAge(time.Since(entry.Timestamp))+")") + "\n")
+	content.WriteString(m.styles.accent.Render("  Line:       ") + m.styles.text.Render(fmt.Sprintf("%d", entry.LineNumber)) + "\n")
+	if entry.Level != "" {
+		content.WriteString(m.styles.accent.Render("  Level:      ") + m.styles.text.Render(entry.Level) + "\n")
+	}
+	if entry.RepeatCount > 0 {
+		content.WriteString(m.styles.accent.Render("  Repeated:   ") + m.styles.text.Render(fmt.Sprintf("%d times", entry.RepeatCount)) + "\n")
+	}
+	if entry.Truncated {
+		content.WriteString(m.styles.accent.Render("  Truncated:  ") + yellowColor.Render("line exceeded max_line_length; the rest was cut") + "\n")
+	}
+	if entry.Highlight != "" {
+		content.WriteString(m.styles.accent.Render("  Highlight:  ") + highlightColor(entry.HighlightColor).Render(entry.Highlight) + "\n")
+	}
 	if len(entry.Tags) > 0 {
-		content.WriteString(cyanColor.Render("  Tags:       ") + whiteColor.Render(strings.Join(entry.Tags, ", ")) + "\n")
+		content.WriteString(m.styles.accent.Render("  Tags:       ") + m.styles.text.Render(strings.Join(entry.Tags, ", ")) + "\n")
+	}
+	if len(entry.Fields) > 0 {
+		content.WriteString(m.styles.accent.Render("  Fields:\n"))
+		keys := make([]string, 0, len(entry.Fields))
+		for k := range entry.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			content.WriteString("    " + grayColor.Render(k+": ") + m.styles.text.Render(entry.Fields[k]) + "\n")
+		}
 	}
 	content.WriteString("\n")
-	content.WriteString(cyanColor.Render("  Content:\n"))
+	content.WriteString(m.styles.accent.Render("  Content:\n"))
 	content.WriteString(grayColor.Render("  " + strings.Repeat("─", m.width-6) + "\n"))
 
-	// Word wrap content for display, use stream color
-	contentLines := m.wrapText(entry.Content, m.width-6)
-	for _, line := range contentLines {
-		content.WriteString("  " + m.sourceColor(entry.Source).Render(line) + "\n")
+	// Word wrap each original line for display, use stream color
+	for _, rawLine := range strings.Split(entry.Content, "\n") {
+		for _, line := range m.wrapText(rawLine, m.width-6) {
+			content.WriteString("  " + m.sourceColor(entry.Source).Render(line) + "\n")
+		}
 	}
 
 	content.WriteString(grayColor.Render("  " + strings.Repeat("─", m.width-6) + "\n"))
@@ -488,7 +1335,7 @@ func (m *Model) renderDetailView() string {
 		Height(m.height - 6).
 		Render(content.String())
 
-	footer := helpBar.Render(grayColor.Render("[ESC/Enter] Back to list  [↑/↓] Navigate"))
+	footer := helpBar.Render(grayColor.Render("[ESC/Enter] Back to list  [↑/↓] Navigate  [y/Y] Copy line/with source") + m.renderNotice())
 
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
@@ -523,7 +1370,7 @@ func (m *Model) wrapText(text string, width int) []string {
 
 func (m *Model) renderTitleBar() string {
 	timeStr := time.Now().Format("15:04:05")
-	title := titleStyle.Render(" LOGDUMP ")
+	title := m.styles.title.Render(" LOGDUMP ")
 	right := helpBar.Render(timeStr)
 
 	// Calculate available width for stream indicators
@@ -571,12 +1418,15 @@ func (m *Model) renderTitleBar() string {
 	left := title + "  " + streamsStr
 	padding := max(0, m.width-lipgloss.Width(left)-lipgloss.Width(right))
 
-	return headerBg.Width(m.width).Render(left + strings.Repeat(" ", padding) + right)
+	return m.styles.header.Width(m.width).Render(left + strings.Repeat(" ", padding) + right)
 }
 
 func (m *Model) renderTable() string {
 	if len(m.filteredBuffer) == 0 {
-		emptyMsg := cyanColor.Render("  No logs to display  ")
+		emptyMsg := m.styles.accent.Render("  No logs to display  ")
+		if read, total, loading := m.manager.HistoryProgress(); loading {
+			emptyMsg = m.styles.accent.Render("  Reading history… " + formatProgress(read, total) + "  ")
+		}
 		helpMsg := grayColor.Render("  Press '?' for help  ")
 		padding := m.width - lipgloss.Width(emptyMsg) - lipgloss.Width(helpMsg)
 		if padding < 0 {
@@ -615,9 +1465,9 @@ func (m *Model) renderTable() string {
 }
 
 func (m *Model) renderTableHeader() string {
-	timestamp := headerCell.Render("TIMESTAMP")
-	source := lipgloss.NewStyle().Width(16).Background(lipgloss.Color("#3d3d5c")).Foreground(lipgloss.Color("#ffffff")).Bold(true).Padding(0, 1).Render("SOURCE")
-	content := lipgloss.NewStyle().Width(m.viewport.Width-13-16-4).Background(lipgloss.Color("#3d3d5c")).Foreground(lipgloss.Color("#ffffff")).Bold(true).Align(lipgloss.Left).Padding(0, 1).Render("LOG CONTENT")
+	timestamp := m.styles.headerCell.Render("TIMESTAMP")
+	source := m.styles.header.Width(16).Bold(true).Padding(0, 1).Render("SOURCE")
+	content := m.styles.header.Width(m.viewport.Width-13-16-4).Bold(true).Align(lipgloss.Left).Padding(0, 1).Render("LOG CONTENT")
 
 	borderLine := cornerTL + strings.Repeat(horiz, 13) + teeUp + strings.Repeat(horiz, 16) + teeUp + strings.Repeat(horiz, m.viewport.Width-13-16-4) + cornerTR
 	headerLine := vert + timestamp + vert + source + vert + content + vert
@@ -627,7 +1477,7 @@ func (m *Model) renderTableHeader() string {
 }
 
 func (m *Model) renderTableRow(entry LogEntry, alt bool, selected bool) string {
-	timestamp := grayColor.Render(entry.Timestamp[:12])
+	timestamp := grayColor.Render(entry.Timestamp.Format("15:04:05.000"))
 
 	indicator := "●"
 	if !m.selectedStreams[entry.Source] {
@@ -637,23 +1487,36 @@ func (m *Model) renderTableRow(entry LogEntry, alt bool, selected bool) string {
 	// Selection indicator
 	selectIndicator := " "
 	if selected {
-		selectIndicator = cyanColor.Render("▶")
+		selectIndicator = m.styles.accent.Render("▶")
 	}
 
-	source := m.sourceColor(entry.Source).Render(indicator + " " + entry.Source)
+	sourceStyle := m.sourceColor(entry.Source)
+	if entry.Highlight != "" {
+		sourceStyle = highlightColor(entry.HighlightColor)
+	}
+	source := sourceStyle.Render(indicator + " " + entry.Source)
 
 	maxContentLen := m.viewport.Width - 13 - 16 - 8
 	if maxContentLen < 10 {
 		maxContentLen = 10
 	}
 
-	content := entry.Content
+	// Multiline entries show their first line and how many more follow
+	content, rest, multiline := strings.Cut(entry.Content, "\n")
+	more := ""
+	if multiline {
+		more = fmt.Sprintf(" (+%d lines)", strings.Count(rest, "\n")+1)
+	}
+	if entry.RepeatCount > 0 {
+		more += fmt.Sprintf(" (x%d)", entry.RepeatCount)
+	}
+	maxContentLen -= len(more)
+
 	if len(content) > maxContentLen {
-		content = content[:maxContentLen-3] + "..."
+		content = content[:max(0, maxContentLen-3)] + "..."
 	}
 
-	// Use stream color for log content
-	styledContent := m.sourceColor(entry.Source).Render(content)
+	styledContent := m.renderContent(entry, content) + grayColor.Render(more)
 
 	tsStyle := lipgloss.NewStyle().Width(12)
 	srcStyle := lipgloss.NewStyle().Width(16)
@@ -661,156 +1524,540 @@ func (m *Model) renderTableRow(entry LogEntry, alt bool, selected bool) string {
 
 	if selected {
 		// Highlight selected row
-		tsStyle = tsStyle.Background(lipgloss.Color("#3d5c5c"))
-		srcStyle = srcStyle.Background(lipgloss.Color("#3d5c5c"))
-		ctStyle = ctStyle.Background(lipgloss.Color("#3d5c5c"))
+		bg := themeColor(m.config.Theme.SelectionBg, "#3d5c5c")
+		tsStyle = tsStyle.Background(bg)
+		srcStyle = srcStyle.Background(bg)
+		ctStyle = ctStyle.Background(bg)
 	} else if alt {
-		tsStyle = tsStyle.Background(lipgloss.Color("#1e1e2e"))
-		srcStyle = srcStyle.Background(lipgloss.Color("#1e1e2e"))
-		ctStyle = ctStyle.Background(lipgloss.Color("#1e1e2e"))
+		bg := themeColor(m.config.Theme.AltRowBg, "#1e1e2e")
+		tsStyle = tsStyle.Background(bg)
+		srcStyle = srcStyle.Background(bg)
+		ctStyle = ctStyle.Background(bg)
 	}
 
 	return selectIndicator + vert + tsStyle.Render(timestamp) + vert + srcStyle.Render(source) + vert + ctStyle.Render(" "+styledContent+" ") + vert
 }
 
+// themeColor returns the configured color, or fallback if none is set.
+func themeColor(configured, fallback string) lipgloss.Color {
+	if configured == "" {
+		return lipgloss.Color(fallback)
+	}
+	return lipgloss.Color(configured)
+}
+
+// contentColor styles a row's content in the color of the filter that
+// highlighted it, if any, otherwise by its level when coloring by level,
+// falling back to the stream color for INFO and unleveled lines.
+func (m *Model) contentColor(entry LogEntry) lipgloss.Style {
+	if entry.Highlight != "" {
+		return highlightColor(entry.HighlightColor)
+	}
+	if m.colorByLevel {
+		if style, ok := levelColor(entry.Level); ok {
+			return style
+		}
+	}
+	return m.sourceColor(entry.Source)
+}
+
+// renderContent styles the visible part of an entry's content, picking out
+// the level token in its level's color.
+func (m *Model) renderContent(entry LogEntry, content string) string {
+	style := m.contentColor(entry)
+	token, ok := levelColor(entry.Level)
+	if !ok {
+		token = style
+	}
+	token = token.Bold(true)
+
+	// Only search when upper-casing keeps byte offsets intact
+	upper := strings.ToUpper(content)
+	i := -1
+	if entry.Level != "" && len(upper) == len(content) {
+		i = strings.Index(upper, entry.Level)
+	}
+	if i < 0 {
+		return style.Render(content)
+	}
+	end := i + len(entry.Level)
+	return style.Render(content[:i]) + token.Render(content[i:end]) + style.Render(content[end:])
+}
+
+// levelColor returns the color for a level, or false for INFO and lines
+// without a level, which keep their stream color.
+func levelColor(level string) (lipgloss.Style, bool) {
+	switch level {
+	case "ERROR", "FATAL":
+		return errorColor, true
+	case "WARN":
+		return yellowColor, true
+	case "DEBUG":
+		return grayColor, true
+	}
+	return lipgloss.Style{}, false
+}
+
 func (m *Model) renderFooter() string {
 	status := ""
 	if m.paused {
 		status = errorColor.Render("[PAUSED] ")
 	}
 	if m.autoScroll {
-		status += cyanColor.Render("[AUTO] ")
+		status += m.styles.accent.Render("[AUTO] ")
 	}
-	if m.reverseOrder {
+	switch {
+	case m.ranked():
+		status += magentaColor.Render("[RANKED] ")
+	case m.reverseOrder:
 		status += yellowColor.Render("[↓NEW] ")
-	} else {
+	default:
 		status += greenColor.Render("[NEW↓] ")
 	}
 
 	if m.searchMode {
-		searchInput := cyanColor.Render("/") + whiteColor.Render(m.searchQuery) + cyanColor.Render("█")
-		searchBar := helpBar.Render(status + searchInput + "  (ESC: cancel, Enter: search)")
+		mode := grayColor.Render("[TEXT] ")
+		if m.searchRegex {
+			mode = yellowColor.Render("[REGEX] ")
+		}
+		searchInput := mode + m.styles.accent.Render("/") + m.styles.text.Render(m.searchQuery) + m.styles.accent.Render("█")
+		hint := "  (ESC: cancel, Enter: search, Tab: text/regex)"
+		if m.searchErr != nil {
+			hint = "  " + errorColor.Render(searchErrorText(m.searchErr))
+		}
+		searchBar := helpBar.Render(status + searchInput + hint)
 		return searchBar
 	}
 
-	stats := fmt.Sprintf("Lines: %d | Visible: %d/%d | Scroll: %d",
-		len(m.logBuffer), len(m.filteredBuffer), 1000, m.scrollOffset)
+	capacity := "unlimited"
+	if m.bufferSize > 0 {
+		capacity = fmt.Sprintf("%d", m.bufferSize)
+	}
+	stats := fmt.Sprintf("Lines: %d | Visible: %d/%s | Scroll: %d",
+		m.logBuffer.Len(), len(m.filteredBuffer), capacity, m.scrollOffset)
+	if m.searchRe != nil {
+		kind := "Search"
+		if m.searchRegex {
+			kind = "Regex"
+		}
+		stats += fmt.Sprintf(" | %s: %s", kind, m.searchQuery)
+	}
+	if m.searchErr != nil {
+		stats += " | " + errorColor.Render(searchErrorText(m.searchErr))
+	}
+	if read, total, loading := m.manager.HistoryProgress(); loading {
+		stats += " | " + yellowColor.Render("Reading history… "+formatProgress(read, total))
+	}
+	if m.minLevel != "" {
+		stats += " | Level: " + m.minLevel + "+"
+	}
+	if m.group != nil {
+		stats += " | Group: " + m.group.name
+	}
+	if filters := m.manager.Filters(); len(filters) > 0 {
+		var active []string
+		for _, f := range filters {
+			if f.Enabled {
+				active = append(active, f.Name)
+			}
+		}
+		if len(active) == 0 {
+			active = []string{"none"}
+		}
+		stats += " | Filters: " + strings.Join(active, ", ")
+	}
+	if m.queued > 0 {
+		stats += " | " + yellowColor.Render(fmt.Sprintf("Behind: %d queued", m.queued))
+	}
+	if dropped := m.droppedLines(); dropped > 0 {
+		stats += " | " + yellowColor.Render(fmt.Sprintf("Dropped: %d", dropped))
+	}
+	stats += m.renderNotice()
 
-	controls := grayColor.Render("[↑/↓]Select [Enter]Detail [/]Search [s]Streams [r]Reverse [c]Clear [D]Delete [p]Pause [q]Quit")
+	controlsText := "[↑/↓]Select [Enter]Detail [/]Search [s]Streams [f]Group [F]Filters [L]Level [w]Watch [y]Copy [e]Export [r]Reverse [c]Clear [D]Delete [p]Pause [?]Help [q]Quit"
+	if m.activity != nil {
+		controlsText = "[↑/↓]Select [Enter]Detail [/]Search [s]Streams [f]Group [F]Filters [A]Agents [L]Level [w]Watch [y]Copy [e]Export [r]Reverse [c]Clear [D]Delete [p]Pause [?]Help [q]Quit"
+	}
+	controls := grayColor.Render(controlsText)
 
 	helpBar2 := helpBar.Render(status + controls)
 	return helpBar2 + "\n" + helpBar.Render(stats)
 }
 
+// droppedLines counts the lines read but never shown: discarded by
+// sampling, rate limits or overflow, or missed while the TUI fell behind.
+func (m *Model) droppedLines() int64 {
+	stats := m.manager.Stats()
+	dropped := stats.SubscriberDrops
+	for _, st := range stats.Streams {
+		dropped += st.Dropped + st.Overflowed
+	}
+	return dropped
+}
+
+// noticeDuration is how long a notice stays in the footer.
+const noticeDuration = 3 * time.Second
+
+func (m *Model) setNotice(text string) {
+	m.notice = text
+	m.noticeUntil = time.Now().Add(noticeDuration)
+	m.noticeErr = false
+}
+
+func (m *Model) setError(text string) {
+	m.setNotice(text)
+	m.noticeErr = true
+}
+
+func (m *Model) renderNotice() string {
+	if m.notice == "" || time.Now().After(m.noticeUntil) {
+		return ""
+	}
+	if m.noticeErr {
+		return " | " + errorColor.Render(m.notice)
+	}
+	return " | " + greenColor.Render(m.notice)
+}
+
+// copySelected puts the selected entry's content on the system clipboard,
+// prefixed with its timestamp and source if full is set.
+func (m *Model) copySelected(full bool) {
+	if len(m.filteredBuffer) == 0 || m.selectedIdx >= len(m.filteredBuffer) {
+		return
+	}
+	entry := m.filteredBuffer[m.selectedIdx]
+
+	text := entry.Content
+	if full {
+		text = fmt.Sprintf("[%s] [%s] %s", entry.Timestamp.Format(time.RFC3339), entry.Source, entry.Content)
+	}
+
+	if clipboard.Unsupported {
+		m.setError("No clipboard available")
+		return
+	}
+	if err := clipboard.WriteAll(text); err != nil {
+		m.setError("Copy failed: no clipboard available")
+		return
+	}
+	m.setNotice("Copied")
+}
+
+// copyStreamConfig puts the highlighted stream's config on the clipboard
+// as YAML for the streams list. Without a clipboard it is printed once the
+// TUI exits instead.
+func (m *Model) copyStreamConfig() {
+	if m.streamIdx >= len(m.streams) {
+		return
+	}
+	name := m.streams[m.streamIdx]
+	idx := slices.IndexFunc(m.config.Streams, func(s config.StreamConfig) bool { return s.Name == name })
+	if idx < 0 {
+		m.setError("No config for " + name)
+		return
+	}
+	text, err := m.config.Streams[idx].Snippet()
+	if err != nil {
+		m.setError("Copy failed: " + err.Error())
+		return
+	}
+
+	if clipboard.Unsupported || clipboard.WriteAll(text) != nil {
+		m.output = append(m.output, text)
+		m.setNotice("No clipboard: config for " + name + " is printed on exit")
+		return
+	}
+	m.setNotice("Copied config for " + name)
+}
+
+// Output returns what the TUI left to print once it has exited.
+func (m *Model) Output() string {
+	return strings.Join(m.output, "")
+}
+
 func (m *Model) sourceColor(source string) lipgloss.Style {
 	for _, stream := range m.config.Streams {
 		if stream.Name == source {
-			switch strings.ToLower(stream.Color) {
-			case "red":
-				return errorColor
-			case "green":
-				return greenColor
-			case "blue":
-				return blueColor
-			case "yellow":
-				return yellowColor
-			case "cyan":
-				return cyanColor
-			case "magenta":
-				return magentaColor
-			case "white":
-				return whiteColor
+			if style, ok := namedColor(stream.Color); ok {
+				return style
 			}
 		}
 	}
 	return grayColor
 }
 
+// namedColor returns the style of one of the stream color names.
+func namedColor(name string) (lipgloss.Style, bool) {
+	switch strings.ToLower(name) {
+	case "red":
+		return errorColor, true
+	case "green":
+		return greenColor, true
+	case "blue":
+		return blueColor, true
+	case "yellow":
+		return yellowColor, true
+	case "cyan":
+		return cyanColor, true
+	case "magenta":
+		return magentaColor, true
+	case "white":
+		return whiteColor, true
+	}
+	return lipgloss.Style{}, false
+}
+
+// highlightColor returns the style of a filter's color: a stream color
+// name or a "#rrggbb" value, magenta if none is set.
+func highlightColor(color string) lipgloss.Style {
+	if style, ok := namedColor(color); ok {
+		return style
+	}
+	if color == "" {
+		return magentaColor
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(color))
+}
+
+// maxEntriesPerTick bounds how many entries one tick takes from the
+// manager, so a burst cannot stall the UI.
+const maxEntriesPerTick = 500
+
+// updateLogs drains the entries that are waiting, up to maxEntriesPerTick,
+// and renders once for the whole batch.
 func (m *Model) updateLogs() {
-	select {
-	case entry, ok := <-m.manager.Entries():
-		if !ok {
-			return
+	received := false
+	added := false
+
+drain:
+	for range maxEntriesPerTick {
+		select {
+		case entry, ok := <-m.entries:
+			if !ok {
+				break drain
+			}
+			received = true
+
+			m.logBuffer.Push(newLogEntry(entry))
+			if entry.Source == m.watchStream {
+				e := newLogEntry(entry)
+				m.watchEntry = &e
+			}
+
+			if e := newLogEntry(entry); m.visible(e) {
+				m.filteredBuffer = append(m.filteredBuffer, e)
+				added = true
+			}
+		default:
+			break drain
 		}
+	}
 
-		m.logBuffer = append(m.logBuffer, LogEntry{
-			Timestamp:  entry.Timestamp.Format("15:04:05.000"),
-			Source:     entry.Source,
-			Content:    entry.Content,
-			Tags:       entry.Tags,
-			LineNumber: entry.LineNumber,
-		})
+	// Entries still waiting after a full batch mean the UI is behind
+	m.queued = len(m.entries)
 
-		if len(m.logBuffer) > 1000 {
-			m.logBuffer = m.logBuffer[len(m.logBuffer)-1000:]
-		}
-
-		if m.selectedStreams[entry.Source] {
-			if m.searchQuery == "" || strings.Contains(
-				strings.ToLower(entry.Content),
-				strings.ToLower(m.searchQuery),
-			) {
-				m.filteredBuffer = append(m.filteredBuffer, LogEntry{
-					Timestamp:  entry.Timestamp.Format("15:04:05.000"),
-					Source:     entry.Source,
-					Content:    entry.Content,
-					Tags:       entry.Tags,
-					LineNumber: entry.LineNumber,
-				})
-
-				if len(m.filteredBuffer) > 1000 {
-					m.filteredBuffer = m.filteredBuffer[len(m.filteredBuffer)-1000:]
-				}
+	if !received {
+		return
+	}
 
-				// Auto-scroll when new logs arrive
-				if m.autoScroll {
-					if m.reverseOrder {
-						// In reverse order, newest is at top, so stay at top
-						m.scrollOffset = 0
-						m.selectedIdx = 0
-					} else {
-						// Normal order, newest at bottom, scroll to bottom
-						m.scrollOffset = max(0, len(m.filteredBuffer)-m.viewport.Height)
-						m.selectedIdx = len(m.filteredBuffer) - 1
-					}
-				}
+	if added && m.ranked() {
+		// New matches take their place by relevance; the view stays put
+		m.applyFilters()
+		m.selectedIdx = min(m.selectedIdx, max(0, len(m.filteredBuffer)-1))
+	} else if added {
+		m.filteredBuffer = trimOldest(m.filteredBuffer, m.bufferSize)
+
+		// Auto-scroll when new logs arrive
+		if m.autoScroll {
+			if m.reverseOrder {
+				// In reverse order, newest is at top, so stay at top
+				m.scrollOffset = 0
+				m.selectedIdx = 0
+			} else {
+				// Normal order, newest at bottom, scroll to bottom
+				m.scrollOffset = max(0, len(m.filteredBuffer)-m.viewport.Height)
+				m.selectedIdx = len(m.filteredBuffer) - 1
 			}
 		}
-
-		m.viewport.SetContent(m.renderTable())
-	default:
 	}
+
+	m.viewport.SetContent(m.renderTable())
 }
 
 func (m *Model) applySearch(query string) {
 	m.searchQuery = query
 
 	if query == "" {
+		m.searchRe = nil
+		m.searchErr = nil
 		m.applyFilters()
 	} else {
-		pattern := regexp.QuoteMeta(query)
-		re := regexp.MustCompile("(?i)" + pattern)
-
-		m.filteredBuffer = make([]LogEntry, 0)
-		for _, entry := range m.logBuffer {
-			if m.selectedStreams[entry.Source] && re.MatchString(entry.Content) {
-				m.filteredBuffer = append(m.filteredBuffer, entry)
-			}
+		re, err := compileSearch(query, m.searchRegex)
+		if err != nil {
+			// Keep the last valid results while the pattern is incomplete
+			m.searchErr = err
+			m.viewport.SetContent(m.renderTable())
+			return
 		}
+		m.searchRe = re
+		m.searchErr = nil
+
+		m.applyFilters()
 	}
 
 	m.viewport.SetContent(m.renderTable())
 }
 
+// compileSearch builds the case-insensitive matcher for a search query,
+// matching it as literal text unless regex is set.
+func compileSearch(query string, regex bool) (*regexp.Regexp, error) {
+	if !regex {
+		query = regexp.QuoteMeta(query)
+	}
+	return regexp.Compile("(?i)" + query)
+}
+
+// searchErrorText describes an invalid search pattern for the footer.
+func searchErrorText(err error) string {
+	msg := err.Error()
+	var syntaxErr *syntax.Error
+	if errors.As(err, &syntaxErr) {
+		msg = fmt.Sprintf("%s: %s", syntaxErr.Code, syntaxErr.Expr)
+	}
+	return "invalid regex: " + msg
+}
+
 func (m *Model) applyFilters() {
 	m.filteredBuffer = make([]LogEntry, 0)
-	for _, entry := range m.logBuffer {
-		if m.selectedStreams[entry.Source] {
+	m.logBuffer.Each(func(entry LogEntry) bool {
+		if m.visible(entry) {
 			m.filteredBuffer = append(m.filteredBuffer, entry)
 		}
+		return true
+	})
+	if m.ranked() {
+		m.rankFiltered()
 	}
 }
 
+// ranked reports whether the table shows search results by relevance.
+func (m *Model) ranked() bool {
+	return m.rankResults && m.searchRe != nil
+}
+
+// rankFiltered orders the filtered entries so the most relevant is shown
+// first whichever way the table is flipped, keeping lines of equal
+// relevance in chronological order.
+func (m *Model) rankFiltered() {
+	type scored struct {
+		entry LogEntry
+		score int
+	}
+	ranked := make([]scored, len(m.filteredBuffer))
+	for i, entry := range m.filteredBuffer {
+		ranked[i] = scored{entry, relevance(m.searchRe, entry.Content)}
+	}
+	slices.SortStableFunc(ranked, func(a, b scored) int {
+		if m.reverseOrder {
+			return cmp.Compare(a.score, b.score)
+		}
+		return cmp.Compare(b.score, a.score)
+	})
+	for i, r := range ranked {
+		m.filteredBuffer[i] = r.entry
+	}
+}
+
+// relevance scores how well content matches re: a point for every match,
+// and another for each match that is a whole word.
+func relevance(re *regexp.Regexp, content string) int {
+	score := 0
+	for _, loc := range re.FindAllStringIndex(content, -1) {
+		score++
+		before, _ := utf8.DecodeLastRuneInString(content[:loc[0]])
+		after, _ := utf8.DecodeRuneInString(content[loc[1]:])
+		if !isWordRune(before) && !isWordRune(after) {
+			score++
+		}
+	}
+	return score
+}
+
+// isWordRune reports whether r is part of a word; RuneError, returned at
+// either end of the content, is not.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// visible reports whether entry passes the stream selection, search and
+// minimum level. Heartbeats bypass the filters.
+func (m *Model) visible(entry LogEntry) bool {
+	if entry.Heartbeat {
+		return true
+	}
+	return m.selectedStreams[entry.Source] &&
+		(m.searchRe == nil || m.searchRe.MatchString(entry.Content)) &&
+		(m.group == nil || m.group.matches(entry)) &&
+		logtail.LevelAtLeast(entry.Level, m.minLevel)
+}
+
+// nextLevel cycles the minimum level: all, then each of logtail.Levels.
+func nextLevel(level string) string {
+	rank := logtail.LevelRank(level)
+	if rank+1 >= len(logtail.Levels) {
+		return ""
+	}
+	return logtail.Levels[rank+1]
+}
+
+// formatProgress renders bytes read out of total, e.g. "45% (12.3 MB/27.0 MB)".
+func formatProgress(read, total int64) string {
+	if total <= 0 {
+		return formatBytes(read)
+	}
+	return fmt.Sprintf("%d%% (%s/%s)", read*100/total, formatBytes(read), formatBytes(total))
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. "1.5 MB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatAge renders how long ago something happened, e.g. "3m ago".
+func formatAge(d time.Duration) string {
+	switch {
+	case d < 0:
+		return "in the future"
+	case d < time.Second:
+		return "just now"
+	case d < time.Minute:
+		return fmt.Sprintf("%ds ago", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%dm ago", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd%dh ago", int(d.Hours())/24, int(d.Hours())%24)
+	}
+}
+
+// trimOldest drops entries beyond the newest n. Zero keeps everything.
+func trimOldest(buf []LogEntry, n int) []LogEntry {
+	if n <= 0 || len(buf) <= n {
+		return buf
+	}
+	return buf[len(buf)-n:]
+}
+
 func (m *Model) tick() tea.Cmd {
 	return tea.Tick(100*time.Millisecond, func(t time.Time) tea.Msg {
 		return tickMsg(t)
@@ -819,11 +2066,73 @@ func (m *Model) tick() tea.Cmd {
 
 type tickMsg time.Time
 
-func (m *Model) deleteLogFiles() {
+// exportEntry is one line of a JSON export.
+type exportEntry struct {
+	Timestamp  string            `json:"timestamp"`
+	Source     string            `json:"source"`
+	Level      string            `json:"level,omitempty"`
+	LineNumber int               `json:"line_number"`
+	Content    string            `json:"content"`
+	Tags       []string          `json:"tags,omitempty"`
+	Fields     map[string]string `json:"fields,omitempty"`
+}
+
+// exportView writes the filtered entries, in display order, to a
+// timestamped file in the current directory as text or JSON, and reports
+// the path or the error in the footer.
+func (m *Model) exportView(asJSON bool) {
+	entries := slices.Clone(m.filteredBuffer)
+	if m.reverseOrder {
+		slices.Reverse(entries)
+	}
+
+	var data []byte
+	ext := "txt"
+	if asJSON {
+		ext = "json"
+		out := make([]exportEntry, 0, len(entries))
+		for _, e := range entries {
+			out = append(out, exportEntry{
+				Timestamp:  e.Timestamp.Format(time.RFC3339Nano),
+				Source:     e.Source,
+				Level:      e.Level,
+				LineNumber: e.LineNumber,
+				Content:    e.Content,
+				Tags:       e.Tags,
+				Fields:     e.Fields,
+			})
+		}
+		var err error
+		if data, err = json.MarshalIndent(out, "", "  "); err != nil {
+			m.setError("Export failed: " + err.Error())
+			return
+		}
+		data = append(data, '\n')
+	} else {
+		var b strings.Builder
+		for _, e := range entries {
+			fmt.Fprintf(&b, "[%s] [%s] %s\n", e.Timestamp.Format(time.RFC3339), e.Source, e.Content)
+		}
+		data = []byte(b.String())
+	}
+
+	path := fmt.Sprintf("logdump-export-%s.%s", time.Now().Format("20060102-150405"), ext)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		m.setError("Export failed: " + err.Error())
+		return
+	}
+	m.setNotice(fmt.Sprintf("Exported %d lines to %s", len(entries), path))
+}
+
+// deleteLogFiles truncates the files of the selected streams and returns
+// the names of the streams it cleared.
+func (m *Model) deleteLogFiles() map[string]bool {
+	deleted := make(map[string]bool)
 	for _, stream := range m.config.Streams {
 		if !m.selectedStreams[stream.Name] {
 			continue
 		}
+		deleted[stream.Name] = true
 
 		// Find log files matching the stream patterns
 		for _, pattern := range stream.Patterns {
@@ -840,6 +2149,21 @@ func (m *Model) deleteLogFiles() {
 			}
 		}
 	}
+	return deleted
+}
+
+// clearDeleted drops the buffered entries of the deleted streams, keeping
+// the rest of the view.
+func (m *Model) clearDeleted(deleted map[string]bool) {
+	m.logBuffer.Filter(func(entry LogEntry) bool {
+		return !deleted[entry.Source]
+	})
+	if m.watchEntry != nil && deleted[m.watchEntry.Source] {
+		m.watchEntry = nil
+	}
+	m.applyFilters()
+	m.selectedIdx = min(m.selectedIdx, max(0, len(m.filteredBuffer)-1))
+	m.scrollOffset = min(m.scrollOffset, max(0, len(m.filteredBuffer)-m.viewport.Height))
 }
 
 func max(a, b int) int {
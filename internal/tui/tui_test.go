@@ -0,0 +1,76 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/appgram/logdump/internal/config"
+	"github.com/appgram/logdump/internal/logtail"
+)
+
+// newTestModel returns a Model showing the stream app, fed from the
+// returned channel instead of a manager's subscription. It keeps up to
+// 10000 entries.
+func newTestModel(t *testing.T) (*Model, chan logtail.LogEntry) {
+	t.Helper()
+	manager := logtail.NewManager()
+	t.Cleanup(func() {
+		manager.Close()
+		manager.Wait()
+	})
+	size := 10000
+	m := New(manager, &config.Config{Streams: []config.StreamConfig{{Name: "app"}}, BufferSize: &size})
+	entries := make(chan logtail.LogEntry, size)
+	m.entries = entries
+	return m, entries
+}
+
+func TestTimestampsSurviveBuffering(t *testing.T) {
+	m, entries := newTestModel(t)
+	at := time.Date(2026, 3, 1, 12, 30, 45, 123456789, time.FixedZone("CET", 3600))
+	entries <- logtail.LogEntry{Timestamp: at, Source: "app", Content: "started"}
+	m.updateLogs()
+
+	if m.logBuffer.Len() != 1 || len(m.filteredBuffer) != 1 {
+		t.Fatalf("buffered %d entries, showing %d, want 1 of each", m.logBuffer.Len(), len(m.filteredBuffer))
+	}
+	// Rebuilding the view from the buffer keeps them too
+	m.applyFilters()
+	for _, got := range []time.Time{m.logBuffer.At(0).Timestamp, m.filteredBuffer[0].Timestamp} {
+		if !got.Equal(at) || got.Location() != at.Location() {
+			t.Errorf("timestamp %v, want %v", got, at)
+		}
+	}
+
+	if row := m.renderTableRow(m.filteredBuffer[0], false, false); !strings.Contains(row, "12:30:45.123") {
+		t.Errorf("row %q does not show the time to the millisecond", row)
+	}
+}
+
+func TestTicksDrainBacklog(t *testing.T) {
+	m, entries := newTestModel(t)
+	const n = 5000
+	for i := range n {
+		entries <- logtail.LogEntry{Timestamp: time.Now(), Source: "app", Content: fmt.Sprintf("line %d", i)}
+	}
+
+	// Each tick takes a full batch, saying how far behind it still is
+	ticks := 0
+	for len(m.filteredBuffer) < n {
+		if ticks++; ticks > n/maxEntriesPerTick {
+			t.Fatalf("%d of %d entries shown after %d ticks", len(m.filteredBuffer), n, ticks-1)
+		}
+		m.Update(tickMsg(time.Now()))
+		if want := n - ticks*maxEntriesPerTick; m.queued != want {
+			t.Fatalf("%d entries queued after tick %d, want %d", m.queued, ticks, want)
+		}
+		if behind := strings.Contains(m.renderFooter(), "Behind:"); behind != (m.queued > 0) {
+			t.Errorf("footer shows lag %v with %d queued", behind, m.queued)
+		}
+	}
+	if last := m.filteredBuffer[n-1].Content; last != "line 4999" {
+		t.Errorf("last entry shown is %q", last)
+	}
+}
@@ -2,10 +2,18 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	stdlog "log"
+	"maps"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
+	"slices"
 	"strings"
 	"sync"
 	"syscall"
@@ -17,6 +25,7 @@ import (
 	"github.com/appgram/logdump/internal/config"
 	"github.com/appgram/logdump/internal/logtail"
 	"github.com/appgram/logdump/internal/mcp"
+	"github.com/appgram/logdump/internal/metrics"
 	"github.com/appgram/logdump/internal/tui"
 )
 
@@ -30,9 +39,17 @@ func main() {
 	printVersion := flag.Bool("version", false, "Print version and exit")
 	configPath := flag.String("config", "", "Path to config file")
 	mcpMode := flag.Bool("mcp", false, "Run in MCP server mode")
-	mcpTransport := flag.String("mcp-transport", "stdio", "MCP transport type (stdio, websocket)")
+	mcpTransport := flag.String("mcp-transport", "stdio", "MCP transport type (stdio, websocket, sse)")
+	mcpAddr := flag.String("mcp-addr", ":8765", "Listen address for the websocket and sse MCP transports")
+	mcpWebsocket := flag.Bool("mcp-websocket", false, "Run the websocket MCP server in the background alongside the TUI")
 	excludeFlag := flag.String("exclude", "", "Comma-separated list of streams to exclude (e.g., -exclude mcp-activity,sample)")
 	tailOnly := flag.Bool("tail", false, "Only show new logs, don't load history")
+	historyLines := flag.Int("history", -1, "Load only the last N lines of each file's history (default from config, else all)")
+	resume := flag.Bool("resume", false, "Continue each file from where the previous run stopped reading it")
+	mcpStrict := flag.Bool("mcp-strict", false, "Reject MCP requests that are not valid JSON-RPC 2.0")
+	bufferSize := flag.Int("buffer", -1, "Number of log entries to keep in memory, 0 for unlimited (default from config, else 1000)")
+	metricsAddr := flag.String("metrics-addr", "", "Serve Prometheus metrics at /metrics on this address, e.g. :9090")
+	sinkPath := flag.String("sink", "", "Also write every log entry to this file (overrides sink.path in the config)")
 	flag.Parse()
 
 	if *printVersion {
@@ -73,16 +90,46 @@ func main() {
 		}
 	}
 
+	overrides := flagOverrides{bufferSize: *bufferSize, strict: *mcpStrict, sinkPath: *sinkPath}
+	overrides.apply(cfg)
+
 	// Auto-discover log files
 	if err := cfg.AutoDiscover(exclude); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: auto-discovery failed: %v\n", err)
 	}
 
+	// At the end of a pipe, show what comes down it
+	readsStdin := slices.ContainsFunc(cfg.Streams, func(s config.StreamConfig) bool {
+		return s.Source == config.SourceStdin
+	})
+	if !*mcpMode && !readsStdin && !exclude["stdin"] && stdinIsPiped() {
+		cfg.Streams = append(cfg.Streams, config.StreamConfig{
+			Name:   "stdin",
+			Source: config.SourceStdin,
+			Color:  "white",
+		})
+		readsStdin = true
+	}
+	if *mcpMode && *mcpTransport == "stdio" && readsStdin {
+		fmt.Fprintln(os.Stderr, "Error: a stream reads stdin, which the MCP stdio transport needs for requests; use -mcp-transport websocket")
+		os.Exit(1)
+	}
+
+	// Catch a bad address before the TUI hides the server's errors
+	if (*mcpMode && *mcpTransport != "stdio") || *mcpWebsocket {
+		if _, _, err := net.SplitHostPort(*mcpAddr); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid -mcp-addr %q: %v\n", *mcpAddr, err)
+			os.Exit(1)
+		}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	reloader := &configReloader{path: *configPath, globalOnly: *mcpMode && *configPath == "", exclude: exclude, overrides: overrides, cfg: cfg}
+
 	if *mcpMode {
-		runMCPServer(ctx, cfg, *mcpTransport)
+		runMCPServer(ctx, cfg, reloader, *mcpTransport, *mcpAddr, *metricsAddr, *resume)
 		return
 	}
 
@@ -95,7 +142,45 @@ func main() {
 	}()
 
 	manager := logtail.NewManagerWithOptions(*tailOnly)
+	if !*tailOnly {
+		manager.SetHistoryLines(*historyLines)
+	}
+	if *resume {
+		resumeFromCheckpoint(manager)
+	}
+	applyBufferPolicy(manager, cfg)
+	applyOverflow(manager, cfg)
+	applyFilters(manager, cfg)
+	manager.SetAlertLog(mcp.ActivityLogPath(), mcp.LogAlert)
+	startSink(manager, cfg)
+
+	model := tui.New(manager, cfg)
+	reloader.manager = manager
+	model.SetReloader(reloader.reload)
+
+	// Combined mode: the MCP server's buffer and the TUI both subscribe to
+	// the manager's entries
+	var server *mcp.Server
+	var serverErr chan error
+	if *mcpWebsocket {
+		// The TUI owns the terminal, so silence the MCP server's stderr logging
+		stdlog.SetOutput(io.Discard)
+
+		manager.StartBuffering()
+
+		server = mcp.NewServer(manager, cfg)
+		model.SetActivitySource(server)
+		reloader.server = server
 
+		serverErr = make(chan error, 1)
+		go func() {
+			serverErr <- server.RunWebsocket(ctx, *mcpAddr)
+		}()
+	}
+	startMetrics(ctx, *metricsAddr, manager, server)
+
+	// Start tailing only once every consumer has subscribed, so none of
+	// them misses the history
 	var wg sync.WaitGroup
 	for _, stream := range cfg.Streams {
 		wg.Add(1)
@@ -107,16 +192,57 @@ func main() {
 		}(stream)
 	}
 
-	p := tea.NewProgram(tui.New(manager, cfg), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	startHeartbeat(manager, cfg)
+
+	options := []tea.ProgramOption{tea.WithAltScreen()}
+	if readsStdin {
+		// Keys come from the terminal, not the piped input
+		options = append(options, tea.WithInputTTY())
+	}
+	p := tea.NewProgram(model, options...)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			p.Send(reloader.reload())
+		}
+	}()
+
+	_, err = p.Run()
+	signal.Stop(hup)
+	fmt.Print(model.Output())
+
+	cancel()
+	manager.Close()
+	manager.Wait()
+
+	if serverErr != nil {
+		if err := <-serverErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "MCP server error: %v\n", err)
+		}
+	}
+
+	if err != nil {
 		log.Fatalf("UI error: %v", err)
 	}
 }
 
-func runMCPServer(ctx context.Context, cfg *config.Config, transport string) {
+func runMCPServer(ctx context.Context, cfg *config.Config, reloader *configReloader, transport, addr, metricsAddr string, resume bool) {
 	manager := logtail.NewManager()
+	reloader.manager = manager
+	if resume {
+		resumeFromCheckpoint(manager)
+	}
+	applyBufferPolicy(manager, cfg)
+	applyOverflow(manager, cfg)
+	applyFilters(manager, cfg)
+	manager.SetAlertLog(mcp.ActivityLogPath(), mcp.LogAlert)
+	startSink(manager, cfg)
 	manager.StartBuffering()
 	server := mcp.NewServer(manager, cfg)
+	reloader.server = server
+	startMetrics(ctx, metricsAddr, manager, server)
 
 	// Use stderr for logging in MCP mode to avoid corrupting JSON-RPC over stdout
 	fmt.Fprintln(os.Stderr, "Starting MCP server...")
@@ -129,6 +255,23 @@ func runMCPServer(ctx context.Context, cfg *config.Config, transport string) {
 		}(stream)
 	}
 
+	startHeartbeat(manager, cfg)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for range hup {
+			fmt.Fprintln(os.Stderr, reloader.reload().Summary())
+		}
+	}()
+
+	// Release the log files once the server stops
+	defer func() {
+		manager.Close()
+		manager.Wait()
+	}()
+
 	// Wait for initial file reads to be processed into buffer
 	// This prevents race condition where MCP requests arrive before entries are buffered
 	time.Sleep(200 * time.Millisecond)
@@ -139,10 +282,224 @@ func runMCPServer(ctx context.Context, cfg *config.Config, transport string) {
 			log.Fatalf("MCP server error: %v", err)
 		}
 	case "websocket":
-		if err := server.RunWebsocket(ctx, ":8765"); err != nil {
+		if err := server.RunWebsocket(ctx, addr); err != nil {
+			log.Fatalf("MCP server error: %v", err)
+		}
+	case "sse":
+		if err := server.RunSSE(ctx, addr); err != nil {
 			log.Fatalf("MCP server error: %v", err)
 		}
 	default:
 		log.Fatalf("Unknown transport: %s", transport)
 	}
 }
+
+// stdinIsPiped reports whether standard input is a pipe or file rather
+// than a terminal.
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
+
+// resumeFromCheckpoint makes manager continue files from the offsets saved
+// by the previous run. Without them every file is read as usual.
+func resumeFromCheckpoint(manager *logtail.Manager) {
+	if err := manager.ResumeFrom(logtail.DefaultCheckpointPath()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cannot resume from checkpoint: %v\n", err)
+	}
+}
+
+// applyBufferPolicy configures the manager's retention from the buffer
+// section of the config, keeping the default policy if it is invalid.
+// An explicit buffer_size or -buffer overrides the entry limit.
+func applyBufferPolicy(manager *logtail.Manager, cfg *config.Config) {
+	policy, err := bufferPolicy(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v, using default buffer policy\n", err)
+	}
+	manager.SetBufferPolicy(policy)
+}
+
+// bufferPolicy returns the retention cfg asks for, or the default policy
+// and the reason if its buffer section is invalid.
+func bufferPolicy(cfg *config.Config) (logtail.BufferPolicy, error) {
+	policy, err := logtail.NewBufferPolicy(cfg.Buffer)
+	if err != nil {
+		policy = logtail.DefaultBufferPolicy()
+	}
+	if cfg.BufferSize != nil {
+		policy.MaxEntries = cfg.EntryLimit()
+	}
+	return policy, err
+}
+
+// applyOverflow sets the overflow policy from the config.
+func applyOverflow(manager *logtail.Manager, cfg *config.Config) {
+	overflow, err := logtail.ParseOverflow(cfg.Overflow)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v, blocking on overflow\n", err)
+	}
+	manager.SetOverflow(overflow)
+}
+
+// applyFilters installs the config's filters, warning about invalid ones.
+func applyFilters(manager *logtail.Manager, cfg *config.Config) {
+	if err := manager.SetFilters(cfg.Filters); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: skipping invalid filters: %v\n", err)
+	}
+}
+
+// startSink starts writing entries to the sink file if one is configured.
+func startSink(manager *logtail.Manager, cfg *config.Config) {
+	if cfg.Sink.Path == "" {
+		return
+	}
+	if err := manager.StartSink(cfg.Sink); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v, sink disabled\n", err)
+	}
+}
+
+// startMetrics serves the Prometheus metrics on addr, if one is given,
+// exiting if it cannot be listened on.
+func startMetrics(ctx context.Context, addr string, manager *logtail.Manager, server *mcp.Server) {
+	if addr == "" {
+		return
+	}
+	errs, err := metrics.Serve(ctx, addr, manager, server)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: cannot serve metrics: %v\n", err)
+		os.Exit(1)
+	}
+	go func() {
+		for err := range errs {
+			stdlog.Printf("Metrics server error: %v", err)
+		}
+	}()
+}
+
+// configReloader rereads the config file, on SIGHUP or from the TUI, and
+// brings the manager's streams and filters in line with it.
+type configReloader struct {
+	mu         sync.Mutex
+	manager    *logtail.Manager
+	server     *mcp.Server // nil without an MCP server
+	path       string      // -config, "" to look for the file as at startup
+	globalOnly bool
+	exclude    map[string]bool
+	overrides  flagOverrides
+	cfg        *config.Config // the config applied last
+}
+
+// flagOverrides are the settings given on the command line, which take
+// precedence over the config file at startup and on every reload.
+type flagOverrides struct {
+	bufferSize int    // -buffer, -1 if not given
+	strict     bool   // -mcp-strict
+	sinkPath   string // -sink
+}
+
+// apply sets the overridden settings in cfg.
+func (o flagOverrides) apply(cfg *config.Config) {
+	if o.bufferSize >= 0 {
+		size := o.bufferSize
+		cfg.BufferSize = &size
+	}
+	if o.strict {
+		cfg.MCP.Strict = true
+	}
+	if o.sinkPath != "" {
+		cfg.Sink.Path = o.sinkPath
+	}
+}
+
+// reload tails the streams added to the config, stops those removed from
+// it and restarts those whose settings changed. Streams added or removed
+// at runtime, through the TUI or MCP, are left alone unless the config
+// names them. The filters and buffer policy are replaced, and the MCP
+// server is given the new config.
+func (r *configReloader) reload() tui.ConfigReloadedMsg {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := config.LoadWithOptions(r.path, r.globalOnly)
+	if err != nil {
+		return tui.ConfigReloadedMsg{Err: err}
+	}
+	r.overrides.apply(cfg)
+	var errs []error
+	if err := cfg.AutoDiscover(r.exclude); err != nil {
+		errs = append(errs, fmt.Errorf("auto-discovery failed: %w", err))
+	}
+	// Standard input can only be read once, so its stream is kept as is
+	previous := make(map[string]config.StreamConfig)
+	for _, s := range r.cfg.Streams {
+		if s.Source == config.SourceStdin {
+			cfg.Streams = slices.DeleteFunc(cfg.Streams, func(n config.StreamConfig) bool { return n.Name == s.Name })
+			cfg.Streams = append(cfg.Streams, s)
+			continue
+		}
+		previous[s.Name] = s
+	}
+
+	msg := tui.ConfigReloadedMsg{Config: cfg}
+	for _, s := range cfg.Streams {
+		old, ok := previous[s.Name]
+		delete(previous, s.Name)
+		switch {
+		case s.Source == config.SourceStdin:
+		case !ok:
+			if err := r.manager.Tail(s); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			msg.Added = append(msg.Added, s.Name)
+		case !sameStream(old, s):
+			if err := r.manager.RestartStream(s); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			msg.Restarted = append(msg.Restarted, s.Name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(previous)) {
+		// A stream already removed from the TUI is not an error
+		_, _ = r.manager.RemoveStream(name, false)
+		msg.Removed = append(msg.Removed, name)
+	}
+
+	if err := r.manager.SetFilters(cfg.Filters); err != nil {
+		errs = append(errs, fmt.Errorf("skipping invalid filters: %w", err))
+	}
+	policy, err := bufferPolicy(cfg)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("using default buffer policy: %w", err))
+	}
+	r.manager.SetBufferPolicy(policy)
+	if r.server != nil {
+		r.server.SetConfig(cfg)
+	}
+
+	r.cfg = cfg
+	msg.Err = errors.Join(errs...)
+	return msg
+}
+
+// sameStream reports whether a and b tail the same way. The color only
+// affects the TUI, which needs no restart for it.
+func sameStream(a, b config.StreamConfig) bool {
+	a.Color = b.Color
+	return reflect.DeepEqual(a, b)
+}
+
+// startHeartbeat starts the heartbeat entries if the config asks for them.
+func startHeartbeat(manager *logtail.Manager, cfg *config.Config) {
+	if cfg.Heartbeat == "" {
+		return
+	}
+	interval, err := time.ParseDuration(cfg.Heartbeat)
+	if err != nil || interval <= 0 {
+		fmt.Fprintf(os.Stderr, "Warning: invalid heartbeat %q, heartbeat disabled\n", cfg.Heartbeat)
+		return
+	}
+	manager.StartHeartbeat(interval)
+}